@@ -37,6 +37,72 @@ $(document).ready(function() {
         }
     });
 
+    $('.cover-image').click(function() {
+        const $button = $(this);
+        const bookId = $('#current-images').data('book-id');
+        const imageId = $button.data('image-id');
+
+        $.ajax({
+            url: '/setcoverimage',
+            type: 'POST',
+            data: { book_id: bookId, image_id: imageId },
+            success: function(response) {
+                $('.cover-image').removeClass('is-cover');
+                $button.addClass('is-cover');
+            },
+            error: function(xhr) {
+                console.log('Error setting the cover: ', xhr.responseText);
+            }
+        });
+    });
+
+    let $draggedImage = null;
+
+    $('#current-images').on('dragstart', '.image-container', function(e) {
+        $draggedImage = $(this);
+        $draggedImage.addClass('dragging');
+        e.originalEvent.dataTransfer.effectAllowed = 'move';
+    });
+
+    $('#current-images').on('dragover', '.image-container', function(e) {
+        e.preventDefault();
+        const $target = $(this);
+        if (!$draggedImage || $target.is($draggedImage)) {
+            return;
+        }
+
+        // Dropping on the right half of an image puts the dragged one after it.
+        const rect = this.getBoundingClientRect();
+        if (e.originalEvent.clientX > rect.left + rect.width / 2) {
+            $target.after($draggedImage);
+        } else {
+            $target.before($draggedImage);
+        }
+    });
+
+    $('#current-images').on('dragend', '.image-container', function() {
+        if (!$draggedImage) {
+            return;
+        }
+        $draggedImage.removeClass('dragging');
+        $draggedImage = null;
+
+        const $images = $('#current-images');
+        const imageIds = $images.find('.image-container').map(function() {
+            return $(this).data('image-id');
+        }).get();
+
+        $.ajax({
+            url: '/reorderimages',
+            type: 'POST',
+            data: { book_id: $images.data('book-id'), image_ids: imageIds.join(',') },
+            error: function(xhr) {
+                alert(xhr.responseText || 'Error guardando el orden de las imágenes');
+                window.location.reload();
+            }
+        });
+    });
+
     $('.badge[data-book-id]').each(async function() {
         const badgeElement = $(this);
         const bookID = badgeElement.data('book-id');
@@ -78,17 +144,49 @@ $(document).ready(function() {
                     }, 800);
             },
             error: function(xhr, status, error) {
-                // TODO: finish impl, show a message here
                 console.error('Error modificando libro:', error);
+                const infoModal = clickedElement.find('.info-modal');
+                infoModal.text(xhr.responseText || 'Error modificando libro');
+                infoModal.show();
             }
         });
     });
 
-    $('#bookForm').on('submit', function(e) {
-        e.preventDefault();
+    const duplicateReasons = {
+        'isbn': 'mismo ISBN',
+        'cover': 'misma portada',
+        'title-author': 'título y autor parecidos'
+    };
 
-        var formData = new FormData(this);
+    // A 409 from /addbook lists the books it may be a copy of, the form is sent again with
+    // confirm=true to add it anyway.
+    function showDuplicates(formData, response) {
+        const message = $('#bookFormMessage').removeClass('alert-success alert-danger').addClass('alert-warning').empty();
+        message.append($('<p>').text(response.message));
+
+        const list = $('<ul class="list-unstyled">');
+        response.matches.forEach(function(match) {
+            const item = $('<li class="mb-2">');
+            if (match.thumbnailURL) {
+                item.append($('<img class="img-thumbnail mr-2" width="48" alt="">').attr('src', match.thumbnailURL));
+            }
+            item.append($('<a target="_blank">').attr('href', match.url).text(match.title + ' by ' + match.author));
+            const reasons = match.reasons.map(function(reason) {
+                return duplicateReasons[reason] || reason;
+            });
+            item.append($('<small class="text-muted ml-2">').text('(' + reasons.join(', ') + ')'));
+            list.append(item);
+        });
+        message.append(list);
 
+        message.append($('<button type="button" class="btn btn-warning btn-sm">Agregarlo de todos modos</button>').click(function() {
+            formData.set('confirm', 'true');
+            submitBook(formData);
+        }));
+        message.show();
+    }
+
+    function submitBook(formData) {
         $.ajax({
             url: '/addbook',
             type: 'POST',
@@ -96,14 +194,29 @@ $(document).ready(function() {
             contentType: false,
             processData: false,
             success: function(response) {
-                // TODO: finish impl
                 console.log('Libro agregado con éxito', response);
+                $('#bookFormMessage').removeClass('alert-danger alert-warning').addClass('alert-success').text(response).show();
             },
             error: function(xhr, status, error) {
-                // TODO: finish impl
+                if (xhr.status === 409) {
+                    try {
+                        showDuplicates(formData, JSON.parse(xhr.responseText));
+                        return;
+                    } catch (e) {
+                        console.error('Respuesta inesperada:', e);
+                    }
+                }
                 console.error('Error al agregar el libro:', error);
+                $('#bookFormMessage').removeClass('alert-success alert-warning').addClass('alert-danger')
+                    .text(xhr.responseText || 'Error al agregar el libro').show();
             }
         });
+    }
+
+    $('#bookForm').on('submit', function(e) {
+        e.preventDefault();
+
+        submitBook(new FormData(this));
     });
 
     $('.like-emoji').click(async function() {
@@ -232,11 +345,11 @@ $(document).ready(function() {
     });*/
 
     $("input[name='author']").change(async function() {
-        const author = $(this).val();
-        if (author) {
+        const authorID = $(this).val();
+        if (authorID) {
             $("#booksList").empty();
             try {
-                const books = await $.get(`/api/books?start_with=${author}`);
+                const books = await $.get(`/api/books?author_id=${authorID}`);
                 console.log(books);
 
                 books.forEach(book => {
@@ -244,17 +357,23 @@ $(document).ready(function() {
                     if (book.images && book.images.length > 0) {
                         console.log(book.images);
                         book.images.forEach(image => {
-                            imagesHtml += `<img src="data:image/jpeg;base64,${image.Image}" class="card-img-bottom" alt="Image of ${book.title}">`;
+                            imagesHtml += `<img src="${image.mediumURL}" srcset="${image.mediumURL} 1x, ${image.url} 2x" class="card-img-bottom" loading="lazy" alt="Image of ${book.title}">`;
                         });
                     } else {
                         console.log('No images...');
                     }
 
+                    // Translators, editors... are listed after the author.
+                    const contributors = (book.contributors || [])
+                        .map(contributor => `${contributor.label}: ${contributor.name}`)
+                        .join(', ');
+
                     $("#booksList").append(`
                         <div class="card my-2">
                             <div class="card-body">
-                                <h5 class="card-title"><a href="book_info?id=${book.id}">${book.title}</a></h5>
+                                <h5 class="card-title"><a href="book_info?id=${book.id}">${book.title}</a>${book.on_loan ? ' <span class="badge badge-warning">Prestado</span>' : ''}</h5>
                                 <h6 class="card-subtitle mb-2 text-muted">${book.author}</h6>
+                                ${contributors ? `<p class="card-text small text-muted">${contributors}</p>` : ''}
                                 <p class="card-text">${book.description || ""}</p>
                             </div>
                             ${imagesHtml}
@@ -272,6 +391,81 @@ $(document).ready(function() {
         }
     });
 
+    // The book forms take any number of contributors, a row each.
+    $(document).on('click', '.add-contributor', function() {
+        const rows = $(this).closest('.contributors').find('.contributor-rows');
+        const row = rows.find('.contributor-row').first().clone();
+        row.find('input').val('');
+        row.find('select').prop('selectedIndex', 0);
+        rows.append(row);
+    });
+
+    $(document).on('click', '.remove-contributor', function() {
+        const rows = $(this).closest('.contributor-rows');
+        const row = $(this).closest('.contributor-row');
+        if (rows.find('.contributor-row').length > 1) {
+            row.remove();
+        } else {
+            row.find('input').val('');
+        }
+    });
+
+    // The modify form takes any number of read-throughs, a row each.
+    $(document).on('click', '.add-reading', function() {
+        const rows = $(this).closest('.readings').find('.reading-rows');
+        const row = rows.find('.reading-row').first().clone();
+        row.find('input').val('');
+        rows.append(row);
+    });
+
+    $(document).on('click', '.remove-reading', function() {
+        const rows = $(this).closest('.reading-rows');
+        const row = $(this).closest('.reading-row');
+        if (rows.find('.reading-row').length > 1) {
+            row.remove();
+        } else {
+            row.find('input').val('');
+        }
+    });
+
+    // The editions of the same work are folded under the first one the search found.
+    function collapseEditions() {
+        const firstOfWork = {};
+        const lastOfWork = {};
+        $('.results-list .result-item[data-work-id]').each(function() {
+            const workID = $(this).data('work-id');
+            if (!workID) {
+                return;
+            }
+
+            if (!firstOfWork[workID]) {
+                firstOfWork[workID] = lastOfWork[workID] = $(this);
+                return;
+            }
+
+            $(this).addClass(`d-none edition-of-work-${workID}`).insertAfter(lastOfWork[workID]);
+            lastOfWork[workID] = $(this);
+        });
+
+        Object.entries(firstOfWork).forEach(([workID, $first]) => {
+            const count = $(`.edition-of-work-${workID}`).length;
+            if (count === 0) {
+                return;
+            }
+
+            const label = count === 1 ? '1 edición más' : `${count} ediciones más`;
+            const $toggle = $(`<button type="button" class="btn btn-link btn-sm p-0 toggle-editions">Ver ${label}</button>`);
+            $toggle.on('click', function() {
+                const $editions = $(`.edition-of-work-${workID}`);
+                $editions.toggleClass('d-none');
+                $(this).text($editions.first().hasClass('d-none') ? `Ver ${label}` : `Ocultar ${label}`);
+            });
+            $first.append($toggle);
+        });
+    }
+
+    collapseEditions();
+
     async function updateBadgeCount(bookID) {
         console.log('Book ID to update: ' + bookID);
         const count = await loadLikesForBook(bookID);
@@ -0,0 +1,641 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"io"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	"leonlib/internal/imaging"
+	"leonlib/internal/migrate"
+	book "leonlib/internal/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const usage = `Uso: catalog <comando> [opciones]
+
+Comandos:
+  export [-o archivo.csv]            Exporta el catálogo completo a CSV
+  import [-dry-run] archivo.csv      Crea o actualiza libros a partir de un CSV
+  goodreads [-commit] export.csv     Concilia un goodreads_library_export.csv con el catálogo
+                                     y la wish list; sin -commit sólo muestra el reporte
+  calibre [-commit] directorio       Agrega los libros y portadas de una biblioteca de Calibre
+                                     (metadata.db o metadata.opf); sin -commit sólo muestra el reporte
+  bib -format bibtex|ris|marcxml [-id N | -search texto [-by byTitle,byAuthor]] [-o archivo]
+                                     Exporta un libro, una búsqueda o el catálogo completo como
+                                     referencias bibliográficas
+  migrate -from toml|sqlite|postgres -to memory|sqlite|postgres [-from-sqlite archivo]
+          [-to-sqlite archivo] [-state archivo]
+                                     Copia autores, estantes, obras, libros, préstamos, citas, las
+                                     wish lists con sus reservas, imágenes, usuarios, likes y reseñas
+                                     de un backend a otro conservando los IDs; si se interrumpe,
+                                     volver a ejecutarlo continúa donde se quedó. Al final compara
+                                     conteos y checksums
+  thumbnails [-force]                Genera las miniaturas y tamaños medianos que falten de las
+                                     imágenes existentes; con -force los vuelve a generar todos
+  images-gc                          Borra del almacén de imágenes las que ya ningún libro usa
+  works [-commit]                    Agrupa en obras los libros con el mismo título y autor, las
+                                     distintas ediciones; sin -commit sólo muestra el reporte
+  validate [-library archivo] [-images directorio]
+                                     Revisa library/books_db.toml contra images/: imágenes que
+                                     faltan, sin usar o repetidas, IDs repetidos, títulos vacíos,
+                                     fechas addedOn mal escritas, ISBN incorrectos, workID de
+                                     obras que no existen y datos de adquisición inválidos;
+                                     termina con error si encuentra alguno
+  loans [-overdue]                   Reporte de los libros prestados con los vencidos; con -overdue
+                                     termina con error si hay alguno vencido
+  quotes [-id N | -search texto] [-tag etiqueta] [-o archivo]
+                                     Exporta a Markdown las citas de un libro, las que coinciden
+                                     con una búsqueda o todas
+  valuation [-csv book|author|category] [-o archivo]
+                                     Totales de lo que se pagó y lo que valen los libros para el
+                                     seguro; con -csv los exporta por libro, autor o categoría
+
+La base de datos se elige con las mismas variables de entorno que la aplicación web
+(DB_MODE, PGHOST, PGPORT, PGUSER, POSTGRES_PASSWORD, PGDATABASE); IMAGE_STORE_DIR guarda las
+imágenes en ese directorio en lugar de la base de datos.
+`
+
+func newDAOFromEnv() (dao.DAO, error) {
+	dbMode := os.Getenv("DB_MODE")
+	if dbMode == "" {
+		dbMode = "sqlite"
+	}
+
+	return dao.NewDAO(dbMode, os.Getenv("PGHOST"), os.Getenv("PGPORT"), os.Getenv("PGUSER"), os.Getenv("POSTGRES_PASSWORD"), os.Getenv("PGDATABASE"))
+}
+
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "", "Archivo de salida (por omisión la salida estándar)")
+	_ = flags.Parse(args)
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return catalog.ExportCSV(bookDAO, w)
+}
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "Sólo muestra los cambios, no modifica la base de datos")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("import necesita el archivo CSV a importar")
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	if os.Getenv("DB_MODE") == "memory" && !*dryRun {
+		_, _ = fmt.Fprintln(os.Stderr, "Aviso: con DB_MODE=memory los cambios se pierden al terminar")
+	}
+
+	report, err := catalog.ImportCSV(bookDAO, f, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report)
+
+	if report.Count(catalog.ActionError) > 0 {
+		return fmt.Errorf("%d renglones con errores", report.Count(catalog.ActionError))
+	}
+
+	return nil
+}
+
+func runGoodreads(args []string) error {
+	flags := flag.NewFlagSet("goodreads", flag.ExitOnError)
+	commit := flags.Bool("commit", false, "Aplica los cambios; sin esta opción sólo se muestra el reporte")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("goodreads necesita el archivo goodreads_library_export.csv")
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	report, err := catalog.ImportGoodreads(bookDAO, f, *commit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report)
+
+	return nil
+}
+
+func runCalibre(args []string) error {
+	flags := flag.NewFlagSet("calibre", flag.ExitOnError)
+	commit := flags.Bool("commit", false, "Agrega los libros; sin esta opción sólo se muestra el reporte")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("calibre necesita el directorio de la biblioteca de Calibre")
+	}
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	report, err := catalog.ImportCalibre(bookDAO, flags.Arg(0), *commit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report)
+
+	return nil
+}
+
+func runBibliography(args []string) error {
+	flags := flag.NewFlagSet("bib", flag.ExitOnError)
+	formatName := flags.String("format", "bibtex", "Formato: bibtex, ris o marcxml")
+	bookID := flags.Int("id", 0, "Exporta sólo el libro con este id")
+	search := flags.String("search", "", "Exporta los libros que coinciden con el texto")
+	searchBy := flags.String("by", "byTitle", "Dónde buscar: byTitle, byAuthor o ambos separados por coma")
+	output := flags.String("o", "", "Archivo de salida (por omisión la salida estándar)")
+	_ = flags.Parse(args)
+
+	format, err := catalog.ParseBibliographicFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	var books []book.BookInfo
+	switch {
+	case *bookID > 0:
+		bookInfo, err := bookDAO.GetBookByID(*bookID)
+		if err != nil {
+			return err
+		}
+		books = append(books, bookInfo)
+
+	case *search != "":
+		seen := map[int]bool{}
+		for _, by := range strings.Split(*searchBy, ",") {
+			var searchType book.BookSearchType
+			switch strings.TrimSpace(by) {
+			case "byTitle":
+				searchType = book.ByTitle
+			case "byAuthor":
+				searchType = book.ByAuthor
+			default:
+				return fmt.Errorf("tipo de búsqueda desconocido: %q", by)
+			}
+
+			results, err := bookDAO.GetBooksBySearchTypeCoincidence(*search, searchType)
+			if err != nil {
+				return err
+			}
+			for _, result := range results {
+				if !seen[result.ID] {
+					seen[result.ID] = true
+					books = append(books, result)
+				}
+			}
+		}
+
+	default:
+		books, err = bookDAO.GetAllBooks()
+		if err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return catalog.WriteBibliography(w, format, books)
+}
+
+// openMigrationDAO opens one side of a migration. "toml" (or "memory" as a source) is the library in
+// library/ and images/, loaded the same way the web app does in memory mode.
+func openMigrationDAO(backend, sqlitePath string, isSource bool) (dao.DAO, error) {
+	switch backend {
+	case "toml", "memory":
+		if isSource {
+			return dao.NewDAO("memory", "", "", "", "", "")
+		}
+		if backend == "toml" {
+			return nil, fmt.Errorf("no se puede migrar hacia toml: los usuarios y likes no tienen lugar en books_db.toml, usa export para los libros")
+		}
+		return dao.OpenDAO("memory", "", "", "", "", "")
+
+	case "sqlite":
+		dao.SQLitePath = sqlitePath
+		return dao.OpenDAO("sqlite", "", "", "", "", "")
+
+	case "postgres":
+		return dao.OpenDAO("postgres", os.Getenv("PGHOST"), os.Getenv("PGPORT"), os.Getenv("PGUSER"), os.Getenv("POSTGRES_PASSWORD"), os.Getenv("PGDATABASE"))
+
+	default:
+		return nil, fmt.Errorf("backend desconocido: %q", backend)
+	}
+}
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := flags.String("from", "", "Backend de origen: toml, sqlite o postgres")
+	to := flags.String("to", "", "Backend de destino: memory (sólo verifica), sqlite o postgres")
+	fromSQLite := flags.String("from-sqlite", "", "Archivo sqlite de origen (por omisión "+dao.SQLitePath+")")
+	toSQLite := flags.String("to-sqlite", "", "Archivo sqlite de destino (por omisión "+dao.SQLitePath+")")
+	statePath := flags.String("state", "migrate.state.json", "Archivo donde se guarda el avance para poder continuar")
+	_ = flags.Parse(args)
+
+	if *from == "" || *to == "" {
+		return fmt.Errorf("migrate necesita -from y -to")
+	}
+
+	if *fromSQLite == "" {
+		*fromSQLite = dao.SQLitePath
+	}
+	if *toSQLite == "" {
+		*toSQLite = dao.SQLitePath
+	}
+
+	if *from == *to && (*from != "sqlite" || *fromSQLite == *toSQLite) {
+		return fmt.Errorf("el origen y el destino son la misma base de datos")
+	}
+
+	source, err := openMigrationDAO(*from, *fromSQLite, true)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := openMigrationDAO(*to, *toSQLite, false)
+	if err != nil {
+		return err
+	}
+	defer destination.Close()
+
+	sourceName := *from
+	if *from == "sqlite" {
+		sourceName += ":" + *fromSQLite
+	}
+	destinationName := *to
+	if *to == "sqlite" {
+		destinationName += ":" + *toSQLite
+	}
+
+	logf := func(format string, args ...any) {
+		_, _ = fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+
+	summary, err := migrate.Migrate(source, destination, sourceName, destinationName, *statePath, logf)
+	if err != nil {
+		return fmt.Errorf("%v (vuelve a ejecutar el mismo comando para continuar)", err)
+	}
+
+	fmt.Print(summary)
+
+	if !summary.OK() {
+		return fmt.Errorf("el destino no coincide con el origen")
+	}
+
+	return nil
+}
+
+func runThumbnails(args []string) error {
+	flags := flag.NewFlagSet("thumbnails", flag.ExitOnError)
+	force := flags.Bool("force", false, "Vuelve a generar también las variantes que ya existen")
+	_ = flags.Parse(args)
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	if os.Getenv("DB_MODE") == "memory" {
+		_, _ = fmt.Fprintln(os.Stderr, "Aviso: con DB_MODE=memory las variantes se generan al pedirse y se pierden al terminar")
+	}
+
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return err
+	}
+
+	var generated, skipped, failed int
+	for _, bookInfo := range books {
+		images, err := bookDAO.GetImagesByBookID(bookInfo.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, image := range images {
+			for _, variant := range imaging.Variants {
+				if !*force {
+					if _, err := bookDAO.GetImageVariant(image.ImageID, variant); err == nil {
+						skipped++
+						continue
+					} else if !errors.Is(err, dao.ErrImageVariantNotFound) {
+						return err
+					}
+				}
+
+				original, err := bookDAO.GetImage(image.ImageID)
+				if err != nil {
+					return err
+				}
+
+				data, err := imaging.Resize(original.Data, variant)
+				if err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "imagen %d del libro %d: %v\n", image.ImageID, bookInfo.ID, err)
+					failed++
+					continue
+				}
+
+				if err := bookDAO.SaveImageVariant(image.ImageID, variant, data); err != nil {
+					return err
+				}
+				generated++
+			}
+		}
+	}
+
+	fmt.Printf("%d variantes generadas, %d ya existían, %d imágenes no se pudieron leer\n", generated, skipped, failed)
+
+	return nil
+}
+
+func runImagesGC(args []string) error {
+	flags := flag.NewFlagSet("images-gc", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	removed, err := bookDAO.CollectImageGarbage()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d imágenes sin referencias borradas\n", removed)
+
+	return nil
+}
+
+func runWorks(args []string) error {
+	flags := flag.NewFlagSet("works", flag.ExitOnError)
+	commit := flags.Bool("commit", false, "Crea las obras y agrupa los libros en lugar de sólo mostrar el reporte")
+	_ = flags.Parse(args)
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	report, err := catalog.GroupWorks(bookDAO, *commit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report)
+
+	return nil
+}
+
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	libraryPath := flags.String("library", filepath.Join("library", "books_db.toml"), "Archivo TOML de la biblioteca")
+	imagesDir := flags.String("images", "images", "Directorio de las imágenes")
+	_ = flags.Parse(args)
+
+	report, err := catalog.ValidateLibrary(*libraryPath, *imagesDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report)
+
+	if !report.OK() {
+		return fmt.Errorf("la biblioteca tiene %d problemas", len(report.Issues))
+	}
+
+	return nil
+}
+
+func runLoans(args []string) error {
+	flags := flag.NewFlagSet("loans", flag.ExitOnError)
+	overdue := flags.Bool("overdue", false, "Termina con error si hay préstamos vencidos")
+	_ = flags.Parse(args)
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	report, err := catalog.LentBooks(bookDAO, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report)
+
+	if *overdue && len(report.Overdue) > 0 {
+		return fmt.Errorf("hay %d préstamos vencidos", len(report.Overdue))
+	}
+
+	return nil
+}
+
+func runQuotes(args []string) error {
+	flags := flag.NewFlagSet("quotes", flag.ExitOnError)
+	bookID := flags.Int("id", 0, "Exporta sólo las citas del libro con este id")
+	search := flags.String("search", "", "Exporta las citas que tienen todas las palabras del texto")
+	tag := flags.String("tag", "", "Exporta sólo las citas con esta etiqueta")
+	output := flags.String("o", "", "Archivo de salida (por omisión la salida estándar)")
+	_ = flags.Parse(args)
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	var quotes []book.Quote
+	if *bookID > 0 {
+		quotes, err = bookDAO.GetQuotesByBookID(*bookID)
+	} else {
+		quotes, err = bookDAO.SearchQuotes(*search)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *tag != "" {
+		var tagged []book.Quote
+		for _, quote := range quotes {
+			if quote.HasTag(*tag) {
+				tagged = append(tagged, quote)
+			}
+		}
+		quotes = tagged
+	}
+
+	quotedBooks, err := catalog.QuotesByBook(bookDAO, quotes)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return catalog.WriteQuotesMarkdown(w, quotedBooks)
+}
+
+func runValuation(args []string) error {
+	flags := flag.NewFlagSet("valuation", flag.ExitOnError)
+	csvBy := flags.String("csv", "", "Exporta la valuación a CSV por book, author o category")
+	output := flags.String("o", "", "Archivo de salida (por omisión la salida estándar)")
+	_ = flags.Parse(args)
+
+	by, ok := catalog.ParseValuationBy(*csvBy)
+	if !ok {
+		return fmt.Errorf("-csv debe ser book, author o category, no %q", *csvBy)
+	}
+
+	bookDAO, err := newDAOFromEnv()
+	if err != nil {
+		return err
+	}
+	defer bookDAO.Close()
+
+	report, err := catalog.Valuation(bookDAO)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *csvBy == "" {
+		_, err = fmt.Fprint(w, report)
+		return err
+	}
+
+	return catalog.WriteValuationCSV(w, report, by)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	dao.ImageStoreDir = os.Getenv("IMAGE_STORE_DIR")
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "goodreads":
+		err = runGoodreads(os.Args[2:])
+	case "calibre":
+		err = runCalibre(os.Args[2:])
+	case "bib":
+		err = runBibliography(os.Args[2:])
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "thumbnails":
+		err = runThumbnails(os.Args[2:])
+	case "images-gc":
+		err = runImagesGC(os.Args[2:])
+	case "works":
+		err = runWorks(os.Args[2:])
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "loans":
+		err = runLoans(os.Args[2:])
+	case "quotes":
+		err = runQuotes(os.Args[2:])
+	case "valuation":
+		err = runValuation(os.Args[2:])
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
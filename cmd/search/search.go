@@ -23,11 +23,48 @@ func createInMemoryDatabaseFromFile() (map[int]model.BookInfo, error) {
 
 	db := make(map[int]model.BookInfo)
 	for _, book := range library.Book {
+		book.NormalizeReading()
 		db[book.ID] = book
 	}
 	return db, nil
 }
 
+// readingOf describes where the book is in its reading: "Leyendo, página 120 de 300 (40%)".
+func readingOf(book model.BookInfo) string {
+	text := book.ReadingStatus.Label()
+	if reading := book.CurrentReading(); reading != nil && reading.CurrentPage > 0 {
+		text += fmt.Sprintf(", página %d", reading.CurrentPage)
+		if book.Pages > 0 {
+			text += fmt.Sprintf(" de %d (%d%%)", book.Pages, book.Progress())
+		}
+	} else if book.Pages > 0 {
+		text += fmt.Sprintf(", %d páginas", book.Pages)
+	}
+
+	if times := book.TimesRead(); times > 1 {
+		text += fmt.Sprintf(", leído %d veces", times)
+	}
+
+	return text
+}
+
+// readThrough describes a read-through: "del 2024-01-02 al 2024-02-10".
+func readThrough(reading model.Reading) string {
+	text := "sin fecha de inicio"
+	if !reading.StartedOn.IsZero() {
+		text = "del " + reading.StartedOn.Format(model.AddedOnLayout)
+	}
+
+	if reading.Finished() {
+		return text + " al " + reading.FinishedOn.Format(model.AddedOnLayout)
+	}
+	if reading.CurrentPage > 0 {
+		return fmt.Sprintf("%s, hasta la página %d", text, reading.CurrentPage)
+	}
+
+	return text + ", sin terminar"
+}
+
 func searchBooks(books map[int]model.BookInfo, query string, searchByTitle, searchByAuthor bool) {
 	matchesCount := 0
 	query = strings.ToLower(query)
@@ -46,10 +83,9 @@ func searchBooks(books map[int]model.BookInfo, query string, searchByTitle, sear
 			}
 			fmt.Printf("id: %d\n", book.ID)
 			fmt.Printf("Agregado el: %s\n", book.AddedOn)
-			if book.HasBeenRead {
-				fmt.Println("Leído: sí")
-			} else {
-				fmt.Println("Leído: no")
+			fmt.Printf("Lectura: %s\n", readingOf(book))
+			for _, reading := range book.Readings {
+				fmt.Printf("  %s\n", readThrough(reading))
 			}
 			fmt.Println()
 			matchesCount++
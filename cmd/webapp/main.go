@@ -57,7 +57,12 @@ func init() {
 	}
 
 	auth.SessionStore = sessions.NewCookieStore([]byte(os.Getenv("SESSION_SECRET")))
+	auth.ShareKey = []byte(os.Getenv("LEONLIB_GIFT_LIST_SECRET"))
+	if len(auth.ShareKey) == 0 {
+		log.Fatal("error: LEONLIB_GIFT_LIST_SECRET not defined")
+	}
 	auth.MainUser = os.Getenv("LEONLIB_MAINAPP_USER")
+	dao.ImageStoreDir = os.Getenv("IMAGE_STORE_DIR")
 }
 
 func main() {
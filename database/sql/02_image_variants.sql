@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS book_image_variants (
+    image_id INTEGER NOT NULL REFERENCES book_images(image_id) ON DELETE CASCADE,
+    variant TEXT NOT NULL,
+    image BYTEA NOT NULL,
+    PRIMARY KEY (image_id, variant)
+);
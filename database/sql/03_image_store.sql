@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS image_blobs (
+    hash TEXT PRIMARY KEY,
+    data BYTEA NOT NULL,
+    added_on TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE book_images ADD COLUMN IF NOT EXISTS image_hash TEXT;
+
+-- Databases created before the image store keep the bytes in book_images.image: move them to
+-- image_blobs, keyed by their SHA-256, and reference them by hash.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'book_images' AND column_name = 'image') THEN
+        DELETE FROM book_images WHERE length(image) = 0;
+
+        INSERT INTO image_blobs(hash, data)
+        SELECT encode(sha256(image), 'hex'), image FROM book_images WHERE image_hash IS NULL
+        ON CONFLICT (hash) DO NOTHING;
+
+        UPDATE book_images SET image_hash = encode(sha256(image), 'hex') WHERE image_hash IS NULL;
+
+        ALTER TABLE book_images DROP COLUMN image;
+    END IF;
+END $$;
+
+ALTER TABLE book_images ALTER COLUMN image_hash SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_book_images_image_hash ON book_images USING btree (image_hash);
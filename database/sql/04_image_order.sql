@@ -0,0 +1,15 @@
+-- The images of a book are shown by position, the one flagged as cover (or else the first) is the
+-- one listings show.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'book_images' AND column_name = 'position') THEN
+        ALTER TABLE book_images ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+        ALTER TABLE book_images ADD COLUMN is_cover BOOLEAN NOT NULL DEFAULT FALSE;
+
+        UPDATE book_images i SET position = o.position
+        FROM (SELECT image_id, row_number() OVER (PARTITION BY book_id ORDER BY image_id) - 1 AS position FROM book_images) o
+        WHERE i.image_id = o.image_id;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_book_images_cover ON book_images USING btree (book_id) WHERE is_cover;
@@ -0,0 +1,5 @@
+-- Upgrades a database created before the books had an ISBN. The ISBN-13 is stored without hyphens,
+-- the ISBN-10 of older books are converted when they are saved.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS isbn VARCHAR(13);
+
+CREATE INDEX IF NOT EXISTS idx_books_isbn ON books USING btree (isbn);
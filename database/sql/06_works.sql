@@ -0,0 +1,15 @@
+-- Groups the editions of the same work ("Ulises" by Colofón, Lumen, Alianza...) and describes each
+-- edition with its own columns instead of the description.
+CREATE TABLE IF NOT EXISTS works (
+   id SERIAL PRIMARY KEY,
+   title VARCHAR(255) NOT NULL,
+   author VARCHAR(255) NOT NULL
+);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS work_id INTEGER REFERENCES works(id);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS publisher VARCHAR(255);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS published_year INTEGER;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS language VARCHAR(64);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS format VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_books_work_id ON books USING btree (work_id);
@@ -0,0 +1,21 @@
+-- The people of a book with what they did for it: authors, translators, editors, illustrators and
+-- prologues. books.author stays as the names of the authors, for the listings.
+CREATE TABLE IF NOT EXISTS book_contributors (
+   book_id INTEGER NOT NULL REFERENCES books(id),
+   position INTEGER NOT NULL,
+   name VARCHAR(255) NOT NULL,
+   role VARCHAR(32) NOT NULL,
+   PRIMARY KEY (book_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_contributors_name ON book_contributors USING btree (name);
+
+-- The authors of the books are split as the application does: "Kathy Sierra, Bert Bates" and
+-- "Eric Freeman & Elisabeth Freeman" are two people, "Henry S. Warren, JR." is one.
+INSERT INTO book_contributors (book_id, position, name, role)
+SELECT b.id, a.position - 1, trim(a.name), 'author'
+FROM books b
+CROSS JOIN LATERAL regexp_split_to_table(b.author, '\s*[,;&]\s*(?!(jr|sr|ii|iii)\.?\s*([,;&]|$))', 'i')
+    WITH ORDINALITY AS a(name, position)
+WHERE trim(a.name) <> ''
+  AND NOT EXISTS (SELECT 1 FROM book_contributors c WHERE c.book_id = b.id);
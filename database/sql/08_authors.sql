@@ -0,0 +1,47 @@
+-- The people of the library, however their name is written on the books. The contributors of a
+-- book keep the name as the book writes it, and point to the author they are.
+CREATE TABLE IF NOT EXISTS authors (
+   id SERIAL PRIMARY KEY,
+   name VARCHAR(255) NOT NULL,
+   sort_name VARCHAR(255) NOT NULL,
+   bio TEXT,
+   photo_url TEXT
+);
+
+-- The other ways the name of an author is written, the names of merged authors among them.
+CREATE TABLE IF NOT EXISTS author_aliases (
+   alias VARCHAR(255) PRIMARY KEY,
+   author_id INTEGER NOT NULL REFERENCES authors(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_author_aliases_author_id ON author_aliases USING btree (author_id);
+
+ALTER TABLE book_contributors ADD COLUMN IF NOT EXISTS author_id INTEGER REFERENCES authors(id);
+
+CREATE INDEX IF NOT EXISTS idx_book_contributors_author_id ON book_contributors USING btree (author_id);
+
+-- Every name of the contributors becomes an author, sorted as types.SortName does and with its
+-- sortNamePattern: "James Joyce" is "Joyce, James" and "Henry S. Warren, JR." is
+-- "Warren, Henry S., JR.". The names it cannot guess, "Gabriel García Márquez", get an empty sort
+-- name for an admin to set.
+INSERT INTO authors (name, sort_name)
+SELECT DISTINCT ON (lower(c.name)) c.name,
+       CASE WHEN n.name ~ ',' AND n.name !~* ',\s*(jr\.?|sr\.?|ii|iii)$' THEN n.name
+            WHEN n.base ~ ',' OR n.base !~ ' ' THEN n.base || n.suffix
+            WHEN n.base ~ '(?i)^([^ ]+(?: [^ .]\.)*)((?: (?:de|del|la|las|los|da|das|do|dos|di|du|le|van|von|der|den))*) ([^ ]+(?: y [^ ]+)*)$'
+                 THEN regexp_replace(n.base, '(?i)^([^ ]+(?: [^ .]\.)*)((?: (?:de|del|la|las|los|da|das|do|dos|di|du|le|van|von|der|den))*) ([^ ]+(?: y [^ ]+)*)$', '\3, \1\2') || n.suffix
+            ELSE ''
+       END
+FROM book_contributors c,
+     LATERAL (SELECT regexp_replace(btrim(c.name), '\s+', ' ', 'g') AS name) s,
+     LATERAL (SELECT s.name AS name,
+                     btrim(regexp_replace(s.name, ',[^,]*$', '')) AS base,
+                     coalesce(substring(s.name from ',[^,]*$'), '') AS suffix) n
+WHERE c.author_id IS NULL
+  AND NOT EXISTS (SELECT 1 FROM authors a WHERE lower(a.name) = lower(c.name))
+ORDER BY lower(c.name), c.name;
+
+UPDATE book_contributors c
+SET author_id = a.id
+FROM authors a
+WHERE c.author_id IS NULL AND lower(a.name) = lower(c.name);
@@ -0,0 +1,13 @@
+-- Free-form tags of the books, stored lowercase, and the category they are filed in: a path of the
+-- category tree written as "Literatura › Novela › Posmodernismo".
+CREATE TABLE IF NOT EXISTS book_tags (
+   book_id INTEGER NOT NULL REFERENCES books(id),
+   tag VARCHAR(255) NOT NULL,
+   PRIMARY KEY (book_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_tags_tag ON book_tags USING btree (tag);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS category VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_books_category ON books USING btree (category);
@@ -0,0 +1,13 @@
+-- The shelves the books are on, in the bookcases of the rooms of the house.
+CREATE TABLE IF NOT EXISTS shelves (
+   id SERIAL PRIMARY KEY,
+   room VARCHAR(255) NOT NULL DEFAULT '',
+   bookcase VARCHAR(255) NOT NULL DEFAULT '',
+   name VARCHAR(255) NOT NULL
+);
+
+-- Where a book is: its shelf and its place on it, counted from the left starting at 1.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS shelf_id INTEGER REFERENCES shelves(id);
+ALTER TABLE books ADD COLUMN IF NOT EXISTS shelf_position INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_books_shelf_id ON books USING btree (shelf_id, shelf_position);
@@ -0,0 +1,15 @@
+-- The books lent to friends: who has them, since when, when they should be back and when they came
+-- back. A book is lent to one person at a time.
+CREATE TABLE IF NOT EXISTS loans (
+   id SERIAL PRIMARY KEY,
+   book_id INTEGER NOT NULL REFERENCES books(id),
+   borrower VARCHAR(255) NOT NULL,
+   loaned_on DATE NOT NULL,
+   due_on DATE,
+   returned_on DATE,
+   notes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_loans_book_id ON loans USING btree (book_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loans_lent ON loans (book_id) WHERE returned_on IS NULL;
@@ -0,0 +1,18 @@
+-- Where a book is in its reading (to-read, reading, read, abandoned, re-reading) and its pages.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS reading_status VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS pages INTEGER;
+
+-- The read-throughs of a book, in the order they were read: when they started, when they finished
+-- and the page they got to.
+CREATE TABLE IF NOT EXISTS book_readings (
+   book_id INTEGER NOT NULL REFERENCES books(id),
+   position INTEGER NOT NULL,
+   started_on DATE,
+   finished_on DATE,
+   current_page INTEGER NOT NULL DEFAULT 0,
+   PRIMARY KEY (book_id, position)
+);
+
+-- The books that were only marked as read are read.
+UPDATE books SET reading_status = 'read'
+WHERE read AND reading_status = '' AND NOT EXISTS (SELECT 1 FROM book_readings WHERE book_id = books.id);
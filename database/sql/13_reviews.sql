@@ -0,0 +1,13 @@
+-- What the users think of the books: a rating in half stars (0 is not rated), a review in Markdown
+-- everyone can read and notes only the user sees.
+CREATE TABLE IF NOT EXISTS book_reviews (
+   book_id INTEGER NOT NULL REFERENCES books(id),
+   user_id TEXT NOT NULL REFERENCES users(user_id),
+   rating INTEGER NOT NULL DEFAULT 0 CHECK (rating BETWEEN 0 AND 10),
+   review TEXT NOT NULL DEFAULT '',
+   notes TEXT NOT NULL DEFAULT '',
+   updated_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   PRIMARY KEY (book_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_reviews_user_id ON book_reviews USING btree (user_id);
@@ -0,0 +1,19 @@
+-- Favourite passages of the books, with the page they are on (NULL when it is not known) and
+-- free-form tags stored lowercase like the ones of the books.
+CREATE TABLE IF NOT EXISTS quotes (
+   id SERIAL PRIMARY KEY,
+   book_id INTEGER NOT NULL REFERENCES books(id),
+   quote TEXT NOT NULL,
+   page INTEGER CHECK (page > 0),
+   added_on DATE NOT NULL DEFAULT CURRENT_DATE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quotes_book_id ON quotes USING btree (book_id);
+
+CREATE TABLE IF NOT EXISTS quote_tags (
+   quote_id INTEGER NOT NULL REFERENCES quotes(id),
+   tag VARCHAR(255) NOT NULL,
+   PRIMARY KEY (quote_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quote_tags_tag ON quote_tags USING btree (tag);
@@ -0,0 +1,8 @@
+-- How a book was bought and what it is worth, for the insurance: the amounts are in cents of the
+-- currency, an ISO 4217 code like MXN.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS purchased_on DATE;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS purchase_price BIGINT;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS purchased_from VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS book_condition VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS estimated_value BIGINT;
@@ -0,0 +1,11 @@
+-- The books of the wish list, which were only read from library/wish_list.toml: priority is 1 for
+-- the most wanted, 2 normal and 3 low.
+CREATE TABLE IF NOT EXISTS wish_list_books (
+   id SERIAL PRIMARY KEY,
+   title VARCHAR(255) NOT NULL,
+   author VARCHAR(255) NOT NULL DEFAULT '',
+   description TEXT NOT NULL DEFAULT '',
+   image_link TEXT NOT NULL DEFAULT '',
+   goodreads_link TEXT NOT NULL DEFAULT '',
+   priority INTEGER NOT NULL DEFAULT 2 CHECK (priority BETWEEN 1 AND 3)
+);
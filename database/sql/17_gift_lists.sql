@@ -0,0 +1,11 @@
+-- Every logged-in user keeps a wish list of their own, the library's is the one without user, and
+-- friends reserve its books as gifts without the owner knowing.
+ALTER TABLE wish_list_books ADD COLUMN IF NOT EXISTS user_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_wish_list_books_user_id ON wish_list_books (user_id);
+
+CREATE TABLE IF NOT EXISTS wish_list_reservations (
+   wish_list_book_id INTEGER PRIMARY KEY REFERENCES wish_list_books(id),
+   user_id TEXT NOT NULL REFERENCES users(user_id),
+   reserved_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/gorilla/sessions"
 	"golang.org/x/oauth2"
 )
@@ -9,4 +12,20 @@ var (
 	SessionStore *sessions.CookieStore
 	Config       *oauth2.Config
 	MainUser     string
+	// ShareKey signs the links to the gift lists, apart from the secret of the sessions.
+	ShareKey []byte
 )
+
+// GiftListToken signs the link to the gift list of a user: whoever gets the link sees the list, and
+// nobody can guess the one of somebody else.
+func GiftListToken(userID string) string {
+	mac := hmac.New(sha256.New, ShareKey)
+	mac.Write([]byte("gift-list\x00" + userID))
+
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// ValidGiftListToken tells whether token is the one of the gift list of the user.
+func ValidGiftListToken(userID, token string) bool {
+	return hmac.Equal([]byte(GiftListToken(userID)), []byte(token))
+}
@@ -0,0 +1,125 @@
+package catalog
+
+import (
+	book "leonlib/internal/types"
+	"sort"
+	"strings"
+)
+
+// minNameSimilarity tells "Marysé Condé" and "Maryse Conde" or "Gabriel Garcia Marquez" and
+// "Gabriel García Márquez" are written by the same hand, while "Henry James" and "William James"
+// are not.
+const minNameSimilarity = 0.85
+
+// AuthorVariant is an author that is likely another way to write the name of Author.
+type AuthorVariant struct {
+	Author  book.Author
+	Variant book.Author
+	// Score goes from 0 to 1, 1 being the same name once accents and punctuation are left out.
+	Score float64
+}
+
+// FindAuthorVariants looks for the authors that are likely the same person: "G. García Márquez",
+// "García Márquez, Gabriel" and "Gabriel Garcia Marquez" are variants of "Gabriel García Márquez".
+// The author with more books (or the longer name) is the one the variant would be merged into. The
+// most likely variants come first.
+func FindAuthorVariants(authors []book.Author) []AuthorVariant {
+	names := make([][]string, len(authors))
+	for i, author := range authors {
+		names[i] = strings.Fields(normalizeText(author.Name))
+	}
+
+	var variants []AuthorVariant
+	for i := range authors {
+		for j := i + 1; j < len(authors); j++ {
+			score := nameScore(names[i], names[j])
+			if score == 0 {
+				continue
+			}
+
+			author, variant := authors[i], authors[j]
+			if preferredName(variant, author) {
+				author, variant = variant, author
+			}
+			variants = append(variants, AuthorVariant{Author: author, Variant: variant, Score: score})
+		}
+	}
+
+	sort.SliceStable(variants, func(i, j int) bool {
+		if variants[i].Score != variants[j].Score {
+			return variants[i].Score > variants[j].Score
+		}
+
+		return strings.ToLower(variants[i].Author.SortKey()) < strings.ToLower(variants[j].Author.SortKey())
+	})
+
+	return variants
+}
+
+// preferredName tells if a is the name to keep over b: the one of more books, else the longer one.
+func preferredName(a, b book.Author) bool {
+	if a.Books != b.Books {
+		return a.Books > b.Books
+	}
+
+	if len(a.Name) != len(b.Name) {
+		return len(a.Name) > len(b.Name)
+	}
+
+	return a.ID < b.ID
+}
+
+// nameScore compares two normalized names, 0 when they are of different people.
+func nameScore(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	if strings.Join(a, " ") == strings.Join(b, " ") {
+		return 1
+	}
+
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) >= 2 && abbreviates(a, b) {
+		return 0.9
+	}
+
+	if score := similarity(strings.Join(a, " "), strings.Join(b, " ")); score >= minNameSimilarity {
+		return score
+	}
+
+	return 0
+}
+
+// abbreviates tells if every word of short is a word of long or its initial, in any order, and at
+// least one of them is a whole word: "g garcia marquez" and "garcia marquez gabriel" abbreviate
+// "gabriel garcia marquez".
+func abbreviates(short, long []string) bool {
+	used := make([]bool, len(long))
+	wholeWords := 0
+	for _, word := range short {
+		found := false
+		for i, other := range long {
+			if used[i] {
+				continue
+			}
+
+			if word == other || (len([]rune(word)) == 1 && strings.HasPrefix(other, word)) {
+				used[i] = true
+				found = true
+				if word == other && len([]rune(word)) > 1 {
+					wholeWords++
+				}
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return wholeWords > 0
+}
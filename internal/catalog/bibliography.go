@@ -0,0 +1,334 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	book "leonlib/internal/types"
+	"strconv"
+	"strings"
+)
+
+// BibliographicFormat is one of the citation formats the catalogue can be exported to.
+type BibliographicFormat string
+
+const (
+	BibTeX  BibliographicFormat = "bibtex"
+	RIS     BibliographicFormat = "ris"
+	MARCXML BibliographicFormat = "marcxml"
+)
+
+func ParseBibliographicFormat(input string) (BibliographicFormat, error) {
+	switch format := BibliographicFormat(strings.TrimSpace(strings.ToLower(input))); format {
+	case BibTeX, RIS, MARCXML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown bibliographic format %q, use bibtex, ris or marcxml", input)
+	}
+}
+
+func (f BibliographicFormat) ContentType() string {
+	switch f {
+	case BibTeX:
+		return "application/x-bibtex; charset=utf-8"
+	case RIS:
+		return "application/x-research-info-systems; charset=utf-8"
+	default:
+		return "application/marcxml+xml; charset=utf-8"
+	}
+}
+
+func (f BibliographicFormat) Extension() string {
+	switch f {
+	case BibTeX:
+		return "bib"
+	case RIS:
+		return "ris"
+	default:
+		return "xml"
+	}
+}
+
+// WriteBibliography writes the books in the given format.
+func WriteBibliography(w io.Writer, format BibliographicFormat, books []book.BookInfo) error {
+	switch format {
+	case BibTeX:
+		return writeBibTeX(w, books)
+	case RIS:
+		return writeRIS(w, books)
+	case MARCXML:
+		return writeMARCXML(w, books)
+	default:
+		return fmt.Errorf("unknown bibliographic format %q", format)
+	}
+}
+
+var bibTeXReplacements = map[rune]string{
+	'\\': `\textbackslash{}`,
+	'{':  `\{`,
+	'}':  `\}`,
+	'&':  `\&`,
+	'%':  `\%`,
+	'$':  `\$`,
+	'#':  `\#`,
+	'_':  `\_`,
+	'~':  `\textasciitilde{}`,
+	'^':  `\textasciicircum{}`,
+	'á':  `{\'a}`, 'é': `{\'e}`, 'í': `{\'i}`, 'ó': `{\'o}`, 'ú': `{\'u}`, 'ý': `{\'y}`,
+	'Á': `{\'A}`, 'É': `{\'E}`, 'Í': `{\'I}`, 'Ó': `{\'O}`, 'Ú': `{\'U}`, 'Ý': `{\'Y}`,
+	'à': "{\\`a}", 'è': "{\\`e}", 'ì': "{\\`i}", 'ò': "{\\`o}", 'ù': "{\\`u}",
+	'À': "{\\`A}", 'È': "{\\`E}", 'Ì': "{\\`I}", 'Ò': "{\\`O}", 'Ù': "{\\`U}",
+	'ä': `{\"a}`, 'ë': `{\"e}`, 'ï': `{\"i}`, 'ö': `{\"o}`, 'ü': `{\"u}`,
+	'Ä': `{\"A}`, 'Ë': `{\"E}`, 'Ï': `{\"I}`, 'Ö': `{\"O}`, 'Ü': `{\"U}`,
+	'â': `{\^a}`, 'ê': `{\^e}`, 'î': `{\^i}`, 'ô': `{\^o}`, 'û': `{\^u}`,
+	'ñ': `{\~n}`, 'Ñ': `{\~N}`, 'ã': `{\~a}`, 'õ': `{\~o}`,
+	'ç': `{\c c}`, 'Ç': `{\c C}`,
+	'¿': "{?`}", '¡': "{!`}",
+	'«': `{\guillemotleft}`, '»': `{\guillemotright}`,
+	'–': `--`, '—': `---`,
+}
+
+// escapeBibTeX turns the text into plain ASCII LaTeX, so "Márquez" becomes "M{\'a}rquez".
+func escapeBibTeX(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if replacement, ok := bibTeXReplacements[r]; ok {
+			sb.WriteString(replacement)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func bibTeXKey(bookInfo book.BookInfo) string {
+	return "leonlib" + strconv.Itoa(bookInfo.ID)
+}
+
+// editionYear is the year of the edition of the book, empty when it is not known.
+func editionYear(bookInfo book.BookInfo) string {
+	if bookInfo.Year <= 0 {
+		return ""
+	}
+
+	return strconv.Itoa(bookInfo.Year)
+}
+
+// authors returns the names of the authors of the book, or its author as it is when it has no
+// contributors.
+func authors(bookInfo book.BookInfo) []string {
+	if names := bookInfo.ContributorsAs(book.RoleAuthor); len(names) > 0 {
+		return names
+	}
+
+	if bookInfo.Author == "" {
+		return nil
+	}
+
+	return []string{bookInfo.Author}
+}
+
+func writeBibTeX(w io.Writer, books []book.BookInfo) error {
+	for _, bookInfo := range books {
+		fields := [][2]string{
+			{"title", bookInfo.Title},
+			{"author", strings.Join(authors(bookInfo), " and ")},
+			{"editor", strings.Join(bookInfo.ContributorsAs(book.RoleEditor), " and ")},
+			{"publisher", bookInfo.Publisher},
+			{"year", editionYear(bookInfo)},
+			{"translator", strings.Join(bookInfo.ContributorsAs(book.RoleTranslator), " and ")},
+			{"language", bookInfo.Language},
+			{"isbn", bookInfo.ISBN},
+			{"note", bookInfo.Description},
+			{"url", bookInfo.GoodreadsLink},
+		}
+
+		if _, err := fmt.Fprintf(w, "@book{%s,\n", bibTeXKey(bookInfo)); err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if field[1] == "" {
+				continue
+			}
+			value := oneLine(field[1])
+			if field[0] != "url" {
+				value = escapeBibTeX(value)
+			}
+			if _, err := fmt.Fprintf(w, "  %s = {%s},\n", field[0], value); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprint(w, "}\n\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeRIS(w io.Writer, books []book.BookInfo) error {
+	for _, bookInfo := range books {
+		lines := [][2]string{
+			{"TY", "BOOK"},
+			{"ID", bibTeXKey(bookInfo)},
+			{"TI", bookInfo.Title},
+		}
+		// Every person goes in a line of their own: AU the authors, A2 the editors, A4 the translators.
+		for _, name := range authors(bookInfo) {
+			lines = append(lines, [2]string{"AU", name})
+		}
+		for _, name := range bookInfo.ContributorsAs(book.RoleEditor) {
+			lines = append(lines, [2]string{"A2", name})
+		}
+		for _, name := range bookInfo.ContributorsAs(book.RoleTranslator) {
+			lines = append(lines, [2]string{"A4", name})
+		}
+		lines = append(lines, [][2]string{
+			{"PB", bookInfo.Publisher},
+			{"PY", editionYear(bookInfo)},
+			{"LA", bookInfo.Language},
+			{"SN", bookInfo.ISBN},
+			{"N1", bookInfo.Description},
+			{"UR", bookInfo.GoodreadsLink},
+			{"ER", ""},
+		}...)
+
+		for _, line := range lines {
+			if line[1] == "" && line[0] != "ER" {
+				continue
+			}
+			// RIS has no escaping, a tag per line is all the structure there is.
+			if _, err := fmt.Fprintf(w, "%s  - %s\r\n", line[0], oneLine(line[1])); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type marcSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcDataField struct {
+	Tag       string         `xml:"tag,attr"`
+	Ind1      string         `xml:"ind1,attr"`
+	Ind2      string         `xml:"ind2,attr"`
+	Subfields []marcSubfield `xml:"subfield"`
+}
+
+type marcControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcRecord struct {
+	Leader        string             `xml:"leader"`
+	ControlFields []marcControlField `xml:"controlfield"`
+	DataFields    []marcDataField    `xml:"datafield"`
+}
+
+type marcCollection struct {
+	XMLName xml.Name     `xml:"http://www.loc.gov/MARC21/slim collection"`
+	Records []marcRecord `xml:"record"`
+}
+
+// marcLeader describes a Unicode encoded monograph ("nam", "a" at position 9).
+const marcLeader = "00000nam a2200000   4500"
+
+func newMARCRecord(bookInfo book.BookInfo) marcRecord {
+	record := marcRecord{
+		Leader:        marcLeader,
+		ControlFields: []marcControlField{{Tag: "001", Value: strconv.Itoa(bookInfo.ID)}},
+	}
+
+	addField := func(tag, ind1, ind2, code, value string) {
+		if value = oneLine(value); value == "" {
+			return
+		}
+		record.DataFields = append(record.DataFields, marcDataField{
+			Tag:       tag,
+			Ind1:      ind1,
+			Ind2:      ind2,
+			Subfields: []marcSubfield{{Code: code, Value: value}},
+		})
+	}
+
+	// 100 is the main author, the other authors and the rest of the contributors go to 700.
+	names := authors(bookInfo)
+	addField("020", " ", " ", "a", bookInfo.ISBN)
+	if len(names) > 0 {
+		addField("100", "0", " ", "a", names[0])
+	}
+	addField("245", "1", "0", "a", bookInfo.Title)
+
+	// 264 is the publication of the edition: $b the publisher and $c the year.
+	var publication []marcSubfield
+	if publisher := oneLine(bookInfo.Publisher); publisher != "" {
+		publication = append(publication, marcSubfield{Code: "b", Value: publisher})
+	}
+	if year := editionYear(bookInfo); year != "" {
+		publication = append(publication, marcSubfield{Code: "c", Value: year})
+	}
+	if len(publication) > 0 {
+		record.DataFields = append(record.DataFields, marcDataField{Tag: "264", Ind1: " ", Ind2: "1", Subfields: publication})
+	}
+
+	addField("500", " ", " ", "a", bookInfo.Description)
+	var addedEntries []book.Contributor
+	for i, name := range names {
+		if i > 0 {
+			addedEntries = append(addedEntries, book.Contributor{Name: name, Role: book.RoleAuthor})
+		}
+	}
+	addedEntries = append(addedEntries, bookInfo.OtherContributors()...)
+	for _, contributor := range addedEntries {
+		if name := oneLine(contributor.Name); name != "" {
+			record.DataFields = append(record.DataFields, marcDataField{Tag: "700", Ind1: "1", Ind2: " ", Subfields: []marcSubfield{
+				{Code: "a", Value: name},
+				{Code: "e", Value: marcRelator(contributor.Role)},
+			}})
+		}
+	}
+	addField("856", "4", "2", "u", bookInfo.GoodreadsLink)
+
+	return record
+}
+
+// marcRelator is the relator term of $e for a role.
+func marcRelator(role book.ContributorRole) string {
+	switch role {
+	case book.RolePrologue:
+		return "writer of preface"
+	default:
+		return string(role)
+	}
+}
+
+func writeMARCXML(w io.Writer, books []book.BookInfo) error {
+	collection := marcCollection{}
+	for _, bookInfo := range books {
+		collection.Records = append(collection.Records, newMARCRecord(bookInfo))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(collection); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
@@ -0,0 +1,413 @@
+package catalog
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"leonlib/internal/dao"
+	"leonlib/internal/isbn"
+	book "leonlib/internal/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// calibreBook is the subset of Calibre metadata we are able to map into the catalogue.
+type calibreBook struct {
+	Title       string
+	Authors     []string
+	Comments    string
+	Tags        []string
+	Identifiers map[string]string
+	Timestamp   time.Time
+	CoverPath   string
+}
+
+func (cb calibreBook) author() string {
+	return strings.Join(cb.Authors, ", ")
+}
+
+func (cb calibreBook) toBookInfo() book.BookInfo {
+	bookInfo := book.BookInfo{
+		Title:       cb.Title,
+		Author:      cb.author(),
+		Description: cb.Comments,
+		Tags:        book.NormalizeTags(cb.Tags),
+	}
+
+	if !cb.Timestamp.IsZero() {
+		bookInfo.AddedOn = cb.Timestamp.Format("2006-01-02")
+	}
+
+	if goodreadsID := cb.Identifiers["goodreads"]; goodreadsID != "" {
+		bookInfo.GoodreadsLink = goodreadsBookURL + goodreadsID
+	}
+
+	bookInfo.ISBN = cb.isbn()
+
+	return bookInfo
+}
+
+// isbn returns the ISBN-13 of the book, empty when Calibre has none or it is not a valid one.
+func (cb calibreBook) isbn() string {
+	normalized, err := isbn.Normalize(cb.Identifiers["isbn"])
+	if err != nil {
+		return ""
+	}
+
+	return normalized
+}
+
+// note summarizes the metadata that has no place in the catalogue yet.
+func (cb calibreBook) note() string {
+	var identifiers []string
+	for idType, value := range cb.Identifiers {
+		// The Goodreads ID and a valid ISBN are imported, an ISBN with a wrong check digit is not.
+		if idType == "goodreads" || (idType == "isbn" && cb.isbn() != "") {
+			continue
+		}
+		identifiers = append(identifiers, idType+":"+value)
+	}
+	if len(identifiers) == 0 {
+		return ""
+	}
+	sort.Strings(identifiers)
+
+	return "identifiers: " + strings.Join(identifiers, ", ")
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainText turns the HTML Calibre stores in comments into plain text.
+func plainText(comments string) string {
+	comments = strings.NewReplacer("</p>", "\n", "<br>", "\n", "<br/>", "\n", "<br />", "\n").Replace(comments)
+	comments = html.UnescapeString(htmlTag.ReplaceAllString(comments, ""))
+
+	var lines []string
+	for _, line := range strings.Split(comments, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// readCalibreDatabase reads the books of a Calibre metadata.db. The sqlite3 driver must be
+// registered by the caller.
+func readCalibreDatabase(libraryDir string) ([]calibreBook, error) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(libraryDir, "metadata.db")+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT id, title, path, has_cover, timestamp FROM books ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	type calibreRow struct {
+		id   int
+		book calibreBook
+	}
+
+	var calibreRows []calibreRow
+	for rows.Next() {
+		var row calibreRow
+		var path string
+		var hasCover bool
+		var timestamp sql.NullTime
+		if err := rows.Scan(&row.id, &row.book.Title, &path, &hasCover, &timestamp); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+
+		if hasCover {
+			row.book.CoverPath = filepath.Join(libraryDir, filepath.FromSlash(path), "cover.jpg")
+		}
+		if timestamp.Valid {
+			row.book.Timestamp = timestamp.Time
+		}
+		row.book.Identifiers = map[string]string{}
+		calibreRows = append(calibreRows, row)
+	}
+	_ = rows.Close()
+
+	queryStrings := func(query string, id int) ([]string, error) {
+		rows, err := db.Query(query, id)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var values []string
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+
+		return values, rows.Err()
+	}
+
+	books := make([]calibreBook, 0, len(calibreRows))
+	for _, row := range calibreRows {
+		calibre := row.book
+
+		calibre.Authors, err = queryStrings(`SELECT a.name FROM authors a JOIN books_authors_link l ON l.author = a.id WHERE l.book = $1 ORDER BY l.id`, row.id)
+		if err != nil {
+			return nil, err
+		}
+
+		calibre.Tags, err = queryStrings(`SELECT t.name FROM tags t JOIN books_tags_link l ON l.tag = t.id WHERE l.book = $1 ORDER BY t.name`, row.id)
+		if err != nil {
+			return nil, err
+		}
+
+		comments, err := queryStrings(`SELECT text FROM comments WHERE book = $1`, row.id)
+		if err != nil {
+			return nil, err
+		}
+		if len(comments) > 0 {
+			calibre.Comments = plainText(comments[0])
+		}
+
+		identifiers, err := queryStrings(`SELECT type || ':' || val FROM identifiers WHERE book = $1`, row.id)
+		if err != nil {
+			return nil, err
+		}
+		for _, identifier := range identifiers {
+			idType, value, _ := strings.Cut(identifier, ":")
+			calibre.Identifiers[strings.ToLower(idType)] = value
+		}
+
+		books = append(books, calibre)
+	}
+
+	return books, nil
+}
+
+type opfPackage struct {
+	Metadata struct {
+		Titles   []string `xml:"title"`
+		Creators []struct {
+			Role string `xml:"role,attr"`
+			Name string `xml:",chardata"`
+		} `xml:"creator"`
+		Description string   `xml:"description"`
+		Subjects    []string `xml:"subject"`
+		Identifiers []struct {
+			Scheme string `xml:"scheme,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"identifier"`
+		Metas []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Guide struct {
+		References []struct {
+			Type string `xml:"type,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"reference"`
+	} `xml:"guide"`
+}
+
+func readOPF(opfPath string) (calibreBook, error) {
+	data, err := os.ReadFile(opfPath)
+	if err != nil {
+		return calibreBook{}, err
+	}
+
+	var opf opfPackage
+	if err := xml.Unmarshal(data, &opf); err != nil {
+		return calibreBook{}, fmt.Errorf("%s: %v", opfPath, err)
+	}
+
+	if len(opf.Metadata.Titles) == 0 {
+		return calibreBook{}, fmt.Errorf("%s: no title", opfPath)
+	}
+
+	calibre := calibreBook{
+		Title:       strings.TrimSpace(opf.Metadata.Titles[0]),
+		Comments:    plainText(opf.Metadata.Description),
+		Tags:        opf.Metadata.Subjects,
+		Identifiers: map[string]string{},
+	}
+
+	for _, creator := range opf.Metadata.Creators {
+		if creator.Role == "" || creator.Role == "aut" {
+			calibre.Authors = append(calibre.Authors, strings.TrimSpace(creator.Name))
+		}
+	}
+
+	for _, identifier := range opf.Metadata.Identifiers {
+		if identifier.Scheme != "" && !strings.EqualFold(identifier.Scheme, "calibre") && !strings.EqualFold(identifier.Scheme, "uuid") {
+			calibre.Identifiers[strings.ToLower(identifier.Scheme)] = strings.TrimSpace(identifier.Value)
+		}
+	}
+
+	for _, meta := range opf.Metadata.Metas {
+		if meta.Name == "calibre:timestamp" {
+			if timestamp, err := time.Parse(time.RFC3339, meta.Content); err == nil {
+				calibre.Timestamp = timestamp
+			}
+		}
+	}
+
+	coverName := "cover.jpg"
+	for _, reference := range opf.Guide.References {
+		if reference.Type == "cover" && reference.Href != "" {
+			coverName = filepath.FromSlash(reference.Href)
+		}
+	}
+	coverPath := filepath.Join(filepath.Dir(opfPath), coverName)
+	if _, err := os.Stat(coverPath); err == nil {
+		calibre.CoverPath = coverPath
+	}
+
+	return calibre, nil
+}
+
+func readCalibreOPFs(libraryDir string) ([]calibreBook, error) {
+	var books []calibreBook
+
+	err := filepath.WalkDir(libraryDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "metadata.opf" {
+			return nil
+		}
+
+		calibre, err := readOPF(path)
+		if err != nil {
+			return err
+		}
+		books = append(books, calibre)
+
+		return nil
+	})
+
+	return books, err
+}
+
+// readCalibreLibrary prefers metadata.db and falls back to the metadata.opf file of each book.
+func readCalibreLibrary(libraryDir string) ([]calibreBook, error) {
+	if _, err := os.Stat(filepath.Join(libraryDir, "metadata.db")); err == nil {
+		return readCalibreDatabase(libraryDir)
+	}
+
+	books, err := readCalibreOPFs(libraryDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(books) == 0 {
+		return nil, errors.New("no metadata.db or metadata.opf files found, is this a Calibre library?")
+	}
+
+	return books, nil
+}
+
+// ImportCalibre adds the books of a local Calibre library, with their covers, to the catalogue.
+// Books whose ISBN, or normalized title and author, are already in the catalogue are skipped; a
+// title that exists under a different author is reported as a conflict and skipped as well.
+func ImportCalibre(bookDAO dao.DAO, libraryDir string, commit bool) (Report, error) {
+	report := Report{DryRun: !commit}
+
+	calibreBooks, err := readCalibreLibrary(libraryDir)
+	if err != nil {
+		return report, err
+	}
+
+	existingBooks, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return report, err
+	}
+
+	byISBN := map[string]book.BookInfo{}
+	byKey := map[string]book.BookInfo{}
+	byTitle := map[string]book.BookInfo{}
+	for _, existing := range existingBooks {
+		if existing.ISBN != "" {
+			byISBN[existing.ISBN] = existing
+		}
+		byKey[normalizedTitleAuthorKey(existing.Title, existing.Author)] = existing
+		byTitle[normalizeTitle(existing.Title)] = existing
+	}
+
+	for i, calibre := range calibreBooks {
+		result := RowResult{Row: i + 1, Title: calibre.Title, Author: calibre.author(), Note: calibre.note()}
+		bookInfo := calibre.toBookInfo()
+
+		if existing, ok := byISBN[bookInfo.ISBN]; ok && bookInfo.ISBN != "" {
+			result.Action = ActionUnchanged
+			result.BookID = existing.ID
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		if existing, ok := byKey[normalizedTitleAuthorKey(bookInfo.Title, bookInfo.Author)]; ok {
+			result.Action = ActionUnchanged
+			result.BookID = existing.ID
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		if existing, ok := byTitle[normalizeTitle(bookInfo.Title)]; ok {
+			result.Action = ActionConflict
+			result.BookID = existing.ID
+			result.Note = fmt.Sprintf("same title as %q by %q", existing.Title, existing.Author)
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		if bookInfo.Title == "" || bookInfo.Author == "" {
+			result.Action = ActionError
+			result.Err = errors.New("missing title or author")
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		var images [][]byte
+		if calibre.CoverPath != "" {
+			cover, err := os.ReadFile(calibre.CoverPath)
+			if err != nil {
+				result.Action = ActionError
+				result.Err = err
+				report.Rows = append(report.Rows, result)
+				continue
+			}
+			images = append(images, cover)
+		}
+
+		result.Action = ActionCreate
+		if commit {
+			bookID, err := createBookWithImages(bookDAO, bookInfo, images)
+			if err != nil {
+				result.Action = ActionError
+				result.Err = err
+			}
+			result.BookID = bookID
+			bookInfo.ID = bookID
+		}
+
+		if bookInfo.ISBN != "" {
+			byISBN[bookInfo.ISBN] = bookInfo
+		}
+		byKey[normalizedTitleAuthorKey(bookInfo.Title, bookInfo.Author)] = bookInfo
+		byTitle[normalizeTitle(bookInfo.Title)] = bookInfo
+		report.Rows = append(report.Rows, result)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,861 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"leonlib/internal/dao"
+	"leonlib/internal/isbn"
+	book "leonlib/internal/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The column names follow the keys used in library/books_db.toml, the location and the acquisition
+// of a book are flattened into its row. The lists are written in a cell: the contributors as
+// "Julio Cortázar (author); Gregory Rabassa (translator)", the tags separated by commas and each
+// read-through as startedOn/finishedOn/currentPage, "2024-06-16//320".
+var csvHeader = []string{"id", "title", "author", "description", "hasBeenRead", "addedOn", "goodreadsLink", "isbn",
+	"contributors", "workID", "publisher", "year", "language", "format", "tags", "category", "shelfID", "position",
+	"readingStatus", "pages", "readings", "purchasedOn", "price", "currency", "source", "condition", "estimatedValue",
+	"imageNames", "likes"}
+
+// The columns each DAO call saves, an import only makes the calls of the groups that change.
+var (
+	editionColumns     = []string{"workID", "publisher", "year", "language", "format"}
+	locationColumns    = []string{"shelfID", "position"}
+	readingColumns     = []string{"hasBeenRead", "readingStatus", "pages", "readings"}
+	acquisitionColumns = []string{"purchasedOn", "price", "currency", "source", "condition", "estimatedValue"}
+)
+
+// listSeparator separates the items of the imageNames, contributors and readings cells.
+const listSeparator = ";"
+
+// ExportCSV writes every book of the catalogue, its image file names and its likes count as CSV.
+func ExportCSV(bookDAO dao.DAO, w io.Writer) error {
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, bookInfo := range books {
+		likes, err := bookDAO.LikesCount(bookInfo.ID)
+		if err != nil {
+			return err
+		}
+
+		record := make([]string, len(csvHeader))
+		for i, column := range csvHeader {
+			record[i] = csvValue(bookInfo, column)
+		}
+		record[len(record)-1] = strconv.Itoa(likes)
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// csvValue is the cell of the column for the book, as ExportCSV writes it. Two books with the same
+// cell have the same value in that column.
+func csvValue(bookInfo book.BookInfo, column string) string {
+	switch column {
+	case "id":
+		return strconv.Itoa(bookInfo.ID)
+	case "title":
+		return bookInfo.Title
+	case "author":
+		return bookInfo.Author
+	case "description":
+		return bookInfo.Description
+	case "hasBeenRead":
+		return strconv.FormatBool(bookInfo.HasBeenRead)
+	case "addedOn":
+		if addedOn, err := book.ParseAddedOn(bookInfo.AddedOn); err == nil {
+			return addedOn.Format(book.AddedOnLayout)
+		}
+		return bookInfo.AddedOn
+	case "goodreadsLink":
+		return bookInfo.GoodreadsLink
+	case "isbn":
+		return bookInfo.ISBN
+	case "contributors":
+		bookInfo.Contributors = slices.Clone(bookInfo.Contributors)
+		bookInfo.NormalizeContributors()
+		contributors := make([]string, len(bookInfo.Contributors))
+		for i, contributor := range bookInfo.Contributors {
+			contributors[i] = fmt.Sprintf("%s (%s)", contributor.Name, contributor.Role)
+		}
+		return strings.Join(contributors, listSeparator+" ")
+	case "workID":
+		return formatCount(bookInfo.WorkID)
+	case "publisher":
+		return bookInfo.Publisher
+	case "year":
+		return formatCount(bookInfo.Year)
+	case "language":
+		return bookInfo.Language
+	case "format":
+		return bookInfo.Format
+	case "tags":
+		return strings.Join(bookInfo.Tags, ", ")
+	case "category":
+		return bookInfo.Category.String()
+	case "shelfID":
+		return formatCount(bookInfo.Location.ShelfID)
+	case "position":
+		return formatCount(bookInfo.Location.Position)
+	case "readingStatus":
+		return string(bookInfo.ReadingStatus)
+	case "pages":
+		return formatCount(bookInfo.Pages)
+	case "readings":
+		readings := make([]string, len(bookInfo.Readings))
+		for i, reading := range bookInfo.Readings {
+			readings[i] = strings.TrimRight(formatDay(reading.StartedOn)+"/"+formatDay(reading.FinishedOn)+"/"+formatCount(reading.CurrentPage), "/")
+		}
+		return strings.Join(readings, listSeparator+" ")
+	case "purchasedOn":
+		return formatDay(bookInfo.Acquisition.PurchasedOn)
+	case "price":
+		return formatMoney(bookInfo.Acquisition.Price)
+	case "currency":
+		return bookInfo.Acquisition.Currency
+	case "source":
+		return bookInfo.Acquisition.Source
+	case "condition":
+		return string(bookInfo.Acquisition.Condition)
+	case "estimatedValue":
+		return formatMoney(bookInfo.Acquisition.EstimatedValue)
+	case "imageNames":
+		return strings.Join(bookInfo.ImageNames, listSeparator)
+	default:
+		return ""
+	}
+}
+
+// formatCount writes the numbers that are 0 when they are not known as an empty cell.
+func formatCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(n)
+}
+
+func formatDay(day time.Time) string {
+	if day.IsZero() {
+		return ""
+	}
+
+	return day.Format(book.AddedOnLayout)
+}
+
+func formatMoney(amount book.Money) string {
+	if amount == 0 {
+		return ""
+	}
+
+	return amount.String()
+}
+
+// csvRow is a parsed CSV record. Only the columns present in the header are taken into account
+// when comparing against the catalogue, so a CSV with just "id,hasBeenRead" only touches that flag.
+type csvRow struct {
+	book    book.BookInfo
+	columns map[string]bool
+}
+
+func parseCSVRecord(record []string, columnIndex map[string]int) (csvRow, error) {
+	row := csvRow{columns: map[string]bool{}}
+
+	value := func(column string) string {
+		idx, ok := columnIndex[column]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		row.columns[column] = true
+
+		return strings.TrimSpace(record[idx])
+	}
+
+	// count reads the columns of numbers that cannot be negative, an empty cell is 0.
+	count := func(column string) (int, error) {
+		text := value(column)
+		if text == "" {
+			return 0, nil
+		}
+
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid %s %q", column, text)
+		}
+
+		return n, nil
+	}
+
+	// day reads the columns of dates, an empty cell is the zero time.
+	day := func(column string) (time.Time, error) {
+		text := value(column)
+		if text == "" {
+			return time.Time{}, nil
+		}
+
+		parsed, err := book.ParseAddedOn(text)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", column, text)
+		}
+
+		return parsed, nil
+	}
+
+	// money reads the columns of amounts.
+	money := func(column string) (book.Money, error) {
+		amount, err := book.ParseMoney(value(column))
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %v", column, err)
+		}
+
+		return amount, nil
+	}
+
+	if id := value("id"); id != "" {
+		bookID, err := strconv.Atoi(id)
+		if err != nil || bookID <= 0 {
+			return csvRow{}, fmt.Errorf("invalid id %q", id)
+		}
+		row.book.ID = bookID
+	}
+
+	row.book.Title = value("title")
+	row.book.Author = value("author")
+	row.book.Description = value("description")
+	row.book.GoodreadsLink = value("goodreadsLink")
+
+	normalized, err := isbn.Normalize(value("isbn"))
+	if err != nil {
+		return csvRow{}, err
+	}
+	row.book.ISBN = normalized
+
+	if read := value("hasBeenRead"); read != "" {
+		hasBeenRead, err := strconv.ParseBool(read)
+		if err != nil {
+			return csvRow{}, fmt.Errorf("invalid hasBeenRead value %q", read)
+		}
+		row.book.HasBeenRead = hasBeenRead
+	}
+
+	// The zero date is kept as 0001-01-01, the day an unreadable added_on comes back as.
+	if addedOn := value("addedOn"); addedOn != "" {
+		parsed, err := book.ParseAddedOn(addedOn)
+		if err != nil {
+			return csvRow{}, fmt.Errorf("invalid addedOn date %q, expected YYYY-MM-DD", addedOn)
+		}
+		row.book.AddedOn = parsed.Format(book.AddedOnLayout)
+	}
+
+	if imageNames := value("imageNames"); imageNames != "" {
+		for _, imageName := range strings.Split(imageNames, listSeparator) {
+			if imageName = strings.TrimSpace(imageName); imageName != "" {
+				row.book.ImageNames = append(row.book.ImageNames, imageName)
+			}
+		}
+	}
+
+	if row.book.Contributors, err = parseContributors(value("contributors")); err != nil {
+		return csvRow{}, err
+	}
+
+	if row.book.WorkID, err = count("workID"); err != nil {
+		return csvRow{}, err
+	}
+	row.book.Publisher = value("publisher")
+	if row.book.Year, err = count("year"); err != nil {
+		return csvRow{}, err
+	}
+	row.book.Language = value("language")
+	row.book.Format = value("format")
+
+	row.book.Tags = book.ParseTags(value("tags"))
+	row.book.Category = book.ParseCategory(value("category"))
+
+	if row.book.Location.ShelfID, err = count("shelfID"); err != nil {
+		return csvRow{}, err
+	}
+	if row.book.Location.Position, err = count("position"); err != nil {
+		return csvRow{}, err
+	}
+	if row.book.Location.ShelfID == 0 && row.book.Location.Position != 0 {
+		return csvRow{}, fmt.Errorf("position %d without a shelfID", row.book.Location.Position)
+	}
+
+	if status := value("readingStatus"); status != "" {
+		readingStatus, ok := book.ParseReadingStatus(status)
+		if !ok {
+			return csvRow{}, fmt.Errorf("invalid readingStatus %q", status)
+		}
+		row.book.ReadingStatus = readingStatus
+	}
+	if row.book.Pages, err = count("pages"); err != nil {
+		return csvRow{}, err
+	}
+	if row.book.Readings, err = parseReadings(value("readings")); err != nil {
+		return csvRow{}, err
+	}
+
+	acquisition := &row.book.Acquisition
+	if acquisition.PurchasedOn, err = day("purchasedOn"); err != nil {
+		return csvRow{}, err
+	}
+	if acquisition.Price, err = money("price"); err != nil {
+		return csvRow{}, err
+	}
+	acquisition.Currency = value("currency")
+	acquisition.Source = value("source")
+	acquisition.Condition = book.Condition(value("condition"))
+	if acquisition.EstimatedValue, err = money("estimatedValue"); err != nil {
+		return csvRow{}, err
+	}
+
+	return row, nil
+}
+
+// parseContributors reads the contributors cell, "Julio Cortázar (author); Gregory Rabassa
+// (translator)". A name without a role is an author.
+func parseContributors(text string) ([]book.Contributor, error) {
+	var contributors []book.Contributor
+	for _, item := range strings.Split(text, listSeparator) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, roleText := item, ""
+		if open := strings.LastIndex(item, "("); open > 0 && strings.HasSuffix(item, ")") {
+			name, roleText = strings.TrimSpace(item[:open]), item[open+1:len(item)-1]
+		}
+
+		role, ok := book.ParseContributorRole(roleText)
+		if !ok {
+			return nil, fmt.Errorf("invalid contributor role %q of %q", roleText, name)
+		}
+		contributors = append(contributors, book.Contributor{Name: name, Role: role})
+	}
+
+	return contributors, nil
+}
+
+// parseReadings reads the readings cell, a startedOn/finishedOn/currentPage for each read-through
+// whose empty parts at the end can be left out: "2019-07-01/2019-09-15; 2024-06-16//320".
+func parseReadings(text string) ([]book.Reading, error) {
+	var readings []book.Reading
+	for _, item := range strings.Split(text, listSeparator) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, "/")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("invalid reading %q, expected startedOn/finishedOn/currentPage", item)
+		}
+		parts = append(parts, "", "")
+
+		var reading book.Reading
+		var err error
+		for i, day := range []*time.Time{&reading.StartedOn, &reading.FinishedOn} {
+			if text := strings.TrimSpace(parts[i]); text != "" {
+				if *day, err = book.ParseAddedOn(text); err != nil {
+					return nil, fmt.Errorf("invalid reading %q, %q is not a YYYY-MM-DD date", item, text)
+				}
+			}
+		}
+		if page := strings.TrimSpace(parts[2]); page != "" {
+			if reading.CurrentPage, err = strconv.Atoi(page); err != nil {
+				return nil, fmt.Errorf("invalid reading %q, %q is not a page", item, page)
+			}
+		}
+
+		readings = append(readings, reading)
+	}
+
+	return readings, nil
+}
+
+// diff lists the columns of the row whose value in the merged book differs from the existing one.
+func (row csvRow) diff(existing, merged book.BookInfo) []FieldChange {
+	var changes []FieldChange
+	for _, column := range csvHeader {
+		if column == "id" || column == "likes" || !row.columns[column] {
+			continue
+		}
+
+		if old, new := csvValue(existing, column), csvValue(merged, column); old != new {
+			changes = append(changes, FieldChange{Field: column, Old: old, New: new})
+		}
+	}
+
+	return changes
+}
+
+// merge applies the columns present in the row on top of the existing book, a zero book for the
+// rows of new books. The reading follows from readingStatus and readings, hasBeenRead only changes
+// it when it says otherwise. A contributors cell lists the authors too and an author column has to
+// name the same ones; an empty one leaves only the authors.
+func (row csvRow) merge(existing book.BookInfo) (book.BookInfo, error) {
+	merged := existing
+	merged.Contributors = slices.Clone(existing.Contributors)
+	if row.columns["title"] {
+		merged.Title = row.book.Title
+	}
+	if row.columns["author"] {
+		merged.SetAuthor(row.book.Author)
+	}
+	if row.columns["contributors"] {
+		author := merged.Author
+		merged.Contributors = slices.Clone(row.book.Contributors)
+		if len(merged.Contributors) == 0 {
+			merged.Contributors = book.NewAuthors(author)
+		}
+		merged.Author = ""
+		merged.NormalizeContributors()
+		if row.columns["author"] && merged.Author != author {
+			return book.BookInfo{}, fmt.Errorf("the author %q is not the one of the contributors, %q", author, merged.Author)
+		}
+	}
+	if row.columns["description"] {
+		merged.Description = row.book.Description
+	}
+	if row.columns["addedOn"] {
+		merged.AddedOn = row.book.AddedOn
+	}
+	if row.columns["goodreadsLink"] {
+		merged.GoodreadsLink = row.book.GoodreadsLink
+	}
+	if row.columns["isbn"] {
+		merged.ISBN = row.book.ISBN
+	}
+	if row.columns["imageNames"] {
+		merged.ImageNames = row.book.ImageNames
+	}
+
+	if row.columns["workID"] {
+		merged.WorkID = row.book.WorkID
+	}
+	if row.columns["publisher"] {
+		merged.Publisher = row.book.Publisher
+	}
+	if row.columns["year"] {
+		merged.Year = row.book.Year
+	}
+	if row.columns["language"] {
+		merged.Language = row.book.Language
+	}
+	if row.columns["format"] {
+		merged.Format = row.book.Format
+	}
+
+	if row.columns["tags"] {
+		merged.Tags = row.book.Tags
+	}
+	if row.columns["category"] {
+		merged.Category = row.book.Category
+	}
+
+	if row.columns["shelfID"] {
+		merged.Location = book.Location{ShelfID: row.book.Location.ShelfID}
+	}
+	if row.columns["position"] && merged.Location.ShelfID != 0 {
+		merged.Location.Position = row.book.Location.Position
+	}
+
+	if row.columns["readingStatus"] {
+		merged.ReadingStatus = row.book.ReadingStatus
+	}
+	if row.columns["pages"] {
+		merged.Pages = row.book.Pages
+	}
+	if row.columns["readings"] {
+		merged.Readings = row.book.Readings
+	}
+	if err := merged.ValidateReading(); err != nil {
+		return book.BookInfo{}, err
+	}
+	merged.NormalizeReading()
+	if row.columns["hasBeenRead"] && merged.HasBeenRead != row.book.HasBeenRead {
+		merged.MarkRead(row.book.HasBeenRead)
+		merged.NormalizeReading()
+	}
+
+	acquisition := &merged.Acquisition
+	if row.columns["purchasedOn"] {
+		acquisition.PurchasedOn = row.book.Acquisition.PurchasedOn
+	}
+	if row.columns["price"] {
+		acquisition.Price = row.book.Acquisition.Price
+	}
+	if row.columns["currency"] {
+		acquisition.Currency = row.book.Acquisition.Currency
+	}
+	if row.columns["source"] {
+		acquisition.Source = row.book.Acquisition.Source
+	}
+	if row.columns["condition"] {
+		acquisition.Condition = row.book.Acquisition.Condition
+	}
+	if row.columns["estimatedValue"] {
+		acquisition.EstimatedValue = row.book.Acquisition.EstimatedValue
+	}
+	if err := acquisition.Validate(); err != nil {
+		return book.BookInfo{}, err
+	}
+	acquisition.Normalize()
+
+	return merged, nil
+}
+
+func titleAuthorKey(title, author string) string {
+	return strings.ToLower(strings.TrimSpace(title)) + "|" + strings.ToLower(strings.TrimSpace(author))
+}
+
+// bookIndex looks books up by ID, by ISBN or by (title, author), the ways an import row can be matched.
+type bookIndex struct {
+	byID          map[int]book.BookInfo
+	byISBN        map[string][]book.BookInfo
+	byTitleAuthor map[string][]book.BookInfo
+}
+
+func newBookIndex(books []book.BookInfo) *bookIndex {
+	index := &bookIndex{
+		byID:          map[int]book.BookInfo{},
+		byISBN:        map[string][]book.BookInfo{},
+		byTitleAuthor: map[string][]book.BookInfo{},
+	}
+	for _, bookInfo := range books {
+		index.add(bookInfo)
+	}
+
+	return index
+}
+
+func (idx *bookIndex) add(bookInfo book.BookInfo) {
+	if bookInfo.ID > 0 {
+		idx.byID[bookInfo.ID] = bookInfo
+	}
+	if bookInfo.ISBN != "" {
+		idx.byISBN[bookInfo.ISBN] = append(idx.byISBN[bookInfo.ISBN], bookInfo)
+	}
+	key := titleAuthorKey(bookInfo.Title, bookInfo.Author)
+	idx.byTitleAuthor[key] = append(idx.byTitleAuthor[key], bookInfo)
+}
+
+// match returns the existing book the candidate refers to, if any. An ISBN identifies the edition,
+// so it is tried before (title, author). A pair shared by several books (e.g. two editions of
+// "Ulises") cannot be resolved and is reported as an error.
+func (idx *bookIndex) match(candidate book.BookInfo) (book.BookInfo, bool, error) {
+	if candidate.ID > 0 {
+		existing, ok := idx.byID[candidate.ID]
+		return existing, ok, nil
+	}
+
+	matches := idx.byISBN[candidate.ISBN]
+	if candidate.ISBN == "" || len(matches) == 0 {
+		matches = idx.byTitleAuthor[titleAuthorKey(candidate.Title, candidate.Author)]
+	}
+
+	switch len(matches) {
+	case 0:
+		return book.BookInfo{}, false, nil
+	case 1:
+		return matches[0], true, nil
+	default:
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = strconv.Itoa(m.ID)
+		}
+		return book.BookInfo{}, false, fmt.Errorf("%q by %q matches several books (ids %s), add an id to the row",
+			candidate.Title, candidate.Author, strings.Join(ids, ", "))
+	}
+}
+
+// readCSVHeader maps each column name to its position. Spreadsheets often save CSVs with a BOM.
+func readCSVHeader(reader *csv.Reader) (map[string]int, error) {
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columnIndex := map[string]int{}
+	for i, column := range header {
+		columnIndex[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+
+	return columnIndex, nil
+}
+
+// ImportCSV creates or updates books from a CSV using the same columns ExportCSV writes. Rows are
+// matched by id, or by (title, author) when the id column is empty. When dryRun is set nothing is
+// written and the report describes what would have changed.
+func ImportCSV(bookDAO dao.DAO, r io.Reader, dryRun bool) (Report, error) {
+	report := Report{DryRun: dryRun}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	columnIndex, err := readCSVHeader(reader)
+	if err != nil {
+		return report, fmt.Errorf("error reading CSV header: %v", err)
+	}
+
+	_, hasID := columnIndex["id"]
+	_, hasTitle := columnIndex["title"]
+	_, hasAuthor := columnIndex["author"]
+	if !hasID && !(hasTitle && hasAuthor) {
+		return report, errors.New("CSV needs an id column or both title and author columns")
+	}
+
+	existingBooks, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return report, err
+	}
+	index := newBookIndex(existingBooks)
+
+	rowNumber := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNumber++
+
+		if err != nil {
+			report.Rows = append(report.Rows, RowResult{Row: rowNumber, Action: ActionError, Err: err})
+			continue
+		}
+
+		report.Rows = append(report.Rows, importCSVRow(bookDAO, index, record, columnIndex, rowNumber, dryRun))
+	}
+
+	return report, nil
+}
+
+func importCSVRow(bookDAO dao.DAO, index *bookIndex, record []string, columnIndex map[string]int, rowNumber int, dryRun bool) RowResult {
+	result := RowResult{Row: rowNumber}
+
+	row, err := parseCSVRecord(record, columnIndex)
+	if err != nil {
+		result.Action = ActionError
+		result.Err = err
+		return result
+	}
+
+	result.BookID = row.book.ID
+	result.Title = row.book.Title
+	result.Author = row.book.Author
+
+	existing, found, err := index.match(row.book)
+	if err != nil {
+		result.Action = ActionError
+		result.Err = err
+		return result
+	}
+
+	if found {
+		result.BookID = existing.ID
+		result.Title = existing.Title
+		result.Author = existing.Author
+
+		merged, err := row.merge(existing)
+		if err != nil {
+			result.Action = ActionError
+			result.Err = err
+			return result
+		}
+
+		result.Changes = row.diff(existing, merged)
+		if len(result.Changes) == 0 {
+			result.Action = ActionUnchanged
+			return result
+		}
+
+		result.Action = ActionUpdate
+		if merged.Title == "" || merged.Author == "" {
+			result.Action = ActionError
+			result.Err = errors.New("title and author cannot be empty")
+			return result
+		}
+
+		changed := map[string]bool{}
+		for _, change := range result.Changes {
+			changed[change.Field] = true
+		}
+
+		// No backend changes the day a book was added nor which images it has, the book page does.
+		for _, column := range []string{"addedOn", "imageNames"} {
+			if changed[column] {
+				result.Action = ActionError
+				result.Err = fmt.Errorf("%s only applies to new books", column)
+				return result
+			}
+		}
+
+		if !dryRun {
+			if err := updateBook(bookDAO, merged, changed); err != nil {
+				result.Action = ActionError
+				result.Err = err
+				return result
+			}
+		}
+		index.add(merged)
+
+		return result
+	}
+
+	newBook, err := row.merge(book.BookInfo{ID: row.book.ID})
+	if err != nil {
+		result.Action = ActionError
+		result.Err = err
+		return result
+	}
+
+	if newBook.Title == "" || newBook.Author == "" {
+		result.Action = ActionError
+		result.Err = errors.New("new books need a title and an author")
+		return result
+	}
+
+	images, err := readImages(newBook.ImageNames)
+	if err != nil {
+		result.Action = ActionError
+		result.Err = err
+		return result
+	}
+
+	result.Action = ActionCreate
+	if !dryRun {
+		bookID, err := createBookWithImages(bookDAO, newBook, images)
+		if err != nil {
+			result.Action = ActionError
+			result.Err = err
+			return result
+		}
+		result.BookID = bookID
+		newBook.ID = bookID
+	}
+	index.add(newBook)
+
+	return result
+}
+
+// updateBook saves the merged book of a row, each group of columns that changed with its DAO call.
+func updateBook(bookDAO dao.DAO, merged book.BookInfo, changed map[string]bool) error {
+	has := func(columns ...string) bool {
+		for _, column := range columns {
+			if changed[column] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	err := bookDAO.UpdateBook(merged.Title, merged.Author, merged.Description, merged.HasBeenRead, merged.GoodreadsLink, merged.ISBN, merged.ID)
+	if err != nil {
+		return err
+	}
+
+	if has("contributors") {
+		if err := bookDAO.SetContributors(merged.ID, merged.Contributors); err != nil {
+			return err
+		}
+	}
+
+	if has(editionColumns...) {
+		if err := bookDAO.UpdateEdition(merged.ID, merged.WorkID, merged.Edition); err != nil {
+			return err
+		}
+	}
+
+	if has("tags") {
+		if err := bookDAO.SetTags(merged.ID, merged.Tags); err != nil {
+			return err
+		}
+	}
+
+	if has("category") {
+		if err := bookDAO.SetCategory(merged.ID, merged.Category); err != nil {
+			return err
+		}
+	}
+
+	if has(locationColumns...) {
+		if err := bookDAO.SetBookLocation(merged.ID, merged.Location); err != nil {
+			return err
+		}
+	}
+
+	if has(readingColumns...) {
+		if err := bookDAO.SetReading(merged.ID, merged.ReadingStatus, merged.Pages, merged.Readings); err != nil {
+			return err
+		}
+	}
+
+	if has(acquisitionColumns...) {
+		if err := bookDAO.SetAcquisition(merged.ID, merged.Acquisition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readImages reads the images of a new book from the images directory. The names are file names in
+// it, a path that could go anywhere else is rejected.
+func readImages(imageNames []string) ([][]byte, error) {
+	var images [][]byte
+	for _, imageName := range imageNames {
+		if filepath.Base(imageName) != imageName || strings.Contains(imageName, "..") {
+			return nil, fmt.Errorf("invalid image name %q, it must be a file of the images directory", imageName)
+		}
+
+		imgBytes, err := os.ReadFile(filepath.Join("images", imageName))
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, imgBytes)
+	}
+
+	return images, nil
+}
+
+// createBookWithImages creates the book of a CSV row. A row with an id keeps it, and the backends
+// number the books added afterwards past it.
+func createBookWithImages(bookDAO dao.DAO, bookInfo book.BookInfo, images [][]byte) (int, error) {
+	bookID, err := bookDAO.CreateBook(bookInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, image := range images {
+		if err := bookDAO.AddImageToBook(bookID, image); err != nil {
+			return bookID, err
+		}
+	}
+
+	return bookID, nil
+}
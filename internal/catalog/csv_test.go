@@ -0,0 +1,282 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCSVTestDAO is a memory backend with a book that has every field the CSV covers.
+func newCSVTestDAO(t *testing.T) (dao.DAO, book.BookInfo) {
+	t.Helper()
+
+	bookDAO, err := dao.OpenDAO("memory", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = bookDAO.Close() })
+
+	workID, err := bookDAO.CreateWork(book.Work{Title: "Rayuela", Author: "Julio Cortázar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shelfID, err := bookDAO.CreateShelf(book.Shelf{Room: "Estudio", Bookcase: "Librero 1", Name: "Balda 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bookID, err := bookDAO.CreateBook(book.BookInfo{
+		Title:  "Hopscotch",
+		Author: "Julio Cortázar",
+		Contributors: []book.Contributor{
+			{Name: "Julio Cortázar", Role: book.RoleAuthor},
+			{Name: "Gregory Rabassa", Role: book.RoleTranslator},
+		},
+		Description:   "Una novela",
+		AddedOn:       "2024-02-03",
+		GoodreadsLink: "https://www.goodreads.com/book/show/53413",
+		ISBN:          "9780306406157",
+		WorkID:        workID,
+		Edition:       book.Edition{Publisher: "Pantheon", Year: 1966, Language: "en", Format: "hardcover"},
+		Tags:          []string{"argentina", "novela"},
+		Category:      book.ParseCategory("Literatura › Novela"),
+		Location:      book.Location{ShelfID: shelfID, Position: 1},
+		ReadingStatus: book.StatusRereading,
+		Pages:         564,
+		Readings: []book.Reading{
+			{StartedOn: time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC), FinishedOn: time.Date(2019, 9, 15, 0, 0, 0, 0, time.UTC)},
+			{StartedOn: time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC), CurrentPage: 320},
+		},
+		Acquisition: book.Acquisition{
+			PurchasedOn:    time.Date(2019, 6, 28, 0, 0, 0, 0, time.UTC),
+			Price:          45050,
+			Currency:       "MXN",
+			Source:         "Librería El Sótano",
+			Condition:      book.ConditionVeryGood,
+			EstimatedValue: 60000,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bookInfo, err := bookDAO.GetBookByID(bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bookDAO, bookInfo
+}
+
+// exportCSV returns the records ExportCSV writes, the header first.
+func exportCSV(t *testing.T, bookDAO dao.DAO) [][]string {
+	t.Helper()
+
+	var exported bytes.Buffer
+	if err := ExportCSV(bookDAO, &exported); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&exported).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return records
+}
+
+func writeCSV(t *testing.T, records [][]string) *bytes.Buffer {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.WriteAll(records); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buffer
+}
+
+func TestExportCSVWritesEveryField(t *testing.T) {
+	bookDAO, _ := newCSVTestDAO(t)
+
+	records := exportCSV(t, bookDAO)
+	if len(records) != 2 {
+		t.Fatalf("the CSV has %d records, want the header and a book", len(records))
+	}
+
+	got := map[string]string{}
+	for i, column := range records[0] {
+		got[column] = records[1][i]
+	}
+
+	want := map[string]string{
+		"contributors":   "Julio Cortázar (author); Gregory Rabassa (translator)",
+		"addedOn":        "2024-02-03",
+		"publisher":      "Pantheon",
+		"year":           "1966",
+		"tags":           "argentina, novela",
+		"category":       "Literatura › Novela",
+		"position":       "1",
+		"readingStatus":  "re-reading",
+		"readings":       "2019-07-01/2019-09-15; 2024-06-16//320",
+		"purchasedOn":    "2019-06-28",
+		"price":          "450.50",
+		"currency":       "MXN",
+		"condition":      "very-good",
+		"estimatedValue": "600.00",
+	}
+	for column, value := range want {
+		if got[column] != value {
+			t.Errorf("%s = %q, want %q", column, got[column], value)
+		}
+	}
+}
+
+func TestImportCSVOfAnExportChangesNothing(t *testing.T) {
+	bookDAO, before := newCSVTestDAO(t)
+
+	report, err := ImportCSV(bookDAO, writeCSV(t, exportCSV(t, bookDAO)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Action != ActionUnchanged {
+		t.Fatalf("the import did %+v, want the book unchanged", report.Rows)
+	}
+
+	after, err := bookDAO.GetBookByID(before.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("the book is %+v, want %+v", after, before)
+	}
+}
+
+func TestImportCSVUpdatesEveryField(t *testing.T) {
+	bookDAO, before := newCSVTestDAO(t)
+
+	records := exportCSV(t, bookDAO)
+	set := func(column, value string) {
+		for i, name := range records[0] {
+			if name == column {
+				records[1][i] = value
+				return
+			}
+		}
+		t.Fatalf("no %s column", column)
+	}
+	set("contributors", "Julio Cortázar (author); Gregory Rabassa (translator); Octavio Paz (prologue)")
+	set("publisher", "Random House")
+	set("tags", "argentina, clásicos")
+	set("category", "Literatura")
+	set("shelfID", "")
+	set("position", "")
+	set("readingStatus", "read")
+	set("readings", "2019-07-01/2019-09-15; 2024-06-16/2024-08-01")
+	set("price", "500")
+	set("condition", "good")
+
+	report, err := ImportCSV(bookDAO, writeCSV(t, records), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Action != ActionUpdate {
+		t.Fatalf("the import did %+v, want the book updated", report.Rows)
+	}
+
+	after, err := bookDAO.GetBookByID(before.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := after.ContributorsAs(book.RolePrologue); !reflect.DeepEqual(got, []string{"Octavio Paz"}) {
+		t.Errorf("the prologue is by %v", got)
+	}
+	if after.Publisher != "Random House" || after.Year != before.Year {
+		t.Errorf("the edition is %+v", after.Edition)
+	}
+	if !reflect.DeepEqual(after.Tags, []string{"argentina", "clásicos"}) || after.Category.String() != "Literatura" {
+		t.Errorf("the tags are %v in %q", after.Tags, after.Category)
+	}
+	if !after.Location.IsZero() {
+		t.Errorf("the book is still on %+v", after.Location)
+	}
+	if after.ReadingStatus != book.StatusRead || after.TimesRead() != 2 || !after.HasBeenRead {
+		t.Errorf("the reading is %q with %+v", after.ReadingStatus, after.Readings)
+	}
+	if after.Acquisition.Price != 50000 || after.Acquisition.Condition != book.ConditionGood ||
+		after.Acquisition.Source != before.Acquisition.Source {
+		t.Errorf("the acquisition is %+v", after.Acquisition)
+	}
+}
+
+func TestImportCSVRejectsWhatCannotBeApplied(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		row    string
+	}{
+		{"addedOn", "id,addedOn", "2024-03-01"},
+		{"imageNames", "id,imageNames", "portada.jpg"},
+		{"author and contributors", "id,author,contributors", "Julio Cortázar,Gregory Rabassa (translator)"},
+		{"position without a shelf", "id,shelfID,position", ",3"},
+		{"reading", "id,pages,readings", "100,2024-06-16//320"},
+		{"condition", "id,condition", "regular"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookDAO, before := newCSVTestDAO(t)
+
+			input := tt.header + "\n" + fmt.Sprint(before.ID) + "," + tt.row + "\n"
+			report, err := ImportCSV(bookDAO, strings.NewReader(input), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Rows) != 1 || report.Rows[0].Action != ActionError {
+				t.Errorf("the import did %+v, want an error", report.Rows)
+			}
+
+			after, err := bookDAO.GetBookByID(before.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("the book is %+v, want %+v", after, before)
+			}
+		})
+	}
+}
+
+func TestImportCSVReadsImagesOnlyFromTheImagesDirectory(t *testing.T) {
+	bookDAO, err := dao.OpenDAO("memory", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bookDAO.Close()
+
+	for _, imageName := range []string{"../secret.jpg", "/etc/passwd", "covers/../../secret.jpg", "..", "portada..jpg"} {
+		input := "title,author,imageNames\nRayuela,Julio Cortázar," + imageName + "\n"
+		report, err := ImportCSV(bookDAO, strings.NewReader(input), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Rows) != 1 || report.Rows[0].Action != ActionError || !strings.Contains(report.Rows[0].Err.Error(), "invalid image name") {
+			t.Errorf("%s: the import did %+v, want the image name rejected", imageName, report.Rows)
+		}
+	}
+
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 0 {
+		t.Errorf("the catalogue has %d books, want none", len(books))
+	}
+}
@@ -0,0 +1,166 @@
+package catalog
+
+import (
+	"leonlib/internal/dao"
+	"leonlib/internal/imagestore"
+	book "leonlib/internal/types"
+	"sort"
+)
+
+// Why a book of the catalogue was taken for the candidate.
+const (
+	MatchISBN        = "isbn"
+	MatchCover       = "cover"
+	MatchTitleAuthor = "title-author"
+)
+
+const (
+	// minTitleSimilarity and minAuthorSimilarity tell "Cien años de soledad" by "Gabriel García
+	// Márquez" and "Cien anos de soledad" by "García Márquez, Gabriel" are the same book, while
+	// "El otoño del patriarca" is another one.
+	minTitleSimilarity  = 0.8
+	minAuthorSimilarity = 0.6
+	maxDuplicates       = 10
+)
+
+// Duplicate is a book of the catalogue the candidate may be a second copy of.
+type Duplicate struct {
+	Book book.BookInfo
+	// Score goes from 0 to 1, 1 being certain: the same ISBN or the same cover photo.
+	Score   float64
+	Reasons []string
+}
+
+// FindDuplicates looks for the books of the catalogue a book about to be added may be: the ones
+// with its ISBN, with a similar normalized title and author, or with one of its images (after
+// imaging.NormalizeUpload, so it is the same photo uploaded again). The candidate may have only some
+// of these, e.g. just the ISBN or the title. The most likely duplicates come first.
+func FindDuplicates(bookDAO dao.DAO, candidate book.BookInfo, images [][]byte) ([]Duplicate, error) {
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := map[int]*Duplicate{}
+	add := func(bookInfo book.BookInfo, score float64, reason string) {
+		duplicate, ok := duplicates[bookInfo.ID]
+		if !ok {
+			duplicate = &Duplicate{Book: bookInfo}
+			duplicates[bookInfo.ID] = duplicate
+		}
+		duplicate.Score = max(duplicate.Score, score)
+		duplicate.Reasons = append(duplicate.Reasons, reason)
+	}
+
+	candidateTitle := normalizeTitle(candidate.Title)
+	candidateAuthor := normalizeText(candidate.Author)
+	byID := make(map[int]book.BookInfo, len(books))
+	for _, bookInfo := range books {
+		byID[bookInfo.ID] = bookInfo
+
+		if candidate.ISBN != "" && bookInfo.ISBN == candidate.ISBN {
+			add(bookInfo, 1, MatchISBN)
+		}
+
+		if candidateTitle == "" {
+			continue
+		}
+
+		titleSimilarity := similarity(candidateTitle, normalizeTitle(bookInfo.Title))
+		if titleSimilarity < minTitleSimilarity {
+			continue
+		}
+
+		score := titleSimilarity
+		if candidateAuthor != "" {
+			authorSimilarity := similarity(candidateAuthor, normalizeText(bookInfo.Author))
+			if authorSimilarity < minAuthorSimilarity {
+				continue
+			}
+			score = (titleSimilarity + authorSimilarity) / 2
+		}
+		add(bookInfo, score, MatchTitleAuthor)
+	}
+
+	for _, image := range images {
+		bookIDs, err := bookDAO.GetBookIDsByImageHash(imagestore.Hash(image))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, bookID := range bookIDs {
+			if bookInfo, ok := byID[bookID]; ok {
+				add(bookInfo, 1, MatchCover)
+			}
+		}
+	}
+
+	result := make([]Duplicate, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		result = append(result, *duplicate)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+
+		return result[i].Book.ID < result[j].Book.ID
+	})
+
+	if len(result) > maxDuplicates {
+		result = result[:maxDuplicates]
+	}
+
+	// Whoever gets asked to confirm wants to see the covers.
+	for i := range result {
+		bookImages, err := bookDAO.GetImagesByBookID(result[i].Book.ID)
+		if err != nil {
+			return nil, err
+		}
+		result[i].Book.SetImages(bookImages)
+	}
+
+	return result, nil
+}
+
+// similarity is the Sørensen–Dice coefficient of the letter pairs of both texts: 1 when they are
+// equal, 0 when they share no pair. It forgives typos and words written in another order.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	pairsA, pairsB := letterPairs(a), letterPairs(b)
+	if len(pairsA) == 0 || len(pairsB) == 0 {
+		return 0
+	}
+
+	counts := map[string]int{}
+	for _, pair := range pairsA {
+		counts[pair]++
+	}
+
+	shared := 0
+	for _, pair := range pairsB {
+		if counts[pair] > 0 {
+			counts[pair]--
+			shared++
+		}
+	}
+
+	return 2 * float64(shared) / float64(len(pairsA)+len(pairsB))
+}
+
+func letterPairs(text string) []string {
+	runes := []rune(text)
+
+	var pairs []string
+	for i := 0; i+1 < len(runes); i++ {
+		if runes[i] != ' ' && runes[i+1] != ' ' {
+			pairs = append(pairs, string(runes[i:i+2]))
+		}
+	}
+
+	return pairs
+}
@@ -0,0 +1,364 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"leonlib/internal/dao"
+	"leonlib/internal/isbn"
+	book "leonlib/internal/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const goodreadsBookURL = "https://www.goodreads.com/book/show/"
+
+var goodreadsBookIDPattern = regexp.MustCompile(`goodreads\.com/book/show/(\d+)`)
+
+// goodreadsBookID extracts the numeric Goodreads ID from a link such as
+// https://www.goodreads.com/book/show/415.Gravity_s_Rainbow?ac=1
+func goodreadsBookID(link string) string {
+	match := goodreadsBookIDPattern.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+// goodreadsEntry is a row of goodreads_library_export.csv.
+type goodreadsEntry struct {
+	BookID         string
+	Title          string
+	Author         string
+	ExclusiveShelf string
+	Shelves        []string
+	OwnedCopies    int
+	DateAdded      string
+	ISBN           string
+}
+
+func (e goodreadsEntry) link() string {
+	return goodreadsBookURL + e.BookID
+}
+
+func (e goodreadsEntry) hasShelf(shelf string) bool {
+	for _, s := range e.Shelves {
+		if s == shelf {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWishlist tells if the entry belongs to the wish list rather than to the catalogue: books
+// shelved as "to-read" (or on a "wishlist" shelf) that we don't own yet.
+func (e goodreadsEntry) isWishlist() bool {
+	if e.OwnedCopies > 0 || e.hasShelf("owned") {
+		return false
+	}
+
+	return e.ExclusiveShelf == "to-read" || e.hasShelf("wishlist") || e.hasShelf("wish-list")
+}
+
+func (e goodreadsEntry) isRead() bool {
+	return e.ExclusiveShelf == "read"
+}
+
+func parseGoodreadsRecord(record []string, columnIndex map[string]int) (goodreadsEntry, error) {
+	value := func(column string) string {
+		idx, ok := columnIndex[column]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[idx])
+	}
+
+	entry := goodreadsEntry{
+		BookID:         value("Book Id"),
+		Title:          value("Title"),
+		Author:         value("Author"),
+		ExclusiveShelf: value("Exclusive Shelf"),
+	}
+
+	if entry.Title == "" || entry.Author == "" {
+		return goodreadsEntry{}, errors.New("missing Title or Author")
+	}
+
+	if _, err := strconv.Atoi(entry.BookID); err != nil {
+		return goodreadsEntry{}, fmt.Errorf("invalid Book Id %q", entry.BookID)
+	}
+
+	for _, shelf := range strings.Split(value("Bookshelves"), ",") {
+		if shelf = strings.TrimSpace(shelf); shelf != "" {
+			entry.Shelves = append(entry.Shelves, shelf)
+		}
+	}
+
+	if owned := value("Owned Copies"); owned != "" {
+		entry.OwnedCopies, _ = strconv.Atoi(owned)
+	}
+
+	// Goodreads writes the ISBNs as spreadsheet formulas, ="9788433920669", so they keep their zeros.
+	// An ISBN it got wrong is not worth rejecting the row for.
+	for _, column := range []string{"ISBN13", "ISBN"} {
+		value := strings.Trim(value(column), `="`)
+		if normalized, err := isbn.Normalize(value); err == nil && normalized != "" {
+			entry.ISBN = normalized
+			break
+		}
+	}
+
+	if dateAdded := value("Date Added"); dateAdded != "" {
+		added, err := time.Parse("2006/01/02", dateAdded)
+		if err != nil {
+			return goodreadsEntry{}, fmt.Errorf("invalid Date Added %q", dateAdded)
+		}
+		entry.DateAdded = added.Format("2006-01-02")
+	}
+
+	return entry, nil
+}
+
+// goodreadsIndex finds catalogue books and wish list entries by Goodreads ID or by normalized
+// title and author.
+type goodreadsIndex struct {
+	booksByGoodreadsID    map[string]book.BookInfo
+	booksByKey            map[string][]book.BookInfo
+	wishListByGoodreadsID map[string]book.WishListBook
+	wishListByKey         map[string]book.WishListBook
+}
+
+func newGoodreadsIndex(books []book.BookInfo, wishList []book.WishListBook) *goodreadsIndex {
+	index := &goodreadsIndex{
+		booksByGoodreadsID:    map[string]book.BookInfo{},
+		booksByKey:            map[string][]book.BookInfo{},
+		wishListByGoodreadsID: map[string]book.WishListBook{},
+		wishListByKey:         map[string]book.WishListBook{},
+	}
+
+	for _, bookInfo := range books {
+		index.addBook(bookInfo)
+	}
+
+	for _, wishListBook := range wishList {
+		index.addWishListBook(wishListBook)
+	}
+
+	return index
+}
+
+func (idx *goodreadsIndex) addBook(bookInfo book.BookInfo) {
+	if id := goodreadsBookID(bookInfo.GoodreadsLink); id != "" {
+		idx.booksByGoodreadsID[id] = bookInfo
+	}
+	key := normalizedTitleAuthorKey(bookInfo.Title, bookInfo.Author)
+	idx.booksByKey[key] = append(idx.booksByKey[key], bookInfo)
+}
+
+func (idx *goodreadsIndex) inCatalogue(entry goodreadsEntry) bool {
+	if _, ok := idx.booksByGoodreadsID[entry.BookID]; ok {
+		return true
+	}
+
+	return len(idx.booksByKey[normalizedTitleAuthorKey(entry.Title, entry.Author)]) > 0
+}
+
+func (idx *goodreadsIndex) addWishListBook(wishListBook book.WishListBook) {
+	if id := goodreadsBookID(wishListBook.GoodreadsLink); id != "" {
+		idx.wishListByGoodreadsID[id] = wishListBook
+	}
+	idx.wishListByKey[normalizedTitleAuthorKey(wishListBook.Title, wishListBook.Author)] = wishListBook
+}
+
+// ImportGoodreads reconciles a Goodreads library export against the catalogue and the wish list.
+// Nothing is written unless commit is set; new wish list entries are added at the normal priority.
+func ImportGoodreads(bookDAO dao.DAO, r io.Reader, commit bool) (Report, error) {
+	report := Report{DryRun: !commit}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	columnIndex, err := readCSVHeader(reader)
+	if err != nil {
+		return report, fmt.Errorf("error reading Goodreads export header: %v", err)
+	}
+
+	for _, required := range []string{"Book Id", "Title", "Author", "Exclusive Shelf"} {
+		if _, ok := columnIndex[required]; !ok {
+			return report, fmt.Errorf("column %q not found, is this a Goodreads library export?", required)
+		}
+	}
+
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return report, err
+	}
+
+	wishList, err := bookDAO.GetWishListBooks("")
+	if err != nil {
+		return report, err
+	}
+
+	index := newGoodreadsIndex(books, wishList)
+
+	rowNumber := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNumber++
+
+		if err != nil {
+			report.Rows = append(report.Rows, RowResult{Row: rowNumber, Action: ActionError, Err: err})
+			continue
+		}
+
+		entry, err := parseGoodreadsRecord(record, columnIndex)
+		if err != nil {
+			report.Rows = append(report.Rows, RowResult{Row: rowNumber, Action: ActionError, Err: err})
+			continue
+		}
+
+		var result RowResult
+		// A to-read book we already own is just an unread book of the catalogue.
+		if entry.isWishlist() && !index.inCatalogue(entry) {
+			result = reconcileGoodreadsWishList(index, entry)
+			if result.Action == ActionCreate {
+				wishListBook := book.WishListBook{
+					Title:         entry.Title,
+					Author:        entry.Author,
+					GoodreadsLink: entry.link(),
+				}
+				if commit {
+					if wishListBook.ID, err = bookDAO.CreateWishListBook(wishListBook); err != nil {
+						result.Action = ActionError
+						result.Err = err
+					}
+				}
+				index.addWishListBook(wishListBook)
+			}
+		} else {
+			result = reconcileGoodreadsBook(bookDAO, index, entry, commit)
+		}
+
+		result.Row = rowNumber
+		report.Rows = append(report.Rows, result)
+	}
+
+	return report, nil
+}
+
+func reconcileGoodreadsWishList(index *goodreadsIndex, entry goodreadsEntry) RowResult {
+	result := RowResult{Title: entry.Title, Author: entry.Author, Wishlist: true}
+	key := normalizedTitleAuthorKey(entry.Title, entry.Author)
+
+	if _, ok := index.wishListByGoodreadsID[entry.BookID]; ok {
+		result.Action = ActionUnchanged
+		return result
+	}
+	if _, ok := index.wishListByKey[key]; ok {
+		result.Action = ActionUnchanged
+		return result
+	}
+
+	result.Action = ActionCreate
+
+	return result
+}
+
+func reconcileGoodreadsBook(bookDAO dao.DAO, index *goodreadsIndex, entry goodreadsEntry, commit bool) RowResult {
+	result := RowResult{Title: entry.Title, Author: entry.Author}
+
+	existing, found := index.booksByGoodreadsID[entry.BookID]
+	if !found {
+		candidates := index.booksByKey[normalizedTitleAuthorKey(entry.Title, entry.Author)]
+		switch len(candidates) {
+		case 0:
+		case 1:
+			existing, found = candidates[0], true
+			if linkedID := goodreadsBookID(existing.GoodreadsLink); linkedID != "" && linkedID != entry.BookID {
+				result.Action = ActionConflict
+				result.BookID = existing.ID
+				result.Note = fmt.Sprintf("already linked to Goodreads book %s, Goodreads export has %s", linkedID, entry.BookID)
+				return result
+			}
+		default:
+			ids := make([]string, len(candidates))
+			for i, c := range candidates {
+				ids[i] = strconv.Itoa(c.ID)
+			}
+			result.Action = ActionConflict
+			result.Note = fmt.Sprintf("matches several books (ids %s)", strings.Join(ids, ", "))
+			return result
+		}
+	}
+
+	if !found {
+		newBook := book.BookInfo{
+			Title:         entry.Title,
+			Author:        entry.Author,
+			HasBeenRead:   entry.isRead(),
+			AddedOn:       entry.DateAdded,
+			GoodreadsLink: entry.link(),
+			ISBN:          entry.ISBN,
+		}
+
+		result.Action = ActionCreate
+		if commit {
+			bookID, err := bookDAO.CreateBook(newBook)
+			if err != nil {
+				result.Action = ActionError
+				result.Err = err
+				return result
+			}
+			newBook.ID = bookID
+			result.BookID = bookID
+		}
+		index.addBook(newBook)
+
+		return result
+	}
+
+	result.BookID = existing.ID
+	result.Title = existing.Title
+	result.Author = existing.Author
+
+	updated := existing
+	if existing.GoodreadsLink == "" {
+		updated.GoodreadsLink = entry.link()
+		result.Changes = append(result.Changes, FieldChange{Field: "goodreadsLink", Old: "", New: updated.GoodreadsLink})
+	}
+	if existing.ISBN == "" && entry.ISBN != "" {
+		updated.ISBN = entry.ISBN
+		result.Changes = append(result.Changes, FieldChange{Field: "isbn", Old: "", New: updated.ISBN})
+	}
+	// A book marked as read on Goodreads is read, but we never mark a book as unread from it.
+	if entry.isRead() && !existing.HasBeenRead {
+		updated.HasBeenRead = true
+		result.Changes = append(result.Changes, FieldChange{Field: "hasBeenRead", Old: "false", New: "true"})
+	}
+
+	if len(result.Changes) == 0 {
+		result.Action = ActionUnchanged
+		return result
+	}
+
+	result.Action = ActionUpdate
+	if commit {
+		err := bookDAO.UpdateBook(updated.Title, updated.Author, updated.Description, updated.HasBeenRead, updated.GoodreadsLink, updated.ISBN, updated.ID)
+		if err != nil {
+			result.Action = ActionError
+			result.Err = err
+			return result
+		}
+	}
+
+	return result
+}
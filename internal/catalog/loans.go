@@ -0,0 +1,76 @@
+package catalog
+
+import (
+	"fmt"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LentBook is a book that is lent, with its loan.
+type LentBook struct {
+	book.Loan
+	Book        book.BookInfo
+	DaysOverdue int
+}
+
+// LoansReport lists the books that are lent on a day.
+type LoansReport struct {
+	Today time.Time
+	// Overdue are the books past their due date, the most overdue first.
+	Overdue []LentBook
+	// Lent are the rest, the oldest loans first.
+	Lent []LentBook
+}
+
+func (r LoansReport) String() string {
+	var sb strings.Builder
+
+	for _, lent := range r.Overdue {
+		fmt.Fprintf(&sb, "book %d %q lent to %q on %s, due on %s: %d days overdue\n", lent.Book.ID, lent.Book.Title, lent.Borrower,
+			lent.LoanedOn.Format(book.AddedOnLayout), lent.DueOn.Format(book.AddedOnLayout), lent.DaysOverdue)
+	}
+
+	fmt.Fprintf(&sb, "%s: %d books lent, %d overdue\n", r.Today.Format(book.AddedOnLayout), len(r.Overdue)+len(r.Lent), len(r.Overdue))
+
+	return sb.String()
+}
+
+// LentBooks returns the books that are lent on the day of today, the overdue ones apart.
+func LentBooks(bookDAO dao.DAO, today time.Time) (LoansReport, error) {
+	report := LoansReport{Today: book.Day(today)}
+
+	loans, err := bookDAO.GetAllLoans()
+	if err != nil {
+		return report, err
+	}
+
+	for _, loan := range loans {
+		if loan.Returned() {
+			continue
+		}
+
+		bookInfo, err := bookDAO.GetBookByID(loan.BookID)
+		if err != nil {
+			return report, fmt.Errorf("book %d: %v", loan.BookID, err)
+		}
+
+		lent := LentBook{Loan: loan, Book: bookInfo, DaysOverdue: loan.DaysOverdue(today)}
+		if lent.DaysOverdue > 0 {
+			report.Overdue = append(report.Overdue, lent)
+		} else {
+			report.Lent = append(report.Lent, lent)
+		}
+	}
+
+	sort.SliceStable(report.Overdue, func(i, j int) bool {
+		return report.Overdue[i].DaysOverdue > report.Overdue[j].DaysOverdue
+	})
+	sort.SliceStable(report.Lent, func(i, j int) bool {
+		return report.Lent[i].LoanedOn.Before(report.Lent[j].LoanedOn)
+	})
+
+	return report, nil
+}
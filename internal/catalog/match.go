@@ -0,0 +1,51 @@
+package catalog
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"à", "a", "è", "e", "ì", "i", "ò", "o", "ù", "u",
+	"â", "a", "ê", "e", "î", "i", "ô", "o", "û", "u",
+	"ä", "a", "ë", "e", "ï", "i", "ö", "o", "ç", "c", "ã", "a", "õ", "o",
+)
+
+// Goodreads appends the series to the title, e.g. "Los juegos del hambre (Los juegos del hambre, #1)".
+var seriesSuffix = regexp.MustCompile(`\s*\([^)]*#\d+[^)]*\)\s*$`)
+
+// normalizeText lowercases the text, removes accents and collapses punctuation and spaces so that
+// "Gótico carpintero" and "Gotico  Carpintero." compare equal.
+func normalizeText(text string) string {
+	text = accentReplacer.Replace(strings.ToLower(text))
+
+	var sb strings.Builder
+	lastSpace := true
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			lastSpace = false
+		} else if !lastSpace {
+			sb.WriteRune(' ')
+			lastSpace = true
+		}
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+// normalizeTitle also drops series suffixes and subtitles, which different sources spell differently.
+func normalizeTitle(title string) string {
+	title = seriesSuffix.ReplaceAllString(title, "")
+	if idx := strings.Index(title, ":"); idx > 0 {
+		title = title[:idx]
+	}
+
+	return normalizeText(title)
+}
+
+func normalizedTitleAuthorKey(title, author string) string {
+	return normalizeTitle(title) + "|" + normalizeText(author)
+}
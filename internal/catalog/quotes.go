@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"sort"
+	"strings"
+)
+
+// QuotedBook is a book with the quotes taken from it.
+type QuotedBook struct {
+	Book   book.BookInfo
+	Quotes []book.Quote
+}
+
+// QuotesByBook groups the quotes by their books, sorted by title, each one keeping the order of its
+// quotes.
+func QuotesByBook(bookDAO dao.DAO, quotes []book.Quote) ([]QuotedBook, error) {
+	var quotedBooks []QuotedBook
+	positions := map[int]int{}
+	for _, quote := range quotes {
+		position, ok := positions[quote.BookID]
+		if !ok {
+			bookInfo, err := bookDAO.GetBookByID(quote.BookID)
+			if err != nil {
+				return nil, fmt.Errorf("book %d: %v", quote.BookID, err)
+			}
+
+			position = len(quotedBooks)
+			positions[quote.BookID] = position
+			quotedBooks = append(quotedBooks, QuotedBook{Book: bookInfo})
+		}
+
+		quotedBooks[position].Quotes = append(quotedBooks[position].Quotes, quote)
+	}
+
+	sort.SliceStable(quotedBooks, func(i, j int) bool {
+		return strings.ToLower(quotedBooks[i].Book.Title) < strings.ToLower(quotedBooks[j].Book.Title)
+	})
+
+	return quotedBooks, nil
+}
+
+// WriteQuotesMarkdown writes the quotes as Markdown: a heading for each book and a quote block for
+// each passage, followed by its page and tags.
+func WriteQuotesMarkdown(w io.Writer, quotedBooks []QuotedBook) error {
+	var sb strings.Builder
+
+	sb.WriteString("# Citas\n")
+	for _, quotedBook := range quotedBooks {
+		sb.WriteString("\n## " + markdownLine(quotedBook.Book.Title))
+		if quotedBook.Book.Author != "" {
+			sb.WriteString(" — " + markdownLine(quotedBook.Book.Author))
+		}
+		sb.WriteString("\n")
+
+		for _, quote := range quotedBook.Quotes {
+			sb.WriteString("\n")
+			for _, line := range strings.Split(strings.ReplaceAll(quote.Text, "\r\n", "\n"), "\n") {
+				sb.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+			}
+
+			var details []string
+			if quote.Page > 0 {
+				details = append(details, fmt.Sprintf("p. %d", quote.Page))
+			}
+			for _, tag := range quote.Tags {
+				details = append(details, "#"+strings.ReplaceAll(tag, " ", "-"))
+			}
+			if len(details) > 0 {
+				sb.WriteString("\n" + strings.Join(details, " · ") + "\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// markdownLine keeps a title or a name in a single line of a heading.
+func markdownLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,112 @@
+// catalog holds the import and export routines used to keep the library in sync with external files
+package catalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RowAction is what an import did (or would do, on a dry run) with a single input row.
+type RowAction int
+
+const (
+	ActionCreate RowAction = iota
+	ActionUpdate
+	ActionUnchanged
+	ActionConflict
+	ActionError
+)
+
+func (a RowAction) String() string {
+	switch a {
+	case ActionCreate:
+		return "create"
+	case ActionUpdate:
+		return "update"
+	case ActionUnchanged:
+		return "unchanged"
+	case ActionConflict:
+		return "conflict"
+	default:
+		return "error"
+	}
+}
+
+// FieldChange describes a single field that differs between the catalogue and the imported row.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (fc FieldChange) String() string {
+	return fmt.Sprintf("%s: %q → %q", fc.Field, fc.Old, fc.New)
+}
+
+// RowResult ...
+type RowResult struct {
+	Row      int
+	Action   RowAction
+	BookID   int
+	Title    string
+	Author   string
+	Wishlist bool
+	Changes  []FieldChange
+	Note     string
+	Err      error
+}
+
+// Report is the per-row outcome of an import.
+type Report struct {
+	DryRun bool
+	Rows   []RowResult
+}
+
+// Count returns how many rows ended up with the given action.
+func (r Report) Count(action RowAction) int {
+	count := 0
+	for _, row := range r.Rows {
+		if row.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r Report) String() string {
+	var sb strings.Builder
+
+	for _, row := range r.Rows {
+		fmt.Fprintf(&sb, "row %d: %s", row.Row, row.Action)
+		if row.BookID > 0 {
+			fmt.Fprintf(&sb, " id=%d", row.BookID)
+		}
+		if row.Title != "" {
+			fmt.Fprintf(&sb, " %q by %q", row.Title, row.Author)
+		}
+		if row.Wishlist {
+			sb.WriteString(" (wishlist)")
+		}
+		if row.Note != "" {
+			fmt.Fprintf(&sb, ": %s", row.Note)
+		}
+		if row.Err != nil {
+			fmt.Fprintf(&sb, ": %v", row.Err)
+		}
+		sb.WriteString("\n")
+		for _, change := range row.Changes {
+			fmt.Fprintf(&sb, "    %s\n", change)
+		}
+	}
+
+	mode := "applied"
+	if r.DryRun {
+		mode = "dry run"
+	}
+
+	fmt.Fprintf(&sb, "%s: %d to create, %d to update, %d unchanged, %d conflicts, %d errors\n",
+		mode, r.Count(ActionCreate), r.Count(ActionUpdate), r.Count(ActionUnchanged), r.Count(ActionConflict), r.Count(ActionError))
+
+	return sb.String()
+}
@@ -0,0 +1,196 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"leonlib/internal/imagestore"
+	"leonlib/internal/isbn"
+	book "leonlib/internal/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IssueKind classifies a problem found by ValidateLibrary.
+type IssueKind string
+
+const (
+	MissingImage      IssueKind = "missing-image"
+	UnreferencedImage IssueKind = "unreferenced-image"
+	DuplicateImage    IssueKind = "duplicate-image"
+	DuplicateID       IssueKind = "duplicate-id"
+	EmptyTitle        IssueKind = "empty-title"
+	BadAddedOn        IssueKind = "bad-added-on"
+	BadISBN           IssueKind = "bad-isbn"
+	UnknownWork       IssueKind = "unknown-work"
+	BadContributor    IssueKind = "bad-contributor"
+	BadAcquisition    IssueKind = "bad-acquisition"
+)
+
+// Issue is a single inconsistency between books_db.toml and the images directory.
+type Issue struct {
+	Kind    IssueKind
+	BookID  int
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.BookID > 0 {
+		return fmt.Sprintf("%s: book %d: %s", i.Kind, i.BookID, i.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", i.Kind, i.Message)
+}
+
+// ValidationReport lists every issue found in the library.
+type ValidationReport struct {
+	Books  int
+	Images int
+	Issues []Issue
+}
+
+func (r ValidationReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Count returns how many issues are of the given kind.
+func (r ValidationReport) Count(kind IssueKind) int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r ValidationReport) String() string {
+	var sb strings.Builder
+
+	for _, issue := range r.Issues {
+		sb.WriteString(issue.String())
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "%d books, %d image files: %d missing, %d unreferenced, %d duplicated images, %d duplicated ids, %d empty titles, %d bad addedOn, %d bad ISBN, %d unknown works, %d bad contributors, %d bad acquisitions\n",
+		r.Books, r.Images, r.Count(MissingImage), r.Count(UnreferencedImage), r.Count(DuplicateImage),
+		r.Count(DuplicateID), r.Count(EmptyTitle), r.Count(BadAddedOn), r.Count(BadISBN), r.Count(UnknownWork), r.Count(BadContributor),
+		r.Count(BadAcquisition))
+
+	return sb.String()
+}
+
+// ValidateLibrary checks the books of libraryPath (books_db.toml) against the files of imagesDir.
+// Only the files directly inside imagesDir are library images, its directories (the README
+// screenshots, the image store) are left out.
+func ValidateLibrary(libraryPath, imagesDir string) (ValidationReport, error) {
+	var report ValidationReport
+
+	var library book.Library
+	if _, err := toml.DecodeFile(libraryPath, &library); err != nil {
+		return report, err
+	}
+	report.Books = len(library.Book)
+
+	works := map[int]bool{}
+	for _, work := range library.Work {
+		works[work.ID] = true
+	}
+
+	referenced := map[string]bool{}
+	booksByID := map[int]int{}
+	for _, bookInfo := range library.Book {
+		booksByID[bookInfo.ID]++
+		if booksByID[bookInfo.ID] == 2 {
+			report.add(DuplicateID, bookInfo.ID, "the id is used by more than one book")
+		}
+
+		if strings.TrimSpace(bookInfo.Title) == "" {
+			report.add(EmptyTitle, bookInfo.ID, "the title is empty")
+		}
+
+		if _, err := book.ParseAddedOn(bookInfo.AddedOn); err != nil {
+			report.add(BadAddedOn, bookInfo.ID, fmt.Sprintf("addedOn %q is not a YYYY-MM-DD date", bookInfo.AddedOn))
+		}
+
+		if _, err := isbn.Normalize(bookInfo.ISBN); err != nil {
+			report.add(BadISBN, bookInfo.ID, err.Error())
+		}
+
+		if bookInfo.WorkID != 0 && !works[bookInfo.WorkID] {
+			report.add(UnknownWork, bookInfo.ID, fmt.Sprintf("work %d is not in the library", bookInfo.WorkID))
+		}
+
+		for _, contributor := range bookInfo.Contributors {
+			if strings.TrimSpace(contributor.Name) == "" {
+				report.add(BadContributor, bookInfo.ID, "a contributor has no name")
+			} else if _, ok := book.ParseContributorRole(string(contributor.Role)); !ok {
+				report.add(BadContributor, bookInfo.ID, fmt.Sprintf("%s has the unknown role %q", contributor.Name, contributor.Role))
+			}
+		}
+
+		if err := bookInfo.Acquisition.Validate(); err != nil {
+			report.add(BadAcquisition, bookInfo.ID, err.Error())
+		}
+
+		for _, imageName := range bookInfo.ImageNames {
+			referenced[imageName] = true
+
+			fileInfo, err := os.Stat(filepath.Join(imagesDir, imageName))
+			if errors.Is(err, os.ErrNotExist) {
+				report.add(MissingImage, bookInfo.ID, fmt.Sprintf("%s does not exist", imageName))
+			} else if err != nil {
+				return report, err
+			} else if fileInfo.Size() == 0 {
+				report.add(MissingImage, bookInfo.ID, fmt.Sprintf("%s is empty", imageName))
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(imagesDir)
+	if err != nil {
+		return report, err
+	}
+
+	filesByHash := map[string][]string{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		report.Images++
+
+		if !referenced[entry.Name()] {
+			report.add(UnreferencedImage, 0, fmt.Sprintf("%s is not in any book", entry.Name()))
+		}
+
+		data, err := os.ReadFile(filepath.Join(imagesDir, entry.Name()))
+		if err != nil {
+			return report, err
+		}
+
+		hash := imagestore.Hash(data)
+		filesByHash[hash] = append(filesByHash[hash], entry.Name())
+	}
+
+	var duplicates []string
+	for _, names := range filesByHash {
+		if len(names) > 1 {
+			sort.Strings(names)
+			duplicates = append(duplicates, strings.Join(names, ", "))
+		}
+	}
+	sort.Strings(duplicates)
+
+	for _, names := range duplicates {
+		report.add(DuplicateImage, 0, fmt.Sprintf("%s have the same content", names))
+	}
+
+	return report, nil
+}
+
+func (r *ValidationReport) add(kind IssueKind, bookID int, message string) {
+	r.Issues = append(r.Issues, Issue{Kind: kind, BookID: bookID, Message: message})
+}
@@ -0,0 +1,248 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValuationTotal is what some books paid and are worth in a currency. Value is their insured value,
+// the estimated value or, without one, the price.
+type ValuationTotal struct {
+	Currency string
+	Books    int
+	Price    book.Money
+	Value    book.Money
+}
+
+// ValuationGroup is the books of an author or of a category, with a total for each currency they
+// were valued in.
+type ValuationGroup struct {
+	Name   string
+	Books  int
+	Totals []ValuationTotal
+}
+
+func (g *ValuationGroup) add(acquisition book.Acquisition) {
+	g.Books++
+
+	i := sort.Search(len(g.Totals), func(i int) bool { return g.Totals[i].Currency >= acquisition.Currency })
+	if i == len(g.Totals) || g.Totals[i].Currency != acquisition.Currency {
+		g.Totals = append(g.Totals[:i], append([]ValuationTotal{{Currency: acquisition.Currency}}, g.Totals[i:]...)...)
+	}
+
+	g.Totals[i].Books++
+	g.Totals[i].Price += acquisition.Price
+	g.Totals[i].Value += acquisition.InsuredValue()
+}
+
+// ValuationReport is what the books of the library are worth, for the insurance.
+type ValuationReport struct {
+	// Books are the books with a price or an estimated value, sorted by author and title.
+	Books []book.BookInfo
+	// ByAuthor and ByCategory group Books by their author and by the first level of their
+	// category, sorted by name.
+	ByAuthor   []ValuationGroup
+	ByCategory []ValuationGroup
+	Total      ValuationGroup
+	// Unvalued are the books without price nor estimated value, which the totals leave out.
+	Unvalued []book.BookInfo
+}
+
+func (r ValuationReport) String() string {
+	var sb strings.Builder
+
+	for _, total := range r.Total.Totals {
+		fmt.Fprintf(&sb, "%d books in %s: paid %s, worth %s\n", total.Books, currencyName(total.Currency), total.Price, total.Value)
+	}
+
+	fmt.Fprintf(&sb, "%d books valued, %d without a value\n", r.Total.Books, len(r.Unvalued))
+
+	return sb.String()
+}
+
+// NoCategory is the group of the books that are filed in no category.
+const NoCategory = "Sin categoría"
+
+// Valuation adds up what the books of the library paid and are worth, by author and by category.
+func Valuation(bookDAO dao.DAO) (ValuationReport, error) {
+	var report ValuationReport
+
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return report, err
+	}
+
+	sort.SliceStable(books, func(i, j int) bool {
+		if author, other := strings.ToLower(books[i].Author), strings.ToLower(books[j].Author); author != other {
+			return author < other
+		}
+
+		return strings.ToLower(books[i].Title) < strings.ToLower(books[j].Title)
+	})
+
+	byAuthor := map[string]*ValuationGroup{}
+	byCategory := map[string]*ValuationGroup{}
+	report.Total.Name = "Total"
+	for _, bookInfo := range books {
+		if bookInfo.Acquisition.InsuredValue() <= 0 {
+			report.Unvalued = append(report.Unvalued, bookInfo)
+			continue
+		}
+
+		report.Books = append(report.Books, bookInfo)
+		groupFor(byAuthor, bookInfo.Author).add(bookInfo.Acquisition)
+		groupFor(byCategory, valuationCategory(bookInfo.Category)).add(bookInfo.Acquisition)
+		report.Total.add(bookInfo.Acquisition)
+	}
+
+	report.ByAuthor = sortedGroups(byAuthor)
+	report.ByCategory = sortedGroups(byCategory)
+
+	return report, nil
+}
+
+func groupFor(groups map[string]*ValuationGroup, name string) *ValuationGroup {
+	group, ok := groups[name]
+	if !ok {
+		group = &ValuationGroup{Name: name}
+		groups[name] = group
+	}
+
+	return group
+}
+
+func sortedGroups(groups map[string]*ValuationGroup) []ValuationGroup {
+	sorted := make([]ValuationGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+
+	return sorted
+}
+
+// valuationCategory is the first level of a category, the one the valuation adds up by.
+func valuationCategory(category book.Category) string {
+	if len(category) == 0 {
+		return NoCategory
+	}
+
+	return category.Levels()[0].String()
+}
+
+// currencyName is a currency as the reports write it, a book without one is valued in "?".
+func currencyName(currency string) string {
+	if currency == "" {
+		return "?"
+	}
+
+	return currency
+}
+
+// ValuationBy is what the rows of the valuation CSV are.
+type ValuationBy string
+
+const (
+	ValuationByBook     ValuationBy = "book"
+	ValuationByAuthor   ValuationBy = "author"
+	ValuationByCategory ValuationBy = "category"
+)
+
+// ParseValuationBy accepts book, author and category; empty is book.
+func ParseValuationBy(s string) (ValuationBy, bool) {
+	switch by := ValuationBy(strings.ToLower(strings.TrimSpace(s))); by {
+	case "":
+		return ValuationByBook, true
+	case ValuationByBook, ValuationByAuthor, ValuationByCategory:
+		return by, true
+	default:
+		return "", false
+	}
+}
+
+// WriteValuationCSV writes the valuation as CSV: a row for each book, or a row for each author or
+// category and currency, followed by a Total row for each currency.
+func WriteValuationCSV(w io.Writer, report ValuationReport, by ValuationBy) error {
+	writer := csv.NewWriter(w)
+
+	if by == ValuationByBook {
+		if err := writer.Write([]string{"id", "title", "author", "category", "purchasedOn", "source", "condition", "currency", "price",
+			"estimatedValue", "insuredValue"}); err != nil {
+			return err
+		}
+
+		for _, bookInfo := range report.Books {
+			acquisition := bookInfo.Acquisition
+			purchasedOn := ""
+			if !acquisition.PurchasedOn.IsZero() {
+				purchasedOn = acquisition.PurchasedOn.Format(book.AddedOnLayout)
+			}
+
+			if err := writer.Write([]string{strconv.Itoa(bookInfo.ID), bookInfo.Title, bookInfo.Author, bookInfo.Category.String(), purchasedOn,
+				acquisition.Source, string(acquisition.Condition), acquisition.Currency, csvMoney(acquisition.Price),
+				csvMoney(acquisition.EstimatedValue), acquisition.InsuredValue().String()}); err != nil {
+				return err
+			}
+		}
+
+		for _, total := range report.Total.Totals {
+			if err := writer.Write([]string{"", report.Total.Name, "", "", "", "", "", total.Currency, total.Price.String(), "",
+				total.Value.String()}); err != nil {
+				return err
+			}
+		}
+	} else {
+		groups := report.ByAuthor
+		if by == ValuationByCategory {
+			groups = report.ByCategory
+		}
+
+		if err := writer.Write([]string{string(by), "currency", "books", "price", "insuredValue"}); err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			if err := writeValuationGroup(writer, group); err != nil {
+				return err
+			}
+		}
+
+		if err := writeValuationGroup(writer, report.Total); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// csvMoney leaves empty the amounts that are not known.
+func csvMoney(amount book.Money) string {
+	if amount <= 0 {
+		return ""
+	}
+
+	return amount.String()
+}
+
+// writeValuationGroup writes a row for each currency of the group.
+func writeValuationGroup(writer *csv.Writer, group ValuationGroup) error {
+	for _, total := range group.Totals {
+		if err := writer.Write([]string{group.Name, total.Currency, strconv.Itoa(total.Books), total.Price.String(),
+			total.Value.String()}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
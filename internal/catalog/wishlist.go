@@ -0,0 +1,110 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"leonlib/internal/dao"
+	"leonlib/internal/imaging"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CoverClient downloads the covers of the wish list books that are bought. It follows redirects
+// only to other http and https links.
+var CoverClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(r *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+
+		return checkCoverLink(r.URL)
+	},
+}
+
+// maxCoverSize is the largest cover downloaded, bigger ones are left out.
+const maxCoverSize = 10 << 20
+
+// PurchasedBook is a book of the wish list once it is in the catalogue.
+type PurchasedBook struct {
+	BookID int
+	// CoverErr is why the cover could not be downloaded or saved; the book is added without it.
+	CoverErr error
+}
+
+// PurchaseWishListBook moves a book of the wish list to the catalogue, bought on the day
+// purchasedOn: its title, author, description and Goodreads link go to a new book, with the cover
+// of its ImageLink, and it leaves the wish list. The cover is downloaded and checked before anything
+// is saved, and the book is created and taken out of the list at once.
+func PurchaseWishListBook(bookDAO dao.DAO, id int, purchasedOn time.Time) (PurchasedBook, error) {
+	var purchased PurchasedBook
+
+	wishListBook, err := bookDAO.GetWishListBookByID(id)
+	if err != nil {
+		return purchased, err
+	}
+
+	var cover []byte
+	if wishListBook.ImageLink != "" {
+		if cover, err = downloadCover(wishListBook.ImageLink); err != nil {
+			purchased.CoverErr = fmt.Errorf("cover %s: %v", wishListBook.ImageLink, err)
+		}
+	}
+
+	if purchased.BookID, err = bookDAO.PurchaseWishListBook(id, wishListBook.ToBook(purchasedOn)); err != nil {
+		return purchased, err
+	}
+
+	if cover != nil {
+		if err := bookDAO.AddImageToBook(purchased.BookID, cover); err != nil {
+			purchased.CoverErr = fmt.Errorf("cover %s: %v", wishListBook.ImageLink, err)
+		}
+	}
+
+	return purchased, nil
+}
+
+// checkCoverLink rejects the links that are not http or https, a cover is only downloaded from the
+// web and never from a file or another protocol.
+func checkCoverLink(link *url.URL) error {
+	if (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+		return fmt.Errorf("%q is not an http or https link", link.Redacted())
+	}
+
+	return nil
+}
+
+func downloadCover(link string) ([]byte, error) {
+	coverURL, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCoverLink(coverURL); err != nil {
+		return nil, err
+	}
+
+	response, err := CoverClient.Get(coverURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the server answered %s", response.Status)
+	}
+
+	cover, err := io.ReadAll(io.LimitReader(response.Body, maxCoverSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cover) > maxCoverSize {
+		return nil, fmt.Errorf("it is larger than %d MB", maxCoverSize>>20)
+	}
+
+	// The cover is stored like an upload, so whatever the server sent has to be an image.
+	return imaging.NormalizeUpload(cover)
+}
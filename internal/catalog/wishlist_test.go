@@ -0,0 +1,122 @@
+package catalog
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurchaseWishListBook(t *testing.T) {
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 40, 60)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cover.jpg":
+			_, _ = w.Write(photo.Bytes())
+		case "/redirect.jpg":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/login.html":
+			_, _ = w.Write([]byte("<html><body>Inicia sesión</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		imageLink string
+		wantCover bool
+	}{
+		{"without a cover", "", false},
+		{"with an image", server.URL + "/cover.jpg", true},
+		{"with a page instead of an image", server.URL + "/login.html", false},
+		{"with a missing cover", server.URL + "/missing.jpg", false},
+		{"with a file link", "file:///etc/passwd", false},
+		{"with another protocol", "ftp://example.com/cover.jpg", false},
+		{"with a redirect to a file", server.URL + "/redirect.jpg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookDAO, err := dao.OpenDAO("memory", "", "", "", "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bookDAO.Close()
+
+			wishListBookID, err := bookDAO.CreateWishListBook(book.WishListBook{Title: "Rayuela", Author: "Julio Cortázar",
+				ImageLink: tt.imageLink})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			purchased, err := PurchaseWishListBook(bookDAO, wishListBookID, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (purchased.CoverErr == nil) != (tt.wantCover || tt.imageLink == "") {
+				t.Errorf("CoverErr = %v", purchased.CoverErr)
+			}
+
+			bookInfo, err := bookDAO.GetBookByID(purchased.BookID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bookInfo.Title != "Rayuela" {
+				t.Errorf("the book is %q, want Rayuela", bookInfo.Title)
+			}
+			if got := len(bookInfo.Images) == 1; got != tt.wantCover {
+				t.Errorf("the book has %d images, want a cover: %t", len(bookInfo.Images), tt.wantCover)
+			}
+
+			if _, err := bookDAO.GetWishListBookByID(wishListBookID); err == nil {
+				t.Error("the book is still in the wish list")
+			}
+		})
+	}
+}
+
+func TestPurchaseMissingWishListBookAddsNothing(t *testing.T) {
+	bookDAO, err := dao.OpenDAO("memory", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bookDAO.Close()
+
+	if _, err := PurchaseWishListBook(bookDAO, 42, time.Now()); err == nil {
+		t.Fatal("a missing wish list book was purchased")
+	}
+
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 0 {
+		t.Errorf("the catalogue has %d books, want none", len(books))
+	}
+}
+
+func TestDownloadCoverOnlyFromTheWeb(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	}))
+	defer server.Close()
+
+	for _, link := range []string{"file:///etc/passwd", "ftp://example.com/cover.jpg", "/cover.jpg", server.URL + "/cover.jpg"} {
+		_, err := downloadCover(link)
+		if err == nil || !strings.Contains(err.Error(), "is not an http or https link") {
+			t.Errorf("downloadCover(%q) = %v, want the link rejected", link, err)
+		}
+	}
+}
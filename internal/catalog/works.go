@@ -0,0 +1,160 @@
+package catalog
+
+import (
+	"fmt"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"sort"
+	"strings"
+)
+
+// WorkGroup is a set of books GroupWorks takes for editions of the same work.
+type WorkGroup struct {
+	Work book.Work
+	// New tells the work did not exist, its ID is 0 on a dry run.
+	New bool
+	// BookIDs are the books put in the work, the ones already in it are not listed.
+	BookIDs []int
+}
+
+// WorksReport lists the works GroupWorks created or completed.
+type WorksReport struct {
+	DryRun bool
+	Groups []WorkGroup
+}
+
+// Books returns how many books were put in a work.
+func (r WorksReport) Books() int {
+	count := 0
+	for _, group := range r.Groups {
+		count += len(group.BookIDs)
+	}
+
+	return count
+}
+
+func (r WorksReport) String() string {
+	var sb strings.Builder
+
+	created := 0
+	for _, group := range r.Groups {
+		if group.New {
+			created++
+			sb.WriteString("new work")
+		} else {
+			fmt.Fprintf(&sb, "work %d", group.Work.ID)
+		}
+
+		bookIDs := make([]string, len(group.BookIDs))
+		for i, bookID := range group.BookIDs {
+			bookIDs[i] = fmt.Sprint(bookID)
+		}
+		fmt.Fprintf(&sb, " %q by %q: books %s\n", group.Work.Title, group.Work.Author, strings.Join(bookIDs, ", "))
+	}
+
+	mode := "applied"
+	if r.DryRun {
+		mode = "dry run"
+	}
+
+	fmt.Fprintf(&sb, "%s: %d works to create, %d books to group\n", mode, created, r.Books())
+
+	return sb.String()
+}
+
+// GroupWorks puts the books with the same normalized title and author in a work, as the editions
+// of it they are. When some of them are already in a work, or there is a work with that title and
+// author, the rest join it; books in a work are never moved to another one, that is done by hand.
+// Nothing is written unless commit is set.
+func GroupWorks(bookDAO dao.DAO, commit bool) (WorksReport, error) {
+	report := WorksReport{DryRun: !commit}
+
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return report, err
+	}
+
+	works, err := bookDAO.GetAllWorks()
+	if err != nil {
+		return report, err
+	}
+
+	worksByID := make(map[int]book.Work, len(works))
+	worksByKey := make(map[string]book.Work, len(works))
+	for _, work := range works {
+		worksByID[work.ID] = work
+		key := normalizedTitleAuthorKey(work.Title, work.Author)
+		if _, ok := worksByKey[key]; !ok {
+			worksByKey[key] = work
+		}
+	}
+
+	// GetAllBooks sorts by ID, so are the books of every group and the groups by their first book.
+	var keys []string
+	groups := map[string][]book.BookInfo{}
+	for _, bookInfo := range books {
+		if strings.TrimSpace(bookInfo.Title) == "" {
+			continue
+		}
+
+		key := normalizedTitleAuthorKey(bookInfo.Title, bookInfo.Author)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], bookInfo)
+	}
+
+	for _, key := range keys {
+		editions := groups[key]
+		existingWork, hasWork := worksByKey[key]
+		if len(editions) < 2 && !hasWork {
+			continue
+		}
+
+		var group WorkGroup
+		var ungrouped []book.BookInfo
+		for _, edition := range editions {
+			if edition.WorkID == 0 {
+				ungrouped = append(ungrouped, edition)
+			} else if group.Work.ID == 0 || edition.WorkID < group.Work.ID {
+				group.Work = worksByID[edition.WorkID]
+				group.Work.ID = edition.WorkID
+			}
+		}
+
+		if len(ungrouped) == 0 {
+			continue
+		}
+
+		if group.Work.ID == 0 && hasWork {
+			group.Work = existingWork
+		}
+
+		if group.Work.ID == 0 {
+			group.New = true
+			group.Work = book.Work{Title: editions[0].Title, Author: editions[0].Author}
+			if commit {
+				if group.Work.ID, err = bookDAO.CreateWork(group.Work); err != nil {
+					return report, fmt.Errorf("work %q: %v", group.Work.Title, err)
+				}
+			}
+		}
+
+		for _, edition := range ungrouped {
+			if commit {
+				if err := bookDAO.UpdateEdition(edition.ID, group.Work.ID, edition.Edition); err != nil {
+					return report, fmt.Errorf("book %d: %v", edition.ID, err)
+				}
+			}
+			group.BookIDs = append(group.BookIDs, edition.ID)
+		}
+
+		report.Groups = append(report.Groups, group)
+	}
+
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		return report.Groups[i].BookIDs[0] < report.Groups[j].BookIDs[0]
+	})
+
+	return report, nil
+}
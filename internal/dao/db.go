@@ -2,79 +2,196 @@ package dao
 
 import (
 	"database/sql"
-	"encoding/base64"
+	"errors"
 	"fmt"
+	"leonlib/internal/imagestore"
+	"leonlib/internal/imaging"
+	"leonlib/internal/isbn"
 	book "leonlib/internal/types"
 	user "leonlib/internal/types"
+	"log"
+	"strings"
+	"sync"
+	"time"
 )
 
 // DAO
 type DAO interface {
 	AddAll([]book.BookInfo) error
 	AddImageToBook(bookID int, imageData []byte) error
+	AddLike(like book.BookLike) error
 	AddUser(userID, email, name, oauthIdentifier string) error
+	CancelWishListReservation(wishListBookID int, userID string) error
 	Close() error
-	CreateBook(book book.BookInfo) error
-	GetAllAuthors() ([]string, error)
+	CollectImageGarbage() (int, error)
+	CreateAuthor(author book.Author) (int, error)
+	CreateBook(book book.BookInfo) (int, error)
+	CreateLoan(loan book.Loan) (int, error)
+	CreateQuote(quote book.Quote) (int, error)
+	CreateShelf(shelf book.Shelf) (int, error)
+	CreateWishListBook(wishListBook book.WishListBook) (int, error)
+	CreateWork(work book.Work) (int, error)
+	DeleteQuote(id int) error
+	DeleteReview(bookID int, userID string) error
+	DeleteShelf(id int) error
+	DeleteWishListBook(id int) error
+	ForEachImage(fn func(image book.BookImage) error) error
+	GetAllAuthors() ([]book.Author, error)
+	GetAllBooks() ([]book.BookInfo, error)
+	GetAllCategories() ([]book.CategoryCount, error)
+	GetAllLikes() ([]book.BookLike, error)
+	GetAllLoans() ([]book.Loan, error)
+	GetAllQuotes() ([]book.Quote, error)
+	GetAllReviews() ([]book.Review, error)
+	GetAllShelves() ([]book.Shelf, error)
+	GetAllTags() ([]book.Tag, error)
+	GetAllUsers() ([]user.User, error)
+	GetAllWorks() ([]book.Work, error)
+	GetAuthorByID(id int) (book.Author, error)
 	GetBookByID(id int) (book.BookInfo, error)
+	GetBookIDsByImageHash(hash string) ([]int, error)
 	GetBookCount() (int, error)
 	GetBooksWithPagination(offset, limit int) ([]book.BookInfo, error)
 	GetBooksBySearchTypeCoincidence(titleSearchText string, bookSearchType book.BookSearchType) ([]book.BookInfo, error)
-	GetWishListBooks() ([]book.WishListBook, error)
+	GetBooksByISBN(isbn string) ([]book.BookInfo, error)
+	GetBooksByAuthorID(authorID int) ([]book.BookInfo, error)
+	GetBooksByShelf(shelfID int) ([]book.BookInfo, error)
+	GetEditions(workID int) ([]book.BookInfo, error)
+	GetImage(imageID int) (book.BookImage, error)
+	GetImageVariant(imageID int, variant imaging.Variant) (book.BookImage, error)
 	GetImagesByBookID(bookID int) ([]book.BookImageInfo, error)
+	GetLoansByBookID(bookID int) ([]book.Loan, error)
+	GetQuoteByID(id int) (book.Quote, error)
+	GetQuotesByBookID(bookID int) ([]book.Quote, error)
+	GetRandomQuote() (book.Quote, error)
+	GetReview(bookID int, userID string) (book.Review, error)
+	GetReviewsByBookID(bookID int) ([]book.Review, error)
+	GetReviewsByUserID(userID string) ([]book.Review, error)
+	GetShelfByID(id int) (book.Shelf, error)
 	GetUserInfoByID(userID string) (user.UserInfo, error)
+	GetWishListBookByID(id int) (book.WishListBook, error)
+	GetWishListBooks(userID string) ([]book.WishListBook, error)
+	GetWishListReservations(ownerID string) ([]book.WishListReservation, error)
 	LikedBy(bookID, userID string) (bool, error)
 	LikeBook(bookID, userID string) error
 	LikesCount(bookID int) (int, error)
+	MergeAuthors(authorID, variantID int) error
 	Ping() error
+	PurchaseWishListBook(wishListBookID int, bookInfo book.BookInfo) (int, error)
+	RelocateBooks(shelfID int, bookIDs []int) error
 	RemoveImage(imageID int) error
+	ReorderImages(bookID int, imageIDs []int) error
+	ReserveWishListBook(reservation book.WishListReservation) error
+	RestoreImage(image book.BookImage) error
+	ReturnLoan(loanID int, returnedOn time.Time) error
+	SaveImageVariant(imageID int, variant imaging.Variant, data []byte) error
+	SaveReview(review book.Review) error
+	SearchQuotes(search string) ([]book.Quote, error)
+	SetAcquisition(bookID int, acquisition book.Acquisition) error
+	SetBookLocation(bookID int, location book.Location) error
+	SetCategory(bookID int, category book.Category) error
+	SetContributors(bookID int, contributors []book.Contributor) error
+	SetCoverImage(bookID, imageID int) error
+	SetReading(bookID int, status book.ReadingStatus, pages int, readings []book.Reading) error
+	SetTags(bookID int, tags []string) error
 	UnlikeBook(bookID, userID string) error
-	UpdateBook(title string, author string, description string, read bool, goodreadsLink string, id int) error
+	UpdateAuthor(author book.Author) error
+	UpdateBook(title string, author string, description string, read bool, goodreadsLink string, isbn string, id int) error
+	UpdateEdition(bookID, workID int, edition book.Edition) error
+	UpdateLoan(loan book.Loan) error
+	UpdateQuote(quote book.Quote) error
+	UpdateShelf(shelf book.Shelf) error
+	UpdateWishListBook(wishListBook book.WishListBook) error
 }
 
 type sqliteBookDAO struct {
-	db            *sql.DB
-	wishListBooks []book.WishListBook
+	db         *sql.DB
+	imageStore imagestore.Store
 }
 
 type postgresBookDAO struct {
-	db            *sql.DB
-	wishListBooks []book.WishListBook
+	db         *sql.DB
+	imageStore imagestore.Store
 }
 
 type memoryBookDAO struct {
+	// mu guards the maps, the web application serves requests at once. The methods ending in Locked
+	// expect it held and the public ones take it.
+	mu            sync.RWMutex
+	authors       *map[int]book.Author
 	books         *map[int]book.BookInfo
 	images        *map[int][]book.BookImageInfo
+	imageHashes   *map[int]string
+	imageFiles    *map[int]string
+	imageStore    imagestore.Store
+	imageVariants *map[int]map[imaging.Variant][]byte
 	bookLikes     *map[string][]string
-	wishListBooks []book.WishListBook
+	likedOn       *map[string]time.Time
+	loans         *map[int]book.Loan
+	quotes        *map[int]book.Quote
+	reviews       *map[reviewKey]book.Review
+	shelves       *map[int]book.Shelf
+	users         *map[string]user.User
+	works         *map[int]book.Work
+	wishListBooks *map[int]book.WishListBook
+	// reservations are by wish list book, a book has one at most.
+	reservations *map[int]book.WishListReservation
+}
+
+// SQLitePath is the database file used in sqlite mode.
+var SQLitePath = "/var/lib/appdata/leonlib.db"
+
+// ImageStoreDir is the directory the image store keeps its files in, usually images/. When it is
+// empty the images are kept in the database (or in memory, in memory mode).
+var ImageStoreDir = ""
+
+func newImageStore(db *sql.DB) imagestore.Store {
+	if ImageStoreDir != "" {
+		return imagestore.NewFileStore(ImageStoreDir)
+	}
+
+	if db == nil {
+		return imagestore.NewMemoryStore()
+	}
+
+	return imagestore.NewDBStore(db)
 }
 
 func NewDAO(dbMode, dbHost, dbPort, dbUser, dbPassword, dbName string) (DAO, error) {
 	var bookDAO DAO
 	switch dbMode {
 	case "sqlite":
-		DB, err := sql.Open("sqlite3", "/var/lib/appdata/leonlib.db")
+		DB, err := sql.Open("sqlite3", SQLitePath)
 		if err != nil {
 			return nil, err
 		}
-		wishListBooks, err := readWishListBooks()
+		// The wish list of library/ is loaded only into a new database: the books bought or
+		// deleted since must not come back.
+		hasWishList, err := sqliteTableExists(DB, "wish_list_books")
 		if err != nil {
 			return nil, err
 		}
+		imageStore := newImageStore(DB)
 		bookDAO = &sqliteBookDAO{
-			db:            DB,
-			wishListBooks: wishListBooks,
+			db:         DB,
+			imageStore: imageStore,
 		}
-		err = createDB(DB)
+		err = createDB(DB, imageStore)
 		if err != nil {
 			return nil, err
 		}
 
-		err = addBooksToDatabase(DB, &bookDAO)
+		err = addBooksToDatabase(bookDAO)
 		if err != nil {
 			return nil, err
 		}
 
+		if !hasWishList {
+			if err := addWishListToDatabase(bookDAO); err != nil {
+				return nil, err
+			}
+		}
+
 	case "postgres":
 		var psqlInfo string
 
@@ -86,21 +203,23 @@ func NewDAO(dbMode, dbHost, dbPort, dbUser, dbPassword, dbName string) (DAO, err
 		if err != nil {
 			return nil, err
 		}
-		wishListBooks, err := readWishListBooks()
-		if err != nil {
-			return nil, err
-		}
 		bookDAO = &postgresBookDAO{
-			db:            DB,
-			wishListBooks: wishListBooks,
+			db:         DB,
+			imageStore: newImageStore(DB),
+		}
+
+		// A database that cannot be read yet, or without database/sql/16_wish_list.sql, still
+		// starts: /admin/initdb loads the wish list later.
+		if err := AddWishList(bookDAO); err != nil {
+			log.Printf("warning: the wish list of library/ was not loaded: %v", err)
 		}
 
 	case "memory":
-		db, err := createInMemoryDatabaseFromFile()
+		db, works, authors, shelves, err := createInMemoryDatabaseFromFile()
 		if err != nil {
 			return nil, err
 		}
-		images, err := createInMemoryImagesDatabase(&db)
+		images, imageFiles, err := createInMemoryImagesDatabase(&db)
 		if err != nil {
 			return nil, err
 		}
@@ -109,78 +228,314 @@ func NewDAO(dbMode, dbHost, dbPort, dbUser, dbPassword, dbName string) (DAO, err
 			return nil, err
 		}
 		bookDAO = &memoryBookDAO{
+			authors:       &authors,
 			books:         &db,
 			images:        &images,
+			imageHashes:   &map[int]string{},
+			imageFiles:    &imageFiles,
+			imageStore:    newImageStore(nil),
+			imageVariants: &map[int]map[imaging.Variant][]byte{},
 			bookLikes:     createInMemoryLikesDatabase(),
-			wishListBooks: wishListBooks,
+			likedOn:       &map[string]time.Time{},
+			loans:         &map[int]book.Loan{},
+			quotes:        &map[int]book.Quote{},
+			reviews:       &map[reviewKey]book.Review{},
+			shelves:       &shelves,
+			users:         &map[string]user.User{},
+			works:         &works,
+			wishListBooks: &wishListBooks,
+			reservations:  &map[int]book.WishListReservation{},
 		}
 	}
 
 	return bookDAO, nil
 }
 
-func getAllAuthors(db *sql.DB) ([]string, error) {
-	var err error
+// OpenDAO opens a backend like NewDAO does but leaves its content alone: the books in library/ are
+// not loaded into it and, in memory mode, it starts empty. Migrations read and write through it.
+func OpenDAO(dbMode, dbHost, dbPort, dbUser, dbPassword, dbName string) (DAO, error) {
+	switch dbMode {
+	case "sqlite":
+		DB, err := sql.Open("sqlite3", SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+
+		imageStore := newImageStore(DB)
+		if err := createDB(DB, imageStore); err != nil {
+			_ = DB.Close()
+			return nil, err
+		}
+
+		return &sqliteBookDAO{db: DB, imageStore: imageStore}, nil
+
+	case "postgres":
+		return NewDAO(dbMode, dbHost, dbPort, dbUser, dbPassword, dbName)
+
+	case "memory":
+		return &memoryBookDAO{
+			authors:       &map[int]book.Author{},
+			books:         &map[int]book.BookInfo{},
+			images:        &map[int][]book.BookImageInfo{},
+			imageHashes:   &map[int]string{},
+			imageFiles:    &map[int]string{},
+			imageStore:    newImageStore(nil),
+			imageVariants: &map[int]map[imaging.Variant][]byte{},
+			bookLikes:     createInMemoryLikesDatabase(),
+			likedOn:       &map[string]time.Time{},
+			loans:         &map[int]book.Loan{},
+			quotes:        &map[int]book.Quote{},
+			reviews:       &map[reviewKey]book.Review{},
+			shelves:       &map[int]book.Shelf{},
+			users:         &map[string]user.User{},
+			works:         &map[int]book.Work{},
+			wishListBooks: &map[int]book.WishListBook{},
+			reservations:  &map[int]book.WishListReservation{},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown database mode %q", dbMode)
+}
+
+// getAllAuthors returns every author, as author of their books or otherwise, in the order of their
+// sort names.
+func getAllAuthors(db *sql.DB) ([]book.Author, error) {
+	return queryAuthors(db, ``)
+}
+
+// queryAuthors returns the authors matching the condition, which goes after WHERE, with their
+// aliases and how many books they contributed to.
+func queryAuthors(db *sql.DB, where string, args ...any) ([]book.Author, error) {
+	if where != "" {
+		where = `WHERE ` + where
+	}
 
-	allAuthorsRows, err := db.Query("SELECT DISTINCT author FROM books ORDER BY author")
+	rows, err := db.Query(`
+		SELECT a.id, a.name, a.sort_name, COALESCE(a.bio, ''), COALESCE(a.photo_url, ''), COUNT(DISTINCT c.book_id)
+		FROM authors a
+		LEFT JOIN book_contributors c ON c.author_id = a.id
+		`+where+`
+		GROUP BY a.id, a.name, a.sort_name, a.bio, a.photo_url
+		ORDER BY LOWER(COALESCE(NULLIF(a.sort_name, ''), a.name)), a.id`, args...)
 	if err != nil {
-		return []string{}, err
+		return nil, err
 	}
 
-	defer allAuthorsRows.Close()
+	defer rows.Close()
 
-	var authors []string
-	for allAuthorsRows.Next() {
-		var author string
-		if err := allAuthorsRows.Scan(&author); err != nil {
-			return []string{}, err
+	authors := []book.Author{}
+	positions := map[int]int{}
+	for rows.Next() {
+		var author book.Author
+		if err := rows.Scan(&author.ID, &author.Name, &author.SortName, &author.Bio, &author.PhotoURL, &author.Books); err != nil {
+			return nil, err
 		}
+		positions[author.ID] = len(authors)
 		authors = append(authors, author)
 	}
 
-	return authors, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	aliasRows, err := db.Query(`SELECT author_id, alias FROM author_aliases ORDER BY alias`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer aliasRows.Close()
+
+	for aliasRows.Next() {
+		var authorID int
+		var alias string
+		if err := aliasRows.Scan(&authorID, &alias); err != nil {
+			return nil, err
+		}
+
+		if i, ok := positions[authorID]; ok {
+			authors[i].Aliases = append(authors[i].Aliases, alias)
+		}
+	}
+
+	return authors, aliasRows.Err()
 }
 
-func addImageToBook(bookID int, imageData []byte, db *sql.DB) error {
-	if len(imageData) == 0 {
-		return nil
+// getAuthorByID returns the author with their aliases. An author that does not exist comes back
+// empty, without an error.
+func getAuthorByID(db *sql.DB, id int) (book.Author, error) {
+	authors, err := queryAuthors(db, `a.id = $1`, id)
+	if err != nil || len(authors) == 0 {
+		return book.Author{}, err
+	}
+
+	return authors[0], nil
+}
+
+// createAuthor inserts an author and their aliases. An author with an ID keeps it, and when that ID
+// already exists nothing changes: the authors of the library are created again every time it is
+// loaded.
+func createAuthor(db *sql.DB, author book.Author) (int, error) {
+	if author.SortName == "" {
+		author.SortName = book.SortName(author.Name)
 	}
 
-	imgStmt, err := db.Prepare("INSERT INTO book_images(book_id, image) VALUES($1, $2)")
+	var err error
+	if author.ID <= 0 {
+		err = db.QueryRow(`INSERT INTO authors(name, sort_name, bio, photo_url) VALUES($1, $2, $3, $4) RETURNING id`,
+			author.Name, author.SortName, author.Bio, author.PhotoURL).Scan(&author.ID)
+	} else {
+		_, err = db.Exec(`INSERT INTO authors(id, name, sort_name, bio, photo_url) VALUES($1, $2, $3, $4, $5) ON CONFLICT(id) DO NOTHING`,
+			author.ID, author.Name, author.SortName, author.Bio, author.PhotoURL)
+	}
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	for _, alias := range author.Aliases {
+		if _, err := db.Exec(`INSERT INTO author_aliases(alias, author_id) VALUES($1, $2) ON CONFLICT(alias) DO NOTHING`, alias, author.ID); err != nil {
+			return author.ID, err
+		}
+	}
+
+	return author.ID, nil
+}
+
+// resolveAuthorID returns the author a contributor is: the one of their AuthorID when it exists, or
+// else the one with their name or an alias of it. A name nobody has yet becomes a new author.
+func resolveAuthorID(tx *sql.Tx, contributor book.Contributor) (int, error) {
+	var authorID int
+	if contributor.AuthorID > 0 {
+		err := tx.QueryRow(`SELECT id FROM authors WHERE id=$1`, contributor.AuthorID).Scan(&authorID)
+		if err == nil || !errors.Is(err, sql.ErrNoRows) {
+			return authorID, err
+		}
+	}
+
+	err := tx.QueryRow(`
+		SELECT id FROM (
+			SELECT id, 0 AS alias FROM authors WHERE LOWER(name) = LOWER($1)
+			UNION ALL
+			SELECT author_id, 1 AS alias FROM author_aliases WHERE LOWER(alias) = LOWER($1)
+		) found ORDER BY alias, id LIMIT 1`, contributor.Name).Scan(&authorID)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return authorID, err
+	}
+
+	err = tx.QueryRow(`INSERT INTO authors(name, sort_name) VALUES($1, $2) RETURNING id`,
+		contributor.Name, book.SortName(contributor.Name)).Scan(&authorID)
+
+	return authorID, err
+}
+
+// getBooksByAuthorID returns the books an author contributed to, with their images.
+func getBooksByAuthorID(db *sql.DB, authorID int) ([]book.BookInfo, error) {
+	books, err := queryBooks(db, `SELECT `+bookColumns+` FROM books
+		WHERE id IN (SELECT book_id FROM book_contributors WHERE author_id=$1) ORDER BY title, id`, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withImages(db, books)
+}
+
+// updateAuthor changes an author, the name they had becomes an alias so the books that write it
+// that way keep finding them.
+func updateAuthor(db *sql.DB, author book.Author) error {
+	if author.SortName == "" {
+		author.SortName = book.SortName(author.Name)
 	}
 
-	_, err = imgStmt.Exec(bookID, imageData)
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	return nil
+	var name string
+	if err := tx.QueryRow(`SELECT name FROM authors WHERE id=$1`, author.ID).Scan(&name); err != nil {
+		return fmt.Errorf("author %d: %w", author.ID, err)
+	}
+
+	if _, err := tx.Exec(`UPDATE authors SET name=$1, sort_name=$2, bio=$3, photo_url=$4 WHERE id=$5`,
+		author.Name, author.SortName, author.Bio, author.PhotoURL, author.ID); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(name, author.Name) {
+		if _, err := tx.Exec(`INSERT INTO author_aliases(alias, author_id) VALUES($1, $2) ON CONFLICT(alias) DO NOTHING`, name, author.ID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM author_aliases WHERE author_id=$1 AND LOWER(alias)=LOWER($2)`, author.ID, author.Name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func getBookCount(db *sql.DB) (int, error) {
-	rows, err := db.Query(`SELECT count(*) FROM books`)
+// mergeAuthors makes the variant one more name of the author: their books, aliases, and bio or
+// photo when the author has none, go to the author and the variant is removed. The books keep the
+// name as they write it.
+func mergeAuthors(db *sql.DB, authorID, variantID int) error {
+	if authorID == variantID {
+		return fmt.Errorf("author %d cannot be merged with itself", authorID)
+	}
+
+	tx, err := db.Begin()
 	if err != nil {
-		return -1, err
+		return err
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	var count int
+	var name, variantName string
+	if err := tx.QueryRow(`SELECT name FROM authors WHERE id=$1`, authorID).Scan(&name); err != nil {
+		return fmt.Errorf("author %d: %w", authorID, err)
+	}
+	if err := tx.QueryRow(`SELECT name FROM authors WHERE id=$1`, variantID).Scan(&variantName); err != nil {
+		return fmt.Errorf("author %d: %w", variantID, err)
+	}
 
-	for rows.Next() {
-		err := rows.Scan(&count)
-		if err != nil {
-			return -1, err
+	commands := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE author_aliases SET author_id=$1 WHERE author_id=$2`, []any{authorID, variantID}},
+		{`INSERT INTO author_aliases(alias, author_id) VALUES($1, $2) ON CONFLICT(alias) DO UPDATE SET author_id=excluded.author_id`,
+			[]any{variantName, authorID}},
+		{`DELETE FROM author_aliases WHERE author_id=$1 AND LOWER(alias)=LOWER($2)`, []any{authorID, name}},
+		{`UPDATE book_contributors SET author_id=$1 WHERE author_id=$2`, []any{authorID, variantID}},
+		{`UPDATE authors SET
+			bio = COALESCE(NULLIF(bio, ''), (SELECT bio FROM authors WHERE id=$2)),
+			photo_url = COALESCE(NULLIF(photo_url, ''), (SELECT photo_url FROM authors WHERE id=$2))
+		WHERE id=$1`, []any{authorID, variantID}},
+		{`DELETE FROM authors WHERE id=$1`, []any{variantID}},
+	}
+	for _, command := range commands {
+		if _, err := tx.Exec(command.query, command.args...); err != nil {
+			return err
 		}
 	}
 
-	return count, nil
+	return tx.Commit()
 }
 
-func getBooksWithPagination(offset, limit int, db *sql.DB) ([]book.BookInfo, error) {
-	query := `SELECT id, title, author, description, read, added_on FROM books ORDER BY title LIMIT $1 OFFSET $2;`
+// bookColumns are the columns queryBooks expects, in its order.
+const bookColumns = `id, title, author, description, read, added_on, goodreads_link, isbn, work_id, publisher, published_year, language, format, category,
+	shelf_id, shelf_position, reading_status, pages, purchased_on, purchase_price, currency, purchased_from, book_condition, estimated_value`
 
-	rows, err := db.Query(query, limit, offset)
+func getAllBooks(db *sql.DB) ([]book.BookInfo, error) {
+	return queryBooks(db, `SELECT `+bookColumns+` FROM books ORDER BY id`)
+}
+
+// queryBooks runs a query selecting the bookColumns, the books come with their contributors and
+// tags but without their images.
+func queryBooks(db *sql.DB, query string, args ...any) ([]book.BookInfo, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -189,86 +544,2147 @@ func getBooksWithPagination(offset, limit int, db *sql.DB) ([]book.BookInfo, err
 
 	books := []book.BookInfo{}
 	for rows.Next() {
-		book := book.BookInfo{}
-		err = rows.Scan(&book.ID, &book.Title, &book.Author, &book.Description, &book.HasBeenRead, &book.AddedOn)
-		if err != nil {
+		var bookInfo book.BookInfo
+		var description sql.NullString
+		var addedOn time.Time
+		var goodreadsLink sql.NullString
+		var isbn sql.NullString
+		var workID sql.NullInt64
+		var publisher, language, format sql.NullString
+		var year sql.NullInt64
+		var category sql.NullString
+		var shelfID, shelfPosition sql.NullInt64
+		var pages sql.NullInt64
+		var purchasedOn sql.NullTime
+		var price, estimatedValue sql.NullInt64
+		var currency, source, condition sql.NullString
+		if err := rows.Scan(&bookInfo.ID, &bookInfo.Title, &bookInfo.Author, &description, &bookInfo.HasBeenRead, &addedOn, &goodreadsLink, &isbn,
+			&workID, &publisher, &year, &language, &format, &category, &shelfID, &shelfPosition, &bookInfo.ReadingStatus, &pages,
+			&purchasedOn, &price, &currency, &source, &condition, &estimatedValue); err != nil {
 			return nil, err
 		}
-		books = append(books, book)
+
+		bookInfo.Description = description.String
+		bookInfo.AddedOn = addedOn.Format(book.AddedOnLayout)
+		bookInfo.GoodreadsLink = goodreadsLink.String
+		bookInfo.ISBN = isbn.String
+		bookInfo.WorkID = int(workID.Int64)
+		bookInfo.Edition = book.Edition{
+			Publisher: publisher.String,
+			Year:      int(year.Int64),
+			Language:  language.String,
+			Format:    format.String,
+		}
+		bookInfo.Category = book.ParseCategory(category.String)
+		bookInfo.Location = book.Location{ShelfID: int(shelfID.Int64), Position: int(shelfPosition.Int64)}
+		bookInfo.Pages = int(pages.Int64)
+		bookInfo.Acquisition = book.Acquisition{
+			Price:          book.Money(price.Int64),
+			Currency:       currency.String,
+			Source:         source.String,
+			Condition:      book.Condition(condition.String),
+			EstimatedValue: book.Money(estimatedValue.Int64),
+		}
+		if purchasedOn.Valid {
+			bookInfo.Acquisition.PurchasedOn = book.Day(purchasedOn.Time)
+		}
+		books = append(books, bookInfo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := addContributors(db, books); err != nil {
+		return nil, err
+	}
+
+	if err := addTags(db, books); err != nil {
+		return nil, err
+	}
+
+	if err := addReadings(db, books); err != nil {
+		return nil, err
+	}
+
+	if err := addLoans(db, books); err != nil {
+		return nil, err
 	}
 
 	return books, nil
 }
 
-func addUser(db *sql.DB, userID, email, name, oauthIdentifier string) error {
-	_, err := db.Exec(`
-			INSERT INTO users(user_id, email, name, oauth_identifier) 
-			VALUES($1, $2, $3, $4)
-			ON CONFLICT(user_id) DO UPDATE
-			SET email = $2, name = $3`, userID, email, name, oauthIdentifier)
+// addContributors sets the contributors of the books. A single book queries its own, a list
+// queries all of them at once.
+func addContributors(db *sql.DB, books []book.BookInfo) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	query := `SELECT book_id, name, role, COALESCE(author_id, 0) FROM book_contributors ORDER BY book_id, position`
+	var args []any
+	if len(books) == 1 {
+		query = `SELECT book_id, name, role, COALESCE(author_id, 0) FROM book_contributors WHERE book_id=$1 ORDER BY position`
+		args = append(args, books[0].ID)
+	}
 
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return err
 	}
 
+	defer rows.Close()
+
+	contributors := map[int][]book.Contributor{}
+	for rows.Next() {
+		var bookID int
+		var contributor book.Contributor
+		if err := rows.Scan(&bookID, &contributor.Name, &contributor.Role, &contributor.AuthorID); err != nil {
+			return err
+		}
+		contributors[bookID] = append(contributors[bookID], contributor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range books {
+		books[i].Contributors = contributors[books[i].ID]
+	}
+
 	return nil
 }
 
-func getImagesByBookID(bookID int, db *sql.DB) ([]book.BookImageInfo, error) {
-	bookImagesRows, err := db.Query(`SELECT i.image_id, i.book_id, i.image FROM book_images i WHERE i.book_id=$1`, bookID)
-	if err != nil {
-		return []book.BookImageInfo{}, err
+// setContributors replaces the contributors of a book and updates its author to theirs.
+func setContributors(db *sql.DB, bookID int, contributors []book.Contributor) error {
+	bookInfo := book.BookInfo{ID: bookID, Contributors: contributors}
+	bookInfo.NormalizeContributors()
+	if len(bookInfo.Contributors) == 0 {
+		return fmt.Errorf("book %d: it needs at least one contributor", bookID)
 	}
 
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
 	defer func() {
-		_ = bookImagesRows.Close()
+		_ = tx.Rollback()
 	}()
 
-	var images []book.BookImageInfo
+	if _, err := tx.Exec(`DELETE FROM book_contributors WHERE book_id=$1`, bookID); err != nil {
+		return err
+	}
 
-	for bookImagesRows.Next() {
-		var imageID int
-		var bookID int
-		var base64Image []byte
-		if err = bookImagesRows.Scan(&imageID, &bookID, &base64Image); err != nil {
-			return []book.BookImageInfo{}, err
+	for position, contributor := range bookInfo.Contributors {
+		authorID, err := resolveAuthorID(tx, contributor)
+		if err != nil {
+			return fmt.Errorf("%s: %v", contributor.Name, err)
 		}
 
-		if len(base64Image) > 0 {
-			encodedImage := base64.StdEncoding.EncodeToString(base64Image)
-			bookImageInfo := book.BookImageInfo{
-				ImageID: imageID,
-				BookID:  bookID,
-				Image:   encodedImage,
-			}
-			images = append(images, bookImageInfo)
+		if _, err := tx.Exec(`INSERT INTO book_contributors(book_id, position, name, role, author_id) VALUES($1, $2, $3, $4, $5)`,
+			bookID, position, contributor.Name, string(contributor.Role), authorID); err != nil {
+			return err
 		}
 	}
 
-	return images, nil
+	if _, err := tx.Exec(`UPDATE books SET author=$1 WHERE id=$2`, bookInfo.Author, bookID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func updateBook(title string, author string, description string, read bool, goodreadsLink string, id int, db *sql.DB) error {
-	bookUpdate, err := db.Prepare(`
-		UPDATE books SET 
-			title = $1,
-			author = $2,
-			description = $3,
-			read = $4,
-			goodreads_link = $5
-		WHERE id = $6
-	`)
+// addTags sets the tags of the books. Like addContributors, a single book queries its own and a
+// list queries all of them at once.
+func addTags(db *sql.DB, books []book.BookInfo) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	query := `SELECT book_id, tag FROM book_tags ORDER BY book_id, tag`
+	var args []any
+	if len(books) == 1 {
+		query = `SELECT book_id, tag FROM book_tags WHERE book_id=$1 ORDER BY tag`
+		args = append(args, books[0].ID)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	tags := map[int][]string{}
+	for rows.Next() {
+		var bookID int
+		var tag string
+		if err := rows.Scan(&bookID, &tag); err != nil {
+			return err
+		}
+		tags[bookID] = append(tags[bookID], tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range books {
+		books[i].Tags = tags[books[i].ID]
+	}
+
+	return nil
+}
+
+// setTags replaces the tags of a book.
+func setTags(db *sql.DB, bookID int, tags []string) error {
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
 	defer func() {
-		_ = bookUpdate.Close()
+		_ = tx.Rollback()
 	}()
 
-	_, err = bookUpdate.Exec(title, author, description, read, goodreadsLink, id)
+	if _, err := tx.Exec(`DELETE FROM book_tags WHERE book_id=$1`, bookID); err != nil {
+		return err
+	}
+
+	for _, tag := range book.NormalizeTags(tags) {
+		if _, err := tx.Exec(`INSERT INTO book_tags(book_id, tag) VALUES($1, $2)`, bookID, tag); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// addReadings sets the read-throughs of the books. Like addContributors, a single book queries its
+// own and a list queries all of them at once.
+func addReadings(db *sql.DB, books []book.BookInfo) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	query := `SELECT book_id, started_on, finished_on, current_page FROM book_readings ORDER BY book_id, position`
+	var args []any
+	if len(books) == 1 {
+		query = `SELECT book_id, started_on, finished_on, current_page FROM book_readings WHERE book_id=$1 ORDER BY position`
+		args = append(args, books[0].ID)
+	}
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return err
 	}
 
+	defer rows.Close()
+
+	readings := map[int][]book.Reading{}
+	for rows.Next() {
+		var bookID int
+		var startedOn, finishedOn sql.NullTime
+		var reading book.Reading
+		if err := rows.Scan(&bookID, &startedOn, &finishedOn, &reading.CurrentPage); err != nil {
+			return err
+		}
+
+		if startedOn.Valid {
+			reading.StartedOn = book.Day(startedOn.Time)
+		}
+		if finishedOn.Valid {
+			reading.FinishedOn = book.Day(finishedOn.Time)
+		}
+		readings[bookID] = append(readings[bookID], reading)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range books {
+		books[i].Readings = readings[books[i].ID]
+	}
+
 	return nil
 }
+
+// pagesColumn is the number of pages of a book, or the page of a quote, NULL when it is not known.
+func pagesColumn(pages int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(pages), Valid: pages > 0}
+}
+
+// setReading replaces the reading status, the pages and the read-throughs of a book, and marks it as
+// read when they say so.
+func setReading(db *sql.DB, bookID int, status book.ReadingStatus, pages int, readings []book.Reading) error {
+	bookInfo := book.BookInfo{ID: bookID, ReadingStatus: status, Pages: pages, Readings: readings}
+	if err := bookInfo.ValidateReading(); err != nil {
+		return err
+	}
+	bookInfo.NormalizeReading()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(`UPDATE books SET reading_status=$1, pages=$2, read=$3 WHERE id=$4`,
+		string(bookInfo.ReadingStatus), pagesColumn(bookInfo.Pages), bookInfo.HasBeenRead, bookID)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(result, fmt.Sprintf("book %d", bookID)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM book_readings WHERE book_id=$1`, bookID); err != nil {
+		return err
+	}
+
+	for position, reading := range bookInfo.Readings {
+		if _, err := tx.Exec(`INSERT INTO book_readings(book_id, position, started_on, finished_on, current_page) VALUES($1, $2, $3, $4, $5)`,
+			bookID, position, dateColumn(reading.StartedOn), dateColumn(reading.FinishedOn), reading.CurrentPage); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// moneyColumn is a price or a value of a book in cents, NULL when it is not known.
+func moneyColumn(amount book.Money) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(amount), Valid: amount > 0}
+}
+
+// acquisitionArgs are the values of the purchased_on, purchase_price, currency, purchased_from,
+// book_condition and estimated_value columns.
+func acquisitionArgs(acquisition book.Acquisition) []any {
+	return []any{dateColumn(acquisition.PurchasedOn), moneyColumn(acquisition.Price), acquisition.Currency, acquisition.Source,
+		string(acquisition.Condition), moneyColumn(acquisition.EstimatedValue)}
+}
+
+// setAcquisition replaces how a book was bought and what it is worth.
+func setAcquisition(db *sql.DB, bookID int, acquisition book.Acquisition) error {
+	if err := acquisition.Validate(); err != nil {
+		return err
+	}
+	acquisition.Normalize()
+
+	result, err := db.Exec(`UPDATE books SET purchased_on=$1, purchase_price=$2, currency=$3, purchased_from=$4, book_condition=$5, estimated_value=$6
+		WHERE id=$7`, append(acquisitionArgs(acquisition), bookID)...)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("book %d", bookID))
+}
+
+// categoryColumn is the category of a book, NULL when it is filed in none.
+func categoryColumn(category book.Category) sql.NullString {
+	return sql.NullString{String: category.String(), Valid: len(category) > 0}
+}
+
+func setCategory(db *sql.DB, bookID int, category book.Category) error {
+	_, err := db.Exec(`UPDATE books SET category=$1 WHERE id=$2`, categoryColumn(category), bookID)
+
+	return err
+}
+
+// getAllTags returns every tag with how many books have it, sorted by name.
+func getAllTags(db *sql.DB) ([]book.Tag, error) {
+	rows, err := db.Query(`SELECT tag, COUNT(*) FROM book_tags GROUP BY tag ORDER BY tag`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := []book.Tag{}
+	for rows.Next() {
+		var tag book.Tag
+		if err := rows.Scan(&tag.Name, &tag.Books); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// getAllCategories returns the categories the books are filed in, with how many books are right
+// in each of them.
+func getAllCategories(db *sql.DB) ([]book.CategoryCount, error) {
+	rows, err := db.Query(`SELECT category, COUNT(*) FROM books WHERE category IS NOT NULL GROUP BY category ORDER BY category`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	categories := []book.CategoryCount{}
+	for rows.Next() {
+		var category string
+		var count book.CategoryCount
+		if err := rows.Scan(&category, &count.Books); err != nil {
+			return nil, err
+		}
+		count.Category = book.ParseCategory(category)
+		categories = append(categories, count)
+	}
+
+	return categories, rows.Err()
+}
+
+// queryShelves returns the shelves matching the condition, which goes after WHERE, with how many
+// books are on them. They are sorted by room, bookcase and name.
+func queryShelves(db *sql.DB, where string, args ...any) ([]book.Shelf, error) {
+	if where != "" {
+		where = `WHERE ` + where
+	}
+
+	rows, err := db.Query(`
+		SELECT s.id, s.room, s.bookcase, s.name, COUNT(b.id)
+		FROM shelves s
+		LEFT JOIN books b ON b.shelf_id = s.id
+		`+where+`
+		GROUP BY s.id, s.room, s.bookcase, s.name
+		ORDER BY LOWER(s.room), LOWER(s.bookcase), LOWER(s.name), s.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	shelves := []book.Shelf{}
+	for rows.Next() {
+		var shelf book.Shelf
+		if err := rows.Scan(&shelf.ID, &shelf.Room, &shelf.Bookcase, &shelf.Name, &shelf.Books); err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, shelf)
+	}
+
+	return shelves, rows.Err()
+}
+
+func getAllShelves(db *sql.DB) ([]book.Shelf, error) {
+	return queryShelves(db, ``)
+}
+
+// getShelfByID returns the shelf. A shelf that does not exist comes back empty, without an error.
+func getShelfByID(db *sql.DB, id int) (book.Shelf, error) {
+	shelves, err := queryShelves(db, `s.id = $1`, id)
+	if err != nil || len(shelves) == 0 {
+		return book.Shelf{}, err
+	}
+
+	return shelves[0], nil
+}
+
+// createShelf inserts a shelf. A shelf with an ID keeps it, and when that ID already exists nothing
+// changes: the shelves of the library are created again every time it is loaded.
+func createShelf(db *sql.DB, shelf book.Shelf) (int, error) {
+	if shelf.ID <= 0 {
+		var shelfID int
+		err := db.QueryRow(`INSERT INTO shelves(room, bookcase, name) VALUES($1, $2, $3) RETURNING id`,
+			shelf.Room, shelf.Bookcase, shelf.Name).Scan(&shelfID)
+
+		return shelfID, err
+	}
+
+	_, err := db.Exec(`INSERT INTO shelves(id, room, bookcase, name) VALUES($1, $2, $3, $4) ON CONFLICT(id) DO NOTHING`,
+		shelf.ID, shelf.Room, shelf.Bookcase, shelf.Name)
+
+	return shelf.ID, err
+}
+
+func updateShelf(db *sql.DB, shelf book.Shelf) error {
+	result, err := db.Exec(`UPDATE shelves SET room=$1, bookcase=$2, name=$3 WHERE id=$4`, shelf.Room, shelf.Bookcase, shelf.Name, shelf.ID)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("shelf %d", shelf.ID))
+}
+
+// deleteShelf removes a shelf, its books are left without a location.
+func deleteShelf(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.Exec(`UPDATE books SET shelf_id=NULL, shelf_position=NULL WHERE shelf_id=$1`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM shelves WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+
+	if err := expectOneRow(result, fmt.Sprintf("shelf %d", id)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// expectOneRow fails when a statement did not change the row of what it names, which does not exist.
+func expectOneRow(result sql.Result, what string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s does not exist", what)
+	}
+
+	return nil
+}
+
+// getBooksByShelf returns the books of a shelf with their images, in the order they are on it.
+func getBooksByShelf(db *sql.DB, shelfID int) ([]book.BookInfo, error) {
+	books, err := queryBooks(db, `SELECT `+bookColumns+` FROM books WHERE shelf_id=$1 ORDER BY shelf_position, title, id`, shelfID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withImages(db, books)
+}
+
+// setBookLocation puts a book on a shelf. Without a position it goes after the last book of the
+// shelf; at the position of another book, that book and the ones after it move one place to the
+// right. A location without shelf takes the book off its shelf.
+func setBookLocation(db *sql.DB, bookID int, location book.Location) error {
+	if location.IsZero() {
+		_, err := db.Exec(`UPDATE books SET shelf_id=NULL, shelf_position=NULL WHERE id=$1`, bookID)
+
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM shelves WHERE id=$1)`, location.ShelfID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("shelf %d does not exist", location.ShelfID)
+	}
+
+	if location.Position <= 0 {
+		err := tx.QueryRow(`SELECT COALESCE(MAX(shelf_position), 0) + 1 FROM books WHERE shelf_id=$1 AND id<>$2`,
+			location.ShelfID, bookID).Scan(&location.Position)
+		if err != nil {
+			return err
+		}
+	} else {
+		var taken bool
+		err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM books WHERE shelf_id=$1 AND shelf_position=$2 AND id<>$3)`,
+			location.ShelfID, location.Position, bookID).Scan(&taken)
+		if err != nil {
+			return err
+		}
+
+		if taken {
+			if _, err := tx.Exec(`UPDATE books SET shelf_position=shelf_position+1 WHERE shelf_id=$1 AND shelf_position>=$2 AND id<>$3`,
+				location.ShelfID, location.Position, bookID); err != nil {
+				return err
+			}
+		}
+	}
+
+	result, err := tx.Exec(`UPDATE books SET shelf_id=$1, shelf_position=$2 WHERE id=$3`, location.ShelfID, location.Position, bookID)
+	if err != nil {
+		return err
+	}
+
+	if err := expectOneRow(result, fmt.Sprintf("book %d", bookID)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// relocateBooks makes the books the content of the shelf, in that order: it is how a reorganized
+// shelf is recorded, and how books are moved to it in bulk. The books that were on the shelf and
+// are not among them are left without a location.
+func relocateBooks(db *sql.DB, shelfID int, bookIDs []int) error {
+	if err := checkRelocation(bookIDs); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM shelves WHERE id=$1)`, shelfID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("shelf %d does not exist", shelfID)
+	}
+
+	if _, err := tx.Exec(`UPDATE books SET shelf_id=NULL, shelf_position=NULL WHERE shelf_id=$1`, shelfID); err != nil {
+		return err
+	}
+
+	for i, bookID := range bookIDs {
+		result, err := tx.Exec(`UPDATE books SET shelf_id=$1, shelf_position=$2 WHERE id=$3`, shelfID, i+1, bookID)
+		if err != nil {
+			return err
+		}
+
+		if err := expectOneRow(result, fmt.Sprintf("book %d", bookID)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// checkRelocation rejects a book that is twice in the order of a shelf.
+func checkRelocation(bookIDs []int) error {
+	seen := make(map[int]bool, len(bookIDs))
+	for _, bookID := range bookIDs {
+		if seen[bookID] {
+			return fmt.Errorf("book %d is more than once on the shelf", bookID)
+		}
+		seen[bookID] = true
+	}
+
+	return nil
+}
+
+// loanColumns are the columns queryLoans expects, in its order.
+const loanColumns = `id, book_id, borrower, loaned_on, due_on, returned_on, notes`
+
+func queryLoans(db *sql.DB, query string, args ...any) ([]book.Loan, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	loans := []book.Loan{}
+	for rows.Next() {
+		var loan book.Loan
+		var loanedOn time.Time
+		var dueOn, returnedOn sql.NullTime
+		var notes sql.NullString
+		if err := rows.Scan(&loan.ID, &loan.BookID, &loan.Borrower, &loanedOn, &dueOn, &returnedOn, &notes); err != nil {
+			return nil, err
+		}
+
+		loan.LoanedOn = book.Day(loanedOn)
+		if dueOn.Valid {
+			loan.DueOn = book.Day(dueOn.Time)
+		}
+		if returnedOn.Valid {
+			loan.ReturnedOn = book.Day(returnedOn.Time)
+		}
+		loan.Notes = notes.String
+		loans = append(loans, loan)
+	}
+
+	return loans, rows.Err()
+}
+
+func getAllLoans(db *sql.DB) ([]book.Loan, error) {
+	return queryLoans(db, `SELECT `+loanColumns+` FROM loans ORDER BY id`)
+}
+
+// getLoansByBookID returns the loans of a book, the latest first.
+func getLoansByBookID(db *sql.DB, bookID int) ([]book.Loan, error) {
+	return queryLoans(db, `SELECT `+loanColumns+` FROM loans WHERE book_id=$1 ORDER BY loaned_on DESC, id DESC`, bookID)
+}
+
+// addLoans sets the current loan of the books that are lent. A single book queries its own, a list
+// queries all of them at once.
+func addLoans(db *sql.DB, books []book.BookInfo) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	query := `SELECT ` + loanColumns + ` FROM loans WHERE returned_on IS NULL`
+	var args []any
+	if len(books) == 1 {
+		query += ` AND book_id=$1`
+		args = append(args, books[0].ID)
+	}
+
+	loans, err := queryLoans(db, query, args...)
+	if err != nil {
+		return err
+	}
+
+	loansByBook := make(map[int]book.Loan, len(loans))
+	for _, loan := range loans {
+		loansByBook[loan.BookID] = loan
+	}
+
+	for i := range books {
+		books[i].CurrentLoan = loansByBook[books[i].ID]
+	}
+
+	return nil
+}
+
+// dateColumn is the value of a nullable date column, NULL for the zero time.
+func dateColumn(date time.Time) sql.NullTime {
+	if date.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: book.Day(date), Valid: true}
+}
+
+// checkLoan rejects a loan that is not valid, one of a book that does not exist, and one that is not
+// returned while the book is lent to someone else.
+func checkLoan(db *sql.DB, loan book.Loan) error {
+	if err := loan.Validate(); err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM books WHERE id=$1)`, loan.BookID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("book %d does not exist", loan.BookID)
+	}
+
+	if loan.Returned() {
+		return nil
+	}
+
+	var borrower string
+	err := db.QueryRow(`SELECT borrower FROM loans WHERE book_id=$1 AND returned_on IS NULL AND id<>$2`, loan.BookID, loan.ID).Scan(&borrower)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("book %d is lent to %s", loan.BookID, borrower)
+}
+
+// createLoan lends a book. A loan with an ID keeps it, and when that ID already exists nothing
+// changes, like the other entities migrations copy.
+func createLoan(db *sql.DB, loan book.Loan) (int, error) {
+	if err := checkLoan(db, loan); err != nil {
+		return 0, err
+	}
+
+	if loan.ID <= 0 {
+		var loanID int
+		err := db.QueryRow(`INSERT INTO loans(book_id, borrower, loaned_on, due_on, returned_on, notes) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`,
+			loan.BookID, loan.Borrower, book.Day(loan.LoanedOn), dateColumn(loan.DueOn), dateColumn(loan.ReturnedOn), loan.Notes).Scan(&loanID)
+
+		return loanID, err
+	}
+
+	_, err := db.Exec(`INSERT INTO loans(id, book_id, borrower, loaned_on, due_on, returned_on, notes) VALUES($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT(id) DO NOTHING`,
+		loan.ID, loan.BookID, loan.Borrower, book.Day(loan.LoanedOn), dateColumn(loan.DueOn), dateColumn(loan.ReturnedOn), loan.Notes)
+
+	return loan.ID, err
+}
+
+// returnLoan records that the book of a loan is back.
+func returnLoan(db *sql.DB, loanID int, returnedOn time.Time) error {
+	result, err := db.Exec(`UPDATE loans SET returned_on=$1 WHERE id=$2 AND returned_on IS NULL`, book.Day(returnedOn), loanID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("loan %d does not exist or was already returned", loanID)
+	}
+
+	return nil
+}
+
+// updateLoan changes a loan, its book stays the same.
+func updateLoan(db *sql.DB, loan book.Loan) error {
+	err := db.QueryRow(`SELECT book_id FROM loans WHERE id=$1`, loan.ID).Scan(&loan.BookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("loan %d does not exist", loan.ID)
+	} else if err != nil {
+		return err
+	}
+
+	if err := checkLoan(db, loan); err != nil {
+		return err
+	}
+
+	result, err := db.Exec(`UPDATE loans SET borrower=$1, loaned_on=$2, due_on=$3, returned_on=$4, notes=$5 WHERE id=$6`,
+		loan.Borrower, book.Day(loan.LoanedOn), dateColumn(loan.DueOn), dateColumn(loan.ReturnedOn), loan.Notes, loan.ID)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("loan %d", loan.ID))
+}
+
+// reviewColumns are the columns queryReviews expects, in its order.
+const reviewColumns = `r.book_id, r.user_id, COALESCE(u.name, ''), r.rating, r.review, r.notes, r.updated_on
+	FROM book_reviews r LEFT JOIN users u ON u.user_id = r.user_id`
+
+func queryReviews(db *sql.DB, query string, args ...any) ([]book.Review, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reviews := []book.Review{}
+	for rows.Next() {
+		var review book.Review
+		if err := rows.Scan(&review.BookID, &review.UserID, &review.UserName, &review.Rating, &review.Text, &review.Notes,
+			&review.UpdatedOn); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+func getAllReviews(db *sql.DB) ([]book.Review, error) {
+	return queryReviews(db, `SELECT `+reviewColumns+` ORDER BY r.book_id, r.user_id`)
+}
+
+// getReview returns the review of a book by a user, an empty one when they have not reviewed it.
+func getReview(db *sql.DB, bookID int, userID string) (book.Review, error) {
+	reviews, err := queryReviews(db, `SELECT `+reviewColumns+` WHERE r.book_id=$1 AND r.user_id=$2`, bookID, userID)
+	if err != nil || len(reviews) == 0 {
+		return book.Review{BookID: bookID, UserID: userID}, err
+	}
+
+	return reviews[0], nil
+}
+
+// getReviewsByBookID returns the reviews of a book, the latest first.
+func getReviewsByBookID(db *sql.DB, bookID int) ([]book.Review, error) {
+	return queryReviews(db, `SELECT `+reviewColumns+` WHERE r.book_id=$1 ORDER BY r.updated_on DESC, r.user_id`, bookID)
+}
+
+// getReviewsByUserID returns the reviews of a user, the latest first.
+func getReviewsByUserID(db *sql.DB, userID string) ([]book.Review, error) {
+	return queryReviews(db, `SELECT `+reviewColumns+` WHERE r.user_id=$1 ORDER BY r.updated_on DESC, r.book_id`, userID)
+}
+
+// saveReview creates or replaces the review of a book by a user, dated now unless it has a date
+// already. A review left empty is deleted.
+func saveReview(db *sql.DB, review book.Review) error {
+	if err := review.Validate(); err != nil {
+		return err
+	}
+
+	if review.IsEmpty() {
+		return deleteReview(db, review.BookID, review.UserID)
+	}
+
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM books WHERE id=$1)`, review.BookID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("book %d does not exist", review.BookID)
+	}
+
+	if review.UpdatedOn.IsZero() {
+		review.UpdatedOn = time.Now()
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO book_reviews(book_id, user_id, rating, review, notes, updated_on) VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT(book_id, user_id) DO UPDATE
+		SET rating = excluded.rating, review = excluded.review, notes = excluded.notes, updated_on = excluded.updated_on`,
+		review.BookID, review.UserID, review.Rating, strings.TrimSpace(review.Text), strings.TrimSpace(review.Notes), review.UpdatedOn.UTC())
+
+	return err
+}
+
+func deleteReview(db *sql.DB, bookID int, userID string) error {
+	_, err := db.Exec(`DELETE FROM book_reviews WHERE book_id=$1 AND user_id=$2`, bookID, userID)
+
+	return err
+}
+
+// quoteColumns are the columns queryQuotes expects, in its order.
+const quoteColumns = `id, book_id, quote, page, added_on`
+
+// queryQuotes runs a query of quotes and adds their tags.
+func queryQuotes(db *sql.DB, query string, args ...any) ([]book.Quote, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	quotes := []book.Quote{}
+	for rows.Next() {
+		var quote book.Quote
+		var page sql.NullInt64
+		var addedOn time.Time
+		if err := rows.Scan(&quote.ID, &quote.BookID, &quote.Text, &page, &addedOn); err != nil {
+			return nil, err
+		}
+
+		quote.Page = int(page.Int64)
+		quote.AddedOn = book.Day(addedOn)
+		quotes = append(quotes, quote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return quotes, addQuoteTags(db, quotes)
+}
+
+// addQuoteTags sets the tags of the quotes. Like addTags, a single quote queries its own and a list
+// queries all of them at once.
+func addQuoteTags(db *sql.DB, quotes []book.Quote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+
+	query := `SELECT quote_id, tag FROM quote_tags ORDER BY quote_id, tag`
+	var args []any
+	if len(quotes) == 1 {
+		query = `SELECT quote_id, tag FROM quote_tags WHERE quote_id=$1 ORDER BY tag`
+		args = append(args, quotes[0].ID)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	tags := map[int][]string{}
+	for rows.Next() {
+		var quoteID int
+		var tag string
+		if err := rows.Scan(&quoteID, &tag); err != nil {
+			return err
+		}
+		tags[quoteID] = append(tags[quoteID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range quotes {
+		quotes[i].Tags = tags[quotes[i].ID]
+	}
+
+	return nil
+}
+
+func getAllQuotes(db *sql.DB) ([]book.Quote, error) {
+	return queryQuotes(db, `SELECT `+quoteColumns+` FROM quotes ORDER BY id`)
+}
+
+func getQuoteByID(db *sql.DB, id int) (book.Quote, error) {
+	quotes, err := queryQuotes(db, `SELECT `+quoteColumns+` FROM quotes WHERE id=$1`, id)
+	if err != nil {
+		return book.Quote{}, err
+	} else if len(quotes) == 0 {
+		return book.Quote{}, fmt.Errorf("quote %d does not exist", id)
+	}
+
+	return quotes[0], nil
+}
+
+// getQuotesByBookID returns the quotes of a book in the order of their pages.
+func getQuotesByBookID(db *sql.DB, bookID int) ([]book.Quote, error) {
+	return queryQuotes(db, `SELECT `+quoteColumns+` FROM quotes WHERE book_id=$1 ORDER BY COALESCE(page, 0), id`, bookID)
+}
+
+// getRandomQuote returns any of the quotes, an empty one when there are none.
+func getRandomQuote(db *sql.DB) (book.Quote, error) {
+	quotes, err := queryQuotes(db, `SELECT `+quoteColumns+` FROM quotes ORDER BY RANDOM() LIMIT 1`)
+	if err != nil || len(quotes) == 0 {
+		return book.Quote{}, err
+	}
+
+	return quotes[0], nil
+}
+
+// searchQuotes returns the quotes with every word of the search in their text or their tags, by book
+// and page. An empty search returns them all.
+func searchQuotes(db *sql.DB, search string) ([]book.Quote, error) {
+	var conditions []string
+	var args []any
+	for _, word := range book.SearchWords(search) {
+		args = append(args, word)
+		conditions = append(conditions, fmt.Sprintf(`(LOWER(quote) LIKE '%%' || $%[1]d || '%%'
+			OR id IN (SELECT quote_id FROM quote_tags WHERE tag LIKE '%%' || $%[1]d || '%%'))`, len(args)))
+	}
+
+	query := `SELECT ` + quoteColumns + ` FROM quotes`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	return queryQuotes(db, query+` ORDER BY book_id, COALESCE(page, 0), id`, args...)
+}
+
+// checkQuote rejects a quote that is not valid and one of a book that does not exist.
+func checkQuote(db *sql.DB, quote book.Quote) error {
+	if err := quote.Validate(); err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM books WHERE id=$1)`, quote.BookID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("book %d does not exist", quote.BookID)
+	}
+
+	return nil
+}
+
+// createQuote saves a quote with its tags, added today unless it has a date. A quote with an ID keeps
+// it, and when that ID already exists nothing changes, like the other entities migrations copy.
+func createQuote(db *sql.DB, quote book.Quote) (int, error) {
+	if err := checkQuote(db, quote); err != nil {
+		return 0, err
+	}
+
+	if quote.AddedOn.IsZero() {
+		quote.AddedOn = time.Now()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	if quote.ID <= 0 {
+		err = tx.QueryRow(`INSERT INTO quotes(book_id, quote, page, added_on) VALUES($1, $2, $3, $4) RETURNING id`,
+			quote.BookID, strings.TrimSpace(quote.Text), pagesColumn(quote.Page), book.Day(quote.AddedOn)).Scan(&quote.ID)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		result, err := tx.Exec(`INSERT INTO quotes(id, book_id, quote, page, added_on) VALUES($1, $2, $3, $4, $5) ON CONFLICT(id) DO NOTHING`,
+			quote.ID, quote.BookID, strings.TrimSpace(quote.Text), pagesColumn(quote.Page), book.Day(quote.AddedOn))
+		if err != nil {
+			return 0, err
+		}
+
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return quote.ID, err
+		}
+	}
+
+	if err := insertQuoteTags(tx, quote.ID, quote.Tags); err != nil {
+		return 0, err
+	}
+
+	return quote.ID, tx.Commit()
+}
+
+func insertQuoteTags(tx *sql.Tx, quoteID int, tags []string) error {
+	for _, tag := range book.NormalizeTags(tags) {
+		if _, err := tx.Exec(`INSERT INTO quote_tags(quote_id, tag) VALUES($1, $2)`, quoteID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateQuote changes the text, the page and the tags of a quote, its book and date stay the same.
+func updateQuote(db *sql.DB, quote book.Quote) error {
+	err := db.QueryRow(`SELECT book_id FROM quotes WHERE id=$1`, quote.ID).Scan(&quote.BookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("quote %d does not exist", quote.ID)
+	} else if err != nil {
+		return err
+	}
+
+	if err := quote.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE quotes SET quote=$1, page=$2 WHERE id=$3`, strings.TrimSpace(quote.Text), pagesColumn(quote.Page), quote.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM quote_tags WHERE quote_id=$1`, quote.ID); err != nil {
+		return err
+	}
+
+	if err := insertQuoteTags(tx, quote.ID, quote.Tags); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteQuote removes a quote with its tags.
+func deleteQuote(db *sql.DB, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM quote_tags WHERE quote_id=$1`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM quotes WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+
+	if err := expectOneRow(result, fmt.Sprintf("quote %d", id)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// wishListColumns are the columns queryWishListBooks expects, in its order.
+const wishListColumns = `id, title, author, description, image_link, goodreads_link, priority, user_id`
+
+// queryWishListBooks returns the books of the wish list matching the condition, which goes after
+// WHERE, the most wanted first.
+func queryWishListBooks(db *sql.DB, where string, args ...any) ([]book.WishListBook, error) {
+	if where != "" {
+		where = `WHERE ` + where
+	}
+
+	rows, err := db.Query(`SELECT `+wishListColumns+` FROM wish_list_books `+where+` ORDER BY priority, LOWER(title), id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	wishListBooks := []book.WishListBook{}
+	for rows.Next() {
+		var wishListBook book.WishListBook
+		if err := rows.Scan(&wishListBook.ID, &wishListBook.Title, &wishListBook.Author, &wishListBook.Description, &wishListBook.ImageLink,
+			&wishListBook.GoodreadsLink, &wishListBook.Priority, &wishListBook.UserID); err != nil {
+			return nil, err
+		}
+		wishListBooks = append(wishListBooks, wishListBook)
+	}
+
+	return wishListBooks, rows.Err()
+}
+
+// getWishListBooks returns the wish list of a user, the one of the library when userID is empty.
+func getWishListBooks(db *sql.DB, userID string) ([]book.WishListBook, error) {
+	return queryWishListBooks(db, `user_id = $1`, userID)
+}
+
+func getWishListBookByID(db *sql.DB, id int) (book.WishListBook, error) {
+	wishListBooks, err := queryWishListBooks(db, `id = $1`, id)
+	if err != nil {
+		return book.WishListBook{}, err
+	}
+
+	if len(wishListBooks) == 0 {
+		return book.WishListBook{}, fmt.Errorf("wish list book %d does not exist", id)
+	}
+
+	return wishListBooks[0], nil
+}
+
+// createWishListBook inserts a book in the wish list. A book with an ID keeps it, and when that ID
+// already exists nothing changes, like the shelves.
+func createWishListBook(db *sql.DB, wishListBook book.WishListBook) (int, error) {
+	if err := wishListBook.Validate(); err != nil {
+		return 0, err
+	}
+	wishListBook.Normalize()
+
+	if wishListBook.ID <= 0 {
+		var wishListBookID int
+		err := db.QueryRow(`INSERT INTO wish_list_books(title, author, description, image_link, goodreads_link, priority, user_id)
+			VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`, wishListBook.Title, wishListBook.Author, wishListBook.Description,
+			wishListBook.ImageLink, wishListBook.GoodreadsLink, wishListBook.Priority, wishListBook.UserID).Scan(&wishListBookID)
+
+		return wishListBookID, err
+	}
+
+	_, err := db.Exec(`INSERT INTO wish_list_books(`+wishListColumns+`) VALUES($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT(id) DO NOTHING`,
+		wishListBook.ID, wishListBook.Title, wishListBook.Author, wishListBook.Description, wishListBook.ImageLink,
+		wishListBook.GoodreadsLink, wishListBook.Priority, wishListBook.UserID)
+
+	return wishListBook.ID, err
+}
+
+// updateWishListBook changes a book of a wish list, the list it is in stays the same.
+func updateWishListBook(db *sql.DB, wishListBook book.WishListBook) error {
+	if err := wishListBook.Validate(); err != nil {
+		return err
+	}
+	wishListBook.Normalize()
+
+	result, err := db.Exec(`UPDATE wish_list_books SET title=$1, author=$2, description=$3, image_link=$4, goodreads_link=$5, priority=$6
+		WHERE id=$7`, wishListBook.Title, wishListBook.Author, wishListBook.Description, wishListBook.ImageLink, wishListBook.GoodreadsLink,
+		wishListBook.Priority, wishListBook.ID)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("wish list book %d", wishListBook.ID))
+}
+
+// deleteWishListBook deletes a book of a wish list and its reservation.
+func deleteWishListBook(db sqlRunner, id int) error {
+	if _, err := db.Exec(`DELETE FROM wish_list_reservations WHERE wish_list_book_id=$1`, id); err != nil {
+		return err
+	}
+
+	result, err := db.Exec(`DELETE FROM wish_list_books WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("wish list book %d", id))
+}
+
+// purchaseWishListBook adds the book bought from the wish list to the catalogue and takes it out
+// of the list, both or neither. The book of a wish list has no contributors, tags, location or
+// readings, and its cover is added once the book is saved, because the image store may write to
+// the same database.
+func purchaseWishListBook(db *sql.DB, wishListBookID int, bookInfo book.BookInfo) (int, error) {
+	if err := normalizeBook(&bookInfo); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	bookID, err := insertBook(tx, bookInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := deleteWishListBook(tx, wishListBookID); err != nil {
+		return 0, err
+	}
+
+	return bookID, tx.Commit()
+}
+
+// ErrWishListBookReserved is the error of reserving a gift somebody else reserved first.
+var ErrWishListBookReserved = errors.New("the wish list book is already reserved")
+
+// getWishListReservations returns the reservations of the books in the wish list of a user.
+func getWishListReservations(db *sql.DB, ownerID string) ([]book.WishListReservation, error) {
+	rows, err := db.Query(`SELECT r.wish_list_book_id, r.user_id, r.reserved_on FROM wish_list_reservations r
+		JOIN wish_list_books w ON w.id = r.wish_list_book_id WHERE w.user_id = $1 ORDER BY r.wish_list_book_id`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reservations := []book.WishListReservation{}
+	for rows.Next() {
+		var reservation book.WishListReservation
+		if err := rows.Scan(&reservation.WishListBookID, &reservation.UserID, &reservation.ReservedOn); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+// reserveWishListBook reserves a gift for a user. Reserving again what the same user reserved
+// changes nothing; what somebody else reserved is ErrWishListBookReserved.
+func reserveWishListBook(db *sql.DB, reservation book.WishListReservation) error {
+	if err := checkWishListReservation(db, reservation); err != nil {
+		return err
+	}
+
+	if reservation.ReservedOn.IsZero() {
+		reservation.ReservedOn = time.Now()
+	}
+
+	result, err := db.Exec(`INSERT INTO wish_list_reservations(wish_list_book_id, user_id, reserved_on) VALUES($1, $2, $3)
+		ON CONFLICT(wish_list_book_id) DO NOTHING`, reservation.WishListBookID, reservation.UserID, reservation.ReservedOn.UTC())
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	var reservedBy string
+	if err := db.QueryRow(`SELECT user_id FROM wish_list_reservations WHERE wish_list_book_id=$1`, reservation.WishListBookID).Scan(&reservedBy); err != nil {
+		return err
+	}
+
+	if reservedBy != reservation.UserID {
+		return ErrWishListBookReserved
+	}
+
+	return nil
+}
+
+// checkWishListReservation checks that the book is in the wish list of a user other than the one
+// who reserves it: the books of the library are not gifts, and nobody reserves their own.
+func checkWishListReservation(db *sql.DB, reservation book.WishListReservation) error {
+	if reservation.UserID == "" {
+		return fmt.Errorf("a reservation needs a user")
+	}
+
+	wishListBook, err := getWishListBookByID(db, reservation.WishListBookID)
+	if err != nil {
+		return err
+	}
+
+	return wishListBook.CheckReservation(reservation.UserID)
+}
+
+// cancelWishListReservation frees a gift a user reserved.
+func cancelWishListReservation(db *sql.DB, wishListBookID int, userID string) error {
+	result, err := db.Exec(`DELETE FROM wish_list_reservations WHERE wish_list_book_id=$1 AND user_id=$2`, wishListBookID, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Sprintf("the reservation of wish list book %d", wishListBookID))
+}
+
+// withImages sets the images of every book.
+func withImages(db *sql.DB, books []book.BookInfo) ([]book.BookInfo, error) {
+	for i := range books {
+		bookImages, err := getImagesByBookID(books[i].ID, db)
+		if err != nil {
+			return nil, err
+		}
+
+		books[i].SetImages(bookImages)
+	}
+
+	return books, nil
+}
+
+// getBookByID returns the book with its images. A book that does not exist comes back empty,
+// without an error.
+func getBookByID(db *sql.DB, id int) (book.BookInfo, error) {
+	books, err := queryBooks(db, `SELECT `+bookColumns+` FROM books WHERE id=$1`, id)
+	if err != nil {
+		return book.BookInfo{}, err
+	}
+
+	var bookInfo book.BookInfo
+	if len(books) > 0 {
+		bookInfo = books[0]
+	}
+
+	bookImages, err := getImagesByBookID(id, db)
+	if err != nil {
+		return book.BookInfo{}, err
+	}
+
+	bookInfo.SetImages(bookImages)
+
+	return bookInfo, nil
+}
+
+func getBooksBySearchTypeCoincidence(db *sql.DB, searchText string, bookSearchType book.BookSearchType) ([]book.BookInfo, error) {
+	where := `LOWER(title) LIKE '%' || LOWER($1) || '%'`
+	if bookSearchType == book.ByAuthor {
+		// Translators, editors... are found as well, and the books of an author by any of their names.
+		where = `LOWER(author) LIKE '%' || LOWER($1) || '%'
+			OR id IN (
+				SELECT c.book_id FROM book_contributors c LEFT JOIN authors a ON a.id = c.author_id
+				WHERE LOWER(c.name) LIKE '%' || LOWER($1) || '%'
+					OR LOWER(a.name) LIKE '%' || LOWER($1) || '%'
+					OR c.author_id IN (SELECT author_id FROM author_aliases WHERE LOWER(alias) LIKE '%' || LOWER($1) || '%'))`
+	}
+
+	query := `SELECT ` + bookColumns + ` FROM books WHERE ` + where + ` ORDER BY title`
+	books, err := queryBooks(db, query, "%"+searchText+"%")
+	if err != nil {
+		return []book.BookInfo{}, err
+	}
+
+	return withImages(db, books)
+}
+
+func getAllWorks(db *sql.DB) ([]book.Work, error) {
+	rows, err := db.Query(`SELECT id, title, author FROM works ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	works := []book.Work{}
+	for rows.Next() {
+		var work book.Work
+		if err := rows.Scan(&work.ID, &work.Title, &work.Author); err != nil {
+			return nil, err
+		}
+		works = append(works, work)
+	}
+
+	return works, rows.Err()
+}
+
+// createWork inserts a work. A work with an ID keeps it, and when that ID already exists nothing
+// changes: the works of the library are created again every time it is loaded.
+func createWork(db *sql.DB, work book.Work) (int, error) {
+	if work.ID <= 0 {
+		var workID int
+		err := db.QueryRow(`INSERT INTO works(title, author) VALUES($1, $2) RETURNING id`, work.Title, work.Author).Scan(&workID)
+
+		return workID, err
+	}
+
+	_, err := db.Exec(`INSERT INTO works(id, title, author) VALUES($1, $2, $3) ON CONFLICT(id) DO NOTHING`, work.ID, work.Title, work.Author)
+
+	return work.ID, err
+}
+
+// getEditions returns the books of a work with their images, the oldest editions first.
+func getEditions(db *sql.DB, workID int) ([]book.BookInfo, error) {
+	books, err := queryBooks(db, `SELECT `+bookColumns+` FROM books WHERE work_id=$1 ORDER BY published_year, id`, workID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withImages(db, books)
+}
+
+func updateEdition(db *sql.DB, bookID, workID int, edition book.Edition) error {
+	_, err := db.Exec(`
+		UPDATE books SET
+			work_id = $1,
+			publisher = $2,
+			published_year = $3,
+			language = $4,
+			format = $5
+		WHERE id = $6`, workIDColumn(workID), edition.Publisher, yearColumn(edition.Year), edition.Language, edition.Format, bookID)
+
+	return err
+}
+
+// workIDColumn is the work_id of a book, NULL when it belongs to no work.
+func workIDColumn(workID int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(workID), Valid: workID > 0}
+}
+
+// yearColumn is the published_year of a book, NULL when it is not known.
+func yearColumn(year int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(year), Valid: year > 0}
+}
+
+// getBooksByISBN finds the books of an edition, the ISBN may be an ISBN-10 or an ISBN-13 written
+// with hyphens. There may be more than one: the same book bought twice.
+func getBooksByISBN(db *sql.DB, isbnText string) ([]book.BookInfo, error) {
+	normalized, err := isbn.Normalize(isbnText)
+	if err != nil {
+		return nil, err
+	} else if normalized == "" {
+		return nil, fmt.Errorf("%w: it is empty", isbn.ErrInvalid)
+	}
+
+	books, err := queryBooks(db, `SELECT `+bookColumns+` FROM books WHERE isbn=$1 ORDER BY id`, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	return withImages(db, books)
+}
+
+// isbnColumn validates the ISBN of a book and returns the value its isbn column is stored with:
+// the ISBN-13, or NULL when the book has none.
+func isbnColumn(bookInfo book.BookInfo) (sql.NullString, error) {
+	normalized, err := isbn.Normalize(bookInfo.ISBN)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("book %s: %v", bookInfo, err)
+	}
+
+	return sql.NullString{String: normalized, Valid: normalized != ""}, nil
+}
+
+// sqlRunner runs statements on a *sql.DB or inside a *sql.Tx.
+type sqlRunner interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// createBook inserts a book and its optional image. When the book already carries an ID or an
+// AddedOn date they are preserved, which is what imports and migrations rely on.
+func createBook(db *sql.DB, imageStore imagestore.Store, bookInfo book.BookInfo) (int, error) {
+	if err := normalizeBook(&bookInfo); err != nil {
+		return 0, err
+	}
+
+	bookID, err := insertBook(db, bookInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(bookInfo.Contributors) > 0 {
+		if err := setContributors(db, bookID, bookInfo.Contributors); err != nil {
+			return bookID, err
+		}
+	}
+
+	if len(bookInfo.Tags) > 0 {
+		if err := setTags(db, bookID, bookInfo.Tags); err != nil {
+			return bookID, err
+		}
+	}
+
+	if !bookInfo.Location.IsZero() {
+		if err := setBookLocation(db, bookID, bookInfo.Location); err != nil {
+			return bookID, err
+		}
+	}
+
+	if len(bookInfo.Readings) > 0 {
+		if err := setReading(db, bookID, bookInfo.ReadingStatus, bookInfo.Pages, bookInfo.Readings); err != nil {
+			return bookID, err
+		}
+	}
+
+	if err := addImageToBook(bookID, bookInfo.Image, db, imageStore); err != nil {
+		return bookID, err
+	}
+
+	return bookID, nil
+}
+
+// normalizeBook checks the reading and the acquisition of a book before it is saved.
+func normalizeBook(bookInfo *book.BookInfo) error {
+	bookInfo.NormalizeContributors()
+
+	if err := bookInfo.ValidateReading(); err != nil {
+		return err
+	}
+	bookInfo.NormalizeReading()
+
+	if err := bookInfo.Acquisition.Validate(); err != nil {
+		return err
+	}
+	bookInfo.Acquisition.Normalize()
+
+	return nil
+}
+
+// insertBook inserts the row of a normalized book, without the contributors, tags, location,
+// readings or images that go in other tables.
+func insertBook(db sqlRunner, bookInfo book.BookInfo) (int, error) {
+	isbn, err := isbnColumn(bookInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	columns := []string{"title", "author", "description", "read", "goodreads_link", "isbn",
+		"work_id", "publisher", "published_year", "language", "format", "category", "reading_status", "pages",
+		"purchased_on", "purchase_price", "currency", "purchased_from", "book_condition", "estimated_value"}
+	args := []any{bookInfo.Title, bookInfo.Author, bookInfo.Description, bookInfo.HasBeenRead, bookInfo.GoodreadsLink, isbn,
+		workIDColumn(bookInfo.WorkID), bookInfo.Publisher, yearColumn(bookInfo.Year), bookInfo.Language, bookInfo.Format,
+		categoryColumn(bookInfo.Category), string(bookInfo.ReadingStatus), pagesColumn(bookInfo.Pages)}
+	args = append(args, acquisitionArgs(bookInfo.Acquisition)...)
+
+	if bookInfo.ID > 0 {
+		columns = append(columns, "id")
+		args = append(args, bookInfo.ID)
+	}
+
+	if bookInfo.AddedOn != "" {
+		addedOn, err := book.ParseAddedOn(bookInfo.AddedOn)
+		if err != nil {
+			return 0, fmt.Errorf("invalid addedOn %q: %v", bookInfo.AddedOn, err)
+		}
+		columns = append(columns, "added_on")
+		args = append(args, addedOn.Format(book.AddedOnLayout))
+	}
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	query := fmt.Sprintf("INSERT INTO books(%s) VALUES(%s) RETURNING id", strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	var bookID int
+	err = db.QueryRow(query, args...).Scan(&bookID)
+
+	return bookID, err
+}
+
+// addImageToBook puts the image in the store and links it to the book by its hash. Adding a photo
+// the book already has does nothing.
+func addImageToBook(bookID int, imageData []byte, db *sql.DB, imageStore imagestore.Store) error {
+	if len(imageData) == 0 {
+		return nil
+	}
+
+	hash, err := imageStore.Put(imageData)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM book_images WHERE book_id=$1 AND image_hash=$2)", bookID, hash).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	// New images go after the ones the book already has.
+	var position int
+	err = db.QueryRow("SELECT COALESCE(MAX(position) + 1, 0) FROM book_images WHERE book_id=$1", bookID).Scan(&position)
+	if err != nil {
+		return err
+	}
+
+	var imageID int
+	err = db.QueryRow("INSERT INTO book_images(book_id, image_hash, position) VALUES($1, $2, $3) RETURNING image_id", bookID, hash, position).Scan(&imageID)
+	if err != nil {
+		return err
+	}
+
+	return addImageVariants(db, imageID, imageData)
+}
+
+// ErrImagesMismatch is returned by ReorderImages when the IDs are not exactly the images of the book.
+var ErrImagesMismatch = errors.New("the images do not match the images of the book")
+
+// reorderImages sets the position of every image of the book to its index in imageIDs.
+func reorderImages(db *sql.DB, bookID int, imageIDs []int) error {
+	images, err := getImagesByBookID(bookID, db)
+	if err != nil {
+		return err
+	}
+
+	if !sameImages(images, imageIDs) {
+		return ErrImagesMismatch
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for position, imageID := range imageIDs {
+		if _, err := tx.Exec("UPDATE book_images SET position=$1 WHERE image_id=$2 AND book_id=$3", position, imageID, bookID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func sameImages(images []book.BookImageInfo, imageIDs []int) bool {
+	if len(images) != len(imageIDs) {
+		return false
+	}
+
+	pending := make(map[int]bool, len(images))
+	for _, image := range images {
+		pending[image.ImageID] = true
+	}
+
+	for _, imageID := range imageIDs {
+		if !pending[imageID] {
+			return false
+		}
+		delete(pending, imageID)
+	}
+
+	return true
+}
+
+// setCoverImage flags the image as the cover of its book, unflagging the previous one.
+func setCoverImage(db *sql.DB, bookID, imageID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Two statements: the unique index on the cover of a book is checked row by row.
+	if _, err := tx.Exec("UPDATE book_images SET is_cover=FALSE WHERE book_id=$1 AND is_cover", bookID); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE book_images SET is_cover=TRUE WHERE book_id=$1 AND image_id=$2", bookID, imageID)
+	if err != nil {
+		return err
+	}
+
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		return fmt.Errorf("image %d of book %d: %w", imageID, bookID, ErrImagesMismatch)
+	}
+
+	return tx.Commit()
+}
+
+// ErrImageVariantNotFound is returned by GetImageVariant when the variant has not been generated.
+var ErrImageVariantNotFound = errors.New("image variant not found")
+
+// generateImageVariants resizes an image to every variant. An image that cannot be decoded gets no
+// variants, it is served as it is instead.
+func generateImageVariants(imageID int, imageData []byte) map[imaging.Variant][]byte {
+	variants, err := imaging.GenerateVariants(imageData)
+	if err != nil {
+		log.Printf("image %d: cannot generate its variants: %v", imageID, err)
+		return nil
+	}
+
+	return variants
+}
+
+func addImageVariants(db *sql.DB, imageID int, imageData []byte) error {
+	for variant, data := range generateImageVariants(imageID, imageData) {
+		if err := saveImageVariant(db, imageID, variant, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func saveImageVariant(db *sql.DB, imageID int, variant imaging.Variant, data []byte) error {
+	_, err := db.Exec(`
+		INSERT INTO book_image_variants(image_id, variant, image) VALUES($1, $2, $3)
+		ON CONFLICT(image_id, variant) DO UPDATE SET image = excluded.image`, imageID, string(variant), data)
+
+	return err
+}
+
+func getImageVariant(db *sql.DB, imageID int, variant imaging.Variant) (book.BookImage, error) {
+	image := book.BookImage{ImageID: imageID}
+	err := db.QueryRow(`
+		SELECT i.book_id, v.image FROM book_image_variants v JOIN book_images i ON i.image_id = v.image_id
+		WHERE v.image_id=$1 AND v.variant=$2`, imageID, string(variant)).Scan(&image.BookID, &image.Data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return image, ErrImageVariantNotFound
+	}
+
+	return image, err
+}
+
+// removeImage unlinks the image from its book, its bytes are removed from the store as well when
+// no other book_images row references them.
+func removeImage(db *sql.DB, imageStore imagestore.Store, imageID int) error {
+	var hash string
+	err := db.QueryRow("SELECT image_hash FROM book_images WHERE image_id=$1", imageID).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DELETE FROM book_image_variants WHERE image_id=$1", imageID); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DELETE FROM book_images WHERE image_id=$1", imageID); err != nil {
+		return err
+	}
+
+	var references int
+	if err := db.QueryRow("SELECT count(*) FROM book_images WHERE image_hash=$1", hash).Scan(&references); err != nil {
+		return err
+	}
+
+	if references > 0 {
+		return nil
+	}
+
+	return imageStore.Delete(hash)
+}
+
+// collectImageGarbage removes from the store every image no book_images row references, the ones
+// left behind by books deleted straight from the database or by interrupted uploads.
+func collectImageGarbage(db *sql.DB, imageStore imagestore.Store) (int, error) {
+	rows, err := db.Query("SELECT DISTINCT image_hash FROM book_images")
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	referenced := map[string]bool{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return 0, err
+		}
+		referenced[hash] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return imagestore.CollectGarbage(imageStore, referenced)
+}
+
+// GetOrCreateImageVariant returns a variant of an image. Images stored before variants existed,
+// and the images of the memory mode, get theirs generated and saved the first time they are asked.
+func GetOrCreateImageVariant(bookDAO DAO, imageID int, variant imaging.Variant) (book.BookImage, error) {
+	image, err := bookDAO.GetImageVariant(imageID, variant)
+	if !errors.Is(err, ErrImageVariantNotFound) {
+		return image, err
+	}
+
+	original, err := bookDAO.GetImage(imageID)
+	if err != nil {
+		return book.BookImage{}, err
+	}
+
+	data, err := imaging.Resize(original.Data, variant)
+	if err != nil {
+		return book.BookImage{}, err
+	}
+
+	if err := bookDAO.SaveImageVariant(imageID, variant, data); err != nil {
+		return book.BookImage{}, err
+	}
+
+	return book.BookImage{ImageID: imageID, BookID: original.BookID, Data: data}, nil
+}
+
+func getBookCount(db *sql.DB) (int, error) {
+	rows, err := db.Query(`SELECT count(*) FROM books`)
+	if err != nil {
+		return -1, err
+	}
+
+	var count int
+
+	for rows.Next() {
+		err := rows.Scan(&count)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	return count, nil
+}
+
+func getBooksWithPagination(offset, limit int, db *sql.DB) ([]book.BookInfo, error) {
+	query := `SELECT id, title, author, description, read, added_on FROM books ORDER BY title LIMIT $1 OFFSET $2;`
+
+	rows, err := db.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	books := []book.BookInfo{}
+	for rows.Next() {
+		book := book.BookInfo{}
+		err = rows.Scan(&book.ID, &book.Title, &book.Author, &book.Description, &book.HasBeenRead, &book.AddedOn)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+
+	if err := addLoans(db, books); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+func addUser(db *sql.DB, userID, email, name, oauthIdentifier string) error {
+	_, err := db.Exec(`
+			INSERT INTO users(user_id, email, name, oauth_identifier) 
+			VALUES($1, $2, $3, $4)
+			ON CONFLICT(user_id) DO UPDATE
+			SET email = $2, name = $3`, userID, email, name, oauthIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func getAllUsers(db *sql.DB) ([]user.User, error) {
+	rows, err := db.Query(`SELECT user_id, email, name, oauth_identifier FROM users ORDER BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []user.User{}
+	for rows.Next() {
+		var userInfo user.User
+		var name sql.NullString
+		if err := rows.Scan(&userInfo.UserID, &userInfo.Email, &name, &userInfo.OAuthIdentifier); err != nil {
+			return nil, err
+		}
+
+		userInfo.Name = name.String
+		users = append(users, userInfo)
+	}
+
+	return users, rows.Err()
+}
+
+func getAllLikes(db *sql.DB) ([]book.BookLike, error) {
+	rows, err := db.Query(`SELECT like_id, book_id, user_id, created_at FROM book_likes ORDER BY like_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	likes := []book.BookLike{}
+	for rows.Next() {
+		var like book.BookLike
+		if err := rows.Scan(&like.ID, &like.BookID, &like.UserID, &like.CreatedAt); err != nil {
+			return nil, err
+		}
+		likes = append(likes, like)
+	}
+
+	return likes, rows.Err()
+}
+
+// addLike stores a like keeping its creation date, a like that already exists is left untouched.
+func addLike(db *sql.DB, like book.BookLike) error {
+	_, err := db.Exec(`
+		INSERT INTO book_likes(book_id, user_id, created_at) VALUES($1, $2, $3)
+		ON CONFLICT(book_id, user_id) DO NOTHING`, like.BookID, like.UserID, like.CreatedAt.UTC())
+
+	return err
+}
+
+// forEachImage streams the raw images ordered by image_id, without holding all of them in memory.
+func forEachImage(db *sql.DB, imageStore imagestore.Store, fn func(image book.BookImage) error) error {
+	rows, err := db.Query(`SELECT image_id, book_id, position, is_cover, image_hash FROM book_images ORDER BY image_id`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var image book.BookImage
+		var hash string
+		if err := rows.Scan(&image.ImageID, &image.BookID, &image.Position, &image.IsCover, &hash); err != nil {
+			return err
+		}
+
+		if image.Data, err = imageStore.Get(hash); err != nil {
+			return fmt.Errorf("image %d: %v", image.ImageID, err)
+		}
+
+		if err := fn(image); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// restoreImage stores an image under its original image_id, an image_id that already exists is
+// left untouched.
+func restoreImage(db *sql.DB, imageStore imagestore.Store, image book.BookImage) error {
+	hash, err := imageStore.Put(image.Data)
+	if err != nil {
+		return err
+	}
+
+	var imageID int
+	err = db.QueryRow(`
+		INSERT INTO book_images(image_id, book_id, image_hash, position, is_cover) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT(image_id) DO NOTHING RETURNING image_id`, image.ImageID, image.BookID, hash, image.Position, image.IsCover).Scan(&imageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already restored by an earlier run.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return addImageVariants(db, imageID, image.Data)
+}
+
+func getImagesByBookID(bookID int, db *sql.DB) ([]book.BookImageInfo, error) {
+	bookImagesRows, err := db.Query(`SELECT i.image_id, i.book_id, i.position, i.is_cover FROM book_images i WHERE i.book_id=$1 ORDER BY i.position, i.image_id`, bookID)
+	if err != nil {
+		return []book.BookImageInfo{}, err
+	}
+
+	defer func() {
+		_ = bookImagesRows.Close()
+	}()
+
+	var images []book.BookImageInfo
+
+	for bookImagesRows.Next() {
+		var imageID int
+		var bookID int
+		var position int
+		var isCover bool
+		if err = bookImagesRows.Scan(&imageID, &bookID, &position, &isCover); err != nil {
+			return []book.BookImageInfo{}, err
+		}
+
+		image := book.NewBookImageInfo(imageID, bookID)
+		image.Position = position
+		image.IsCover = isCover
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// getBookIDsByImageHash returns the books that have the image, usually none or one.
+func getBookIDsByImageHash(db *sql.DB, hash string) ([]int, error) {
+	rows, err := db.Query(`SELECT DISTINCT book_id FROM book_images WHERE image_hash=$1 ORDER BY book_id`, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var bookIDs []int
+	for rows.Next() {
+		var bookID int
+		if err := rows.Scan(&bookID); err != nil {
+			return nil, err
+		}
+		bookIDs = append(bookIDs, bookID)
+	}
+
+	return bookIDs, rows.Err()
+}
+
+func getImage(db *sql.DB, imageStore imagestore.Store, imageID int) (book.BookImage, error) {
+	image := book.BookImage{ImageID: imageID}
+	var hash string
+	err := db.QueryRow(`SELECT book_id, image_hash FROM book_images WHERE image_id=$1`, imageID).Scan(&image.BookID, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return image, fmt.Errorf("image %d does not exist", imageID)
+	} else if err != nil {
+		return image, err
+	}
+
+	image.Data, err = imageStore.Get(hash)
+
+	return image, err
+}
+
+// updateBook saves the main fields of a book. Marking it as read or unread, as the CSV and Goodreads
+// imports do, changes its reading status to match.
+func updateBook(title string, author string, description string, read bool, goodreadsLink string, isbnText string, id int, db *sql.DB) error {
+	isbn, err := isbnColumn(book.BookInfo{ID: id, Title: title, Author: author, ISBN: isbnText})
+	if err != nil {
+		return err
+	}
+
+	bookUpdate, err := db.Prepare(`
+		UPDATE books SET 
+			title = $1,
+			author = $2,
+			description = $3,
+			read = $4,
+			reading_status = CASE
+				WHEN $4 AND reading_status NOT IN ('read', 're-reading') THEN 'read'
+				WHEN NOT $4 AND reading_status IN ('read', 're-reading') THEN ''
+				ELSE reading_status
+			END,
+			goodreads_link = $5,
+			isbn = $6
+		WHERE id = $7
+	`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = bookUpdate.Close()
+	}()
+
+	_, err = bookUpdate.Exec(title, author, description, read, goodreadsLink, isbn, id)
+	if err != nil {
+		return err
+	}
+
+	// A new author replaces the authors among the contributors, the translators and the rest stay.
+	books, err := queryBooks(db, `SELECT `+bookColumns+` FROM books WHERE id=$1`, id)
+	if err != nil || len(books) == 0 {
+		return err
+	}
+
+	bookInfo := books[0]
+	if strings.Join(bookInfo.ContributorsAs(book.RoleAuthor), ", ") == author {
+		return nil
+	}
+	bookInfo.SetAuthor(author)
+
+	return setContributors(db, id, bookInfo.Contributors)
+}
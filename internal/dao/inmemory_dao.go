@@ -1,39 +1,54 @@
 package dao
 
 import (
-	"encoding/base64"
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"leonlib/internal/imagestore"
+	"leonlib/internal/imaging"
+	"leonlib/internal/isbn"
 	book "leonlib/internal/types"
 	user "leonlib/internal/types"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
-func createInMemoryImagesDatabase(booksDB *map[int]book.BookInfo) (map[int][]book.BookImageInfo, error) {
-	//         map[imgID::int][]List of Books
+// createInMemoryImagesDatabase indexes the images of the books, the files are only read when an
+// image is requested.
+func createInMemoryImagesDatabase(booksDB *map[int]book.BookInfo) (map[int][]book.BookImageInfo, map[int]string, error) {
+	//         map[bookID::int][]List of images
 	db := make(map[int][]book.BookImageInfo)
+	//          map[imageID::int]file path
+	files := make(map[int]string)
 
-	imageID := 0
-	for _, b := range *booksDB {
+	bookIDs := make([]int, 0, len(*booksDB))
+	for bookID := range *booksDB {
+		bookIDs = append(bookIDs, bookID)
+	}
+	// Numbering the images in book order keeps their IDs stable between runs, like a database would.
+	sort.Ints(bookIDs)
+
+	imageID := 1
+	for _, bookID := range bookIDs {
+		b := (*booksDB)[bookID]
 		var images []book.BookImageInfo
 		for _, imageName := range b.ImageNames {
-			imgBytes, err := os.ReadFile(filepath.Join("images", imageName))
+			imagePath := filepath.Join("images", imageName)
+			fileInfo, err := os.Stat(imagePath)
 			if err != nil {
-				return map[int][]book.BookImageInfo{}, err
+				return map[int][]book.BookImageInfo{}, map[int]string{}, fmt.Errorf("book %d: image %s: %w", b.ID, imageName, err)
 			}
 
-			if len(imgBytes) > 0 {
-				encodedImage := base64.StdEncoding.EncodeToString(imgBytes)
-				bookImageInfo := book.BookImageInfo{
-					ImageID: imageID,
-					BookID:  b.ID,
-					Image:   encodedImage,
-				}
-				images = append(images, bookImageInfo)
+			if fileInfo.Size() > 0 {
+				image := book.NewBookImageInfo(imageID, b.ID)
+				image.Position = len(images)
+				images = append(images, image)
+				files[imageID] = imagePath
 			}
 
 			db[b.ID] = images
@@ -42,43 +57,120 @@ func createInMemoryImagesDatabase(booksDB *map[int]book.BookInfo) (map[int][]boo
 		}
 	}
 
-	return db, nil
+	return db, files, nil
 }
 
-func createInMemoryDatabaseFromFile() (map[int]book.BookInfo, error) {
+func createInMemoryDatabaseFromFile() (map[int]book.BookInfo, map[int]book.Work, map[int]book.Author, map[int]book.Shelf, error) {
 	libraryDir := "library"
 	libraryDirPath := filepath.Join(libraryDir, "books_db.toml")
 
 	var library book.Library
 
 	if _, err := toml.DecodeFile(libraryDirPath, &library); err != nil {
-		return map[int]book.BookInfo{}, err
+		return map[int]book.BookInfo{}, map[int]book.Work{}, map[int]book.Author{}, map[int]book.Shelf{}, err
+	}
+
+	authors := make(map[int]book.Author)
+	for _, author := range library.Author {
+		if author.SortName == "" {
+			author.SortName = book.SortName(author.Name)
+		}
+		authors[author.ID] = author
 	}
 
 	db := make(map[int]book.BookInfo)
 
-	for _, book := range library.Book {
-		db[book.ID] = book
+	for _, bookInfo := range library.Book {
+		normalized, err := isbn.Normalize(bookInfo.ISBN)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("book %s: %v", bookInfo, err)
+		}
+		bookInfo.ISBN = normalized
+		bookInfo.NormalizeContributors()
+		resolveAuthors(&authors, bookInfo.Contributors)
+		bookInfo.Tags = book.NormalizeTags(bookInfo.Tags)
+		if err := bookInfo.ValidateReading(); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		bookInfo.NormalizeReading()
+		if err := bookInfo.Acquisition.Validate(); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("book %s: %v", bookInfo, err)
+		}
+		bookInfo.Acquisition.Normalize()
+		db[bookInfo.ID] = bookInfo
+	}
+
+	works := make(map[int]book.Work)
+	for _, work := range library.Work {
+		works[work.ID] = work
 	}
 
-	return db, nil
+	shelves := make(map[int]book.Shelf)
+	for _, shelf := range library.Shelf {
+		shelves[shelf.ID] = shelf
+	}
+
+	return db, works, authors, shelves, nil
+}
+
+// resolveAuthors gives every contributor the author of their AuthorID, or else the one with their
+// name or an alias of it. A name nobody has yet becomes a new author.
+func resolveAuthors(authors *map[int]book.Author, contributors []book.Contributor) {
+	for i := range contributors {
+		if _, ok := (*authors)[contributors[i].AuthorID]; ok {
+			continue
+		}
+
+		contributors[i].AuthorID = authorIDByName(authors, contributors[i].Name)
+	}
+}
+
+func authorIDByName(authors *map[int]book.Author, name string) int {
+	authorIDs := make([]int, 0, len(*authors))
+	for authorID := range *authors {
+		authorIDs = append(authorIDs, authorID)
+	}
+	sort.Ints(authorIDs)
+
+	// The name of an author goes before the alias of another one.
+	for _, authorID := range authorIDs {
+		if strings.EqualFold((*authors)[authorID].Name, name) {
+			return authorID
+		}
+	}
+	for _, authorID := range authorIDs {
+		if (*authors)[authorID].HasName(name) {
+			return authorID
+		}
+	}
+
+	newID := 1
+	if len(authorIDs) > 0 {
+		newID = authorIDs[len(authorIDs)-1] + 1
+	}
+	(*authors)[newID] = book.Author{ID: newID, Name: name, SortName: book.SortName(name)}
+
+	return newID
 }
 
-func readWishListBooks() ([]book.WishListBook, error) {
+func readWishListBooks() (map[int]book.WishListBook, error) {
 	libraryDir := "library"
 	libraryDirPath := filepath.Join(libraryDir, "wish_list.toml")
 
 	var library book.WishList
 
 	if _, err := toml.DecodeFile(libraryDirPath, &library); err != nil {
-		fmt.Println("Hmmmm")
-		return []book.WishListBook{}, err
+		return map[int]book.WishListBook{}, err
 	}
 
-	db := make([]book.WishListBook, 0)
+	db := make(map[int]book.WishListBook)
 
-	for _, book := range library.Book {
-		db = append(db, book)
+	for _, wishListBook := range library.Book {
+		if err := wishListBook.Validate(); err != nil {
+			return map[int]book.WishListBook{}, err
+		}
+		wishListBook.Normalize()
+		db[wishListBook.ID] = wishListBook
 	}
 
 	return db, nil
@@ -108,15 +200,29 @@ func searchByTitle(titleSearchText string, db *map[int]book.BookInfo) (*[]book.B
 	return &results, nil
 }
 
-func searchByAuthor(authorSearchText string, db *map[int]book.BookInfo) (*[]book.BookInfo, error) {
+func searchByAuthor(authorSearchText string, db *map[int]book.BookInfo, authors *map[int]book.Author) (*[]book.BookInfo, error) {
 	if len(authorSearchText) == 0 {
 		return &[]book.BookInfo{}, fmt.Errorf("author search text empty")
 	}
 	var results []book.BookInfo
+	searchText := strings.ToLower(authorSearchText)
 	for _, bookInfo := range *db {
 		author := strings.ToLower(bookInfo.Author)
-		if has := strings.Contains(author, strings.ToLower(authorSearchText)); has {
+		if has := strings.Contains(author, searchText); has {
 			results = append(results, bookInfo)
+			continue
+		}
+
+		// Translators, editors... are found as well, and the books of an author by any of their names.
+		for _, contributor := range bookInfo.Contributors {
+			names := append([]string{contributor.Name}, (*authors)[contributor.AuthorID].Name)
+			names = append(names, (*authors)[contributor.AuthorID].Aliases...)
+			if slices.ContainsFunc(names, func(name string) bool {
+				return name != "" && strings.Contains(strings.ToLower(name), searchText)
+			}) {
+				results = append(results, bookInfo)
+				break
+			}
 		}
 	}
 
@@ -128,253 +234,2024 @@ func (dao *memoryBookDAO) AddAll(books []book.BookInfo) error {
 }
 
 func (dao *memoryBookDAO) AddImageToBook(bookID int, imageData []byte) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return dao.addImageToBookLocked(bookID, imageData)
+}
+
+// addImageToBookLocked is AddImageToBook for the callers that hold the lock.
+func (dao *memoryBookDAO) addImageToBookLocked(bookID int, imageData []byte) error {
 	if len(imageData) == 0 {
 		return nil
 	}
 
-	// TODO: pending...
+	if _, ok := (*dao.books)[bookID]; !ok {
+		return fmt.Errorf("id %d does not exist", bookID)
+	}
+
+	hash, err := dao.imageStore.Put(imageData)
+	if err != nil {
+		return err
+	}
+
+	for _, image := range (*dao.images)[bookID] {
+		if (*dao.imageHashes)[image.ImageID] == hash {
+			return nil
+		}
+	}
+
+	bookImageInfo := book.NewBookImageInfo(dao.nextImageID(), bookID)
+	if images := (*dao.images)[bookID]; len(images) > 0 {
+		bookImageInfo.Position = images[len(images)-1].Position + 1
+	}
+	(*dao.images)[bookID] = append((*dao.images)[bookID], bookImageInfo)
+	(*dao.imageHashes)[bookImageInfo.ImageID] = hash
+	if variants := generateImageVariants(bookImageInfo.ImageID, imageData); variants != nil {
+		(*dao.imageVariants)[bookImageInfo.ImageID] = variants
+	}
+
+	return nil
+}
+
+func (dao *memoryBookDAO) nextImageID() int {
+	maxID := 0
+	for _, images := range *dao.images {
+		for _, image := range images {
+			if image.ImageID > maxID {
+				maxID = image.ImageID
+			}
+		}
+	}
+
+	return maxID + 1
+}
+
+func (dao *memoryBookDAO) AddLike(like book.BookLike) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookID := strconv.Itoa(like.BookID)
+	likesPerUser := (*dao.bookLikes)[like.UserID]
+	if exists(&likesPerUser, bookID) {
+		return nil
+	}
+
+	(*dao.bookLikes)[like.UserID] = append(likesPerUser, bookID)
+	(*dao.likedOn)[likeKey(bookID, like.UserID)] = like.CreatedAt
 
 	return nil
 }
 
 func (dao *memoryBookDAO) AddUser(userID, email, name, oauthIdentifier string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	userInfo, ok := (*dao.users)[userID]
+	if !ok {
+		userInfo = user.User{UserID: userID, OAuthIdentifier: oauthIdentifier}
+	}
+	userInfo.Email = email
+	userInfo.Name = name
+	(*dao.users)[userID] = userInfo
+
+	return nil
+}
+
+func (dao *memoryBookDAO) CancelWishListReservation(wishListBookID int, userID string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if reservation, ok := (*dao.reservations)[wishListBookID]; !ok || reservation.UserID != userID {
+		return fmt.Errorf("the reservation of wish list book %d does not exist", wishListBookID)
+	}
+
+	delete(*dao.reservations, wishListBookID)
+
 	return nil
 }
 
 func (dao *memoryBookDAO) Close() error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	clear(*dao.authors)
 	clear(*dao.bookLikes)
 	clear(*dao.books)
 	clear(*dao.images)
+	clear(*dao.imageHashes)
+	clear(*dao.imageFiles)
+	clear(*dao.imageVariants)
+	clear(*dao.likedOn)
+	clear(*dao.loans)
+	clear(*dao.quotes)
+	clear(*dao.reviews)
+	clear(*dao.shelves)
+	clear(*dao.users)
+	clear(*dao.works)
+	clear(*dao.wishListBooks)
+	clear(*dao.reservations)
 
 	return nil
 }
 
-func (dao *memoryBookDAO) CreateBook(book book.BookInfo) error {
-	// TODO: pending...
+// CollectImageGarbage removes the images of the store no book references. The files of the library
+// in images/ are not part of the store and are never removed.
+func (dao *memoryBookDAO) CollectImageGarbage() (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-	return nil
+	referenced := make(map[string]bool, len(*dao.imageHashes))
+	for _, hash := range *dao.imageHashes {
+		referenced[hash] = true
+	}
+
+	return imagestore.CollectGarbage(dao.imageStore, referenced)
 }
 
-func (dao *memoryBookDAO) GetAllAuthors() ([]string, error) {
-	authors := map[string]struct{}{}
+func (dao *memoryBookDAO) CreateBook(bookInfo book.BookInfo) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return dao.createBookLocked(bookInfo)
+}
 
-	for _, v := range *dao.books {
-		authors[v.Author] = struct{}{}
+// createBookLocked is CreateBook for the callers that hold the lock.
+func (dao *memoryBookDAO) createBookLocked(bookInfo book.BookInfo) (int, error) {
+	if bookInfo.ID <= 0 {
+		bookInfo.ID = 1
+		for id := range *dao.books {
+			if id >= bookInfo.ID {
+				bookInfo.ID = id + 1
+			}
+		}
+	} else if _, exists := (*dao.books)[bookInfo.ID]; exists {
+		return 0, fmt.Errorf("id %d already exists", bookInfo.ID)
 	}
 
-	authorsUnique := make([]string, 0, len(authors))
+	if bookInfo.AddedOn == "" {
+		bookInfo.AddedOn = time.Now().Format("2006-01-02")
+	}
 
-	for k, _ := range authors {
-		authorsUnique = append(authorsUnique, k)
+	normalized, err := isbn.Normalize(bookInfo.ISBN)
+	if err != nil {
+		return 0, fmt.Errorf("book %s: %v", bookInfo, err)
 	}
+	bookInfo.ISBN = normalized
+	bookInfo.NormalizeContributors()
+	resolveAuthors(dao.authors, bookInfo.Contributors)
+	bookInfo.Tags = book.NormalizeTags(bookInfo.Tags)
+	if err := bookInfo.ValidateReading(); err != nil {
+		return 0, err
+	}
+	bookInfo.NormalizeReading()
+	if err := bookInfo.Acquisition.Validate(); err != nil {
+		return 0, err
+	}
+	bookInfo.Acquisition.Normalize()
+	// The book is lent by creating its loan.
+	bookInfo.CurrentLoan = book.Loan{}
 
-	sort.Strings(authorsUnique)
+	image := bookInfo.Image
+	bookInfo.Image = nil
+	bookInfo.Images = nil
+	(*dao.books)[bookInfo.ID] = bookInfo
 
-	return authorsUnique, nil
-}
+	if err := dao.addImageToBookLocked(bookInfo.ID, image); err != nil {
+		return bookInfo.ID, err
+	}
 
-func (dao *memoryBookDAO) GetBookByID(id int) (book.BookInfo, error) {
-	bookInfo, ok := (*dao.books)[id]
-	if !ok {
-		return book.BookInfo{}, fmt.Errorf("id %d does not exist", id)
+	if !bookInfo.Location.IsZero() {
+		if err := dao.setBookLocationLocked(bookInfo.ID, bookInfo.Location); err != nil {
+			return bookInfo.ID, err
+		}
 	}
 
-	bookImages, err := dao.GetImagesByBookID(id)
-	if err != nil {
-		return book.BookInfo{}, err
+	return bookInfo.ID, nil
+}
+
+func (dao *memoryBookDAO) CreateAuthor(author book.Author) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if author.ID <= 0 {
+		author.ID = 1
+		for id := range *dao.authors {
+			if id >= author.ID {
+				author.ID = id + 1
+			}
+		}
+	} else if _, exists := (*dao.authors)[author.ID]; exists {
+		return author.ID, nil
 	}
 
-	bookInfo.Base64Images = bookImages
+	if author.SortName == "" {
+		author.SortName = book.SortName(author.Name)
+	}
+	author.Books = 0
+	(*dao.authors)[author.ID] = author
 
-	return bookInfo, nil
+	return author.ID, nil
 }
 
-func (dao *memoryBookDAO) GetBookCount() (int, error) {
-	return len(*dao.books), nil
-}
+// CreateLoan lends a book. A loan with an ID keeps it, and when that ID already exists nothing
+// changes.
+func (dao *memoryBookDAO) CreateLoan(loan book.Loan) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-func (dao *memoryBookDAO) GetBooksWithPagination(offset, limit int) ([]book.BookInfo, error) {
-	if offset > len(*dao.books) {
-		offset = len(*dao.books)
+	if err := dao.checkLoan(loan); err != nil {
+		return 0, err
 	}
 
-	end := offset + limit
-	if end > len(*dao.books) {
-		end = len(*dao.books)
+	if loan.ID <= 0 {
+		loan.ID = 1
+		for id := range *dao.loans {
+			if id >= loan.ID {
+				loan.ID = id + 1
+			}
+		}
+	} else if _, exists := (*dao.loans)[loan.ID]; exists {
+		return loan.ID, nil
 	}
 
-	var books []book.BookInfo
+	dao.saveLoan(loan)
 
-	for _, bookInfo := range *dao.books {
-		books = append(books, bookInfo)
+	return loan.ID, nil
+}
+
+// checkLoan rejects a loan that is not valid, one of a book that does not exist, and one that is not
+// returned while the book is lent to someone else.
+func (dao *memoryBookDAO) checkLoan(loan book.Loan) error {
+	if err := loan.Validate(); err != nil {
+		return err
 	}
 
-	sort.Slice(books, func(i, j int) bool {
-		return books[i].Title < books[j].Title
-	})
+	bookInfo, ok := (*dao.books)[loan.BookID]
+	if !ok {
+		return fmt.Errorf("book %d does not exist", loan.BookID)
+	}
 
-	return books[offset:end], nil
+	if !loan.Returned() && bookInfo.OnLoan() && bookInfo.CurrentLoan.ID != loan.ID {
+		return fmt.Errorf("book %d is lent to %s", loan.BookID, bookInfo.CurrentLoan.Borrower)
+	}
+
+	return nil
 }
 
-func (dao *memoryBookDAO) GetBooksBySearchTypeCoincidence(titleSearchText string, bookSearchType book.BookSearchType) ([]book.BookInfo, error) {
-	var err error
+// saveLoan stores a loan with its dates as days, and keeps the current loan of its book.
+func (dao *memoryBookDAO) saveLoan(loan book.Loan) {
+	loan.LoanedOn = book.Day(loan.LoanedOn)
+	if !loan.DueOn.IsZero() {
+		loan.DueOn = book.Day(loan.DueOn)
+	}
+	if loan.Returned() {
+		loan.ReturnedOn = book.Day(loan.ReturnedOn)
+	}
+	(*dao.loans)[loan.ID] = loan
 
-	var found *[]book.BookInfo
+	bookInfo := (*dao.books)[loan.BookID]
+	if !loan.Returned() {
+		bookInfo.CurrentLoan = loan
+	} else if bookInfo.CurrentLoan.ID == loan.ID {
+		bookInfo.CurrentLoan = book.Loan{}
+	}
+	(*dao.books)[loan.BookID] = bookInfo
+}
 
-	switch bookSearchType {
-	case book.ByAuthor:
-		found, err = searchByAuthor(titleSearchText, dao.books)
-		if err != nil {
-			return []book.BookInfo{}, err
-		}
-	case book.ByTitle:
-		found, err = searchByTitle(titleSearchText, dao.books)
-		if err != nil {
-			return []book.BookInfo{}, err
-		}
+func (dao *memoryBookDAO) CreateQuote(quote book.Quote) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.checkQuote(quote); err != nil {
+		return 0, err
 	}
 
-	for i := range *found {
-		bookInfo := &(*found)[i]
-		bookImages, err := dao.GetImagesByBookID(bookInfo.ID)
-		if err != nil {
-			return []book.BookInfo{}, err
+	if quote.ID <= 0 {
+		quote.ID = 1
+		for id := range *dao.quotes {
+			if id >= quote.ID {
+				quote.ID = id + 1
+			}
 		}
+	} else if _, exists := (*dao.quotes)[quote.ID]; exists {
+		return quote.ID, nil
+	}
 
-		bookInfo.Base64Images = bookImages
+	if quote.AddedOn.IsZero() {
+		quote.AddedOn = time.Now()
 	}
+	dao.saveQuote(quote)
 
-	return *found, nil
+	return quote.ID, nil
 }
 
-func (dao *memoryBookDAO) GetImagesByBookID(bookID int) ([]book.BookImageInfo, error) {
-	images, ok := (*dao.images)[bookID]
-	if !ok {
-		return []book.BookImageInfo{}, nil
+// checkQuote rejects a quote that is not valid and one of a book that does not exist.
+func (dao *memoryBookDAO) checkQuote(quote book.Quote) error {
+	if err := quote.Validate(); err != nil {
+		return err
 	}
 
-	return images, nil
+	if _, ok := (*dao.books)[quote.BookID]; !ok {
+		return fmt.Errorf("book %d does not exist", quote.BookID)
+	}
+
+	return nil
 }
 
-func (dao *memoryBookDAO) GetUserInfoByID(userID string) (user.UserInfo, error) {
-	return user.UserInfo{}, nil
+// saveQuote stores a quote the way the databases keep it.
+func (dao *memoryBookDAO) saveQuote(quote book.Quote) {
+	quote.Text = strings.TrimSpace(quote.Text)
+	quote.Tags = book.NormalizeTags(quote.Tags)
+	quote.AddedOn = book.Day(quote.AddedOn)
+	(*dao.quotes)[quote.ID] = quote
 }
 
-func (dao *memoryBookDAO) LikedBy(bookID, userID string) (bool, error) {
-	likesPerUser := (*dao.bookLikes)[userID]
+func (dao *memoryBookDAO) CreateShelf(shelf book.Shelf) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-	return exists(&likesPerUser, bookID), nil
+	if shelf.ID <= 0 {
+		shelf.ID = 1
+		for id := range *dao.shelves {
+			if id >= shelf.ID {
+				shelf.ID = id + 1
+			}
+		}
+	} else if _, exists := (*dao.shelves)[shelf.ID]; exists {
+		return shelf.ID, nil
+	}
+
+	shelf.Books = 0
+	(*dao.shelves)[shelf.ID] = shelf
+
+	return shelf.ID, nil
 }
 
-func exists(IDs *[]string, target string) bool {
-	for _, id := range *IDs {
-		if target == id {
-			return true
+func (dao *memoryBookDAO) CreateWishListBook(wishListBook book.WishListBook) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := wishListBook.Validate(); err != nil {
+		return 0, err
+	}
+	wishListBook.Normalize()
+
+	if wishListBook.ID <= 0 {
+		wishListBook.ID = 1
+		for id := range *dao.wishListBooks {
+			if id >= wishListBook.ID {
+				wishListBook.ID = id + 1
+			}
 		}
+	} else if _, exists := (*dao.wishListBooks)[wishListBook.ID]; exists {
+		return wishListBook.ID, nil
 	}
 
-	return false
+	(*dao.wishListBooks)[wishListBook.ID] = wishListBook
+
+	return wishListBook.ID, nil
 }
 
-func hasBeenLiked(IDs *[]string, target string) bool {
-	for _, id := range *IDs {
-		if target == id {
-			return true
+func (dao *memoryBookDAO) CreateWork(work book.Work) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if work.ID <= 0 {
+		work.ID = 1
+		for id := range *dao.works {
+			if id >= work.ID {
+				work.ID = id + 1
+			}
 		}
+	} else if _, exists := (*dao.works)[work.ID]; exists {
+		return work.ID, nil
 	}
 
-	return false
+	(*dao.works)[work.ID] = work
+
+	return work.ID, nil
 }
 
-func (dao *memoryBookDAO) LikeBook(bookID, userID string) error {
-	bookLikes, exists := (*dao.bookLikes)[userID]
-	if !exists {
-		(*dao.bookLikes)[userID] = make([]string, 0)
-		(*dao.bookLikes)[userID] = append((*dao.bookLikes)[userID], bookID)
+// DeleteShelf removes a shelf, its books are left without a location.
+func (dao *memoryBookDAO) DeleteShelf(id int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-		return nil
+	if _, ok := (*dao.shelves)[id]; !ok {
+		return fmt.Errorf("shelf %d does not exist", id)
 	}
 
-	if hasLike := hasBeenLiked(&bookLikes, bookID); !hasLike {
-		(*dao.bookLikes)[userID] = append((*dao.bookLikes)[userID], bookID)
+	for _, bookInfo := range dao.booksOnShelf(id) {
+		bookInfo.Location = book.Location{}
+		(*dao.books)[bookInfo.ID] = bookInfo
 	}
+	delete(*dao.shelves, id)
 
 	return nil
 }
 
-func (dao *memoryBookDAO) LikesCount(bookID int) (int, error) {
-	count := 0
-	id := strconv.Itoa(bookID)
-	for _, bookLikesPerUser := range *dao.bookLikes {
-		if exists(&bookLikesPerUser, id) {
-			count++
-		}
-	}
+func (dao *memoryBookDAO) DeleteWishListBook(id int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-	return count, nil
+	return dao.deleteWishListBookLocked(id)
 }
 
-func (dao *memoryBookDAO) Ping() error {
+// deleteWishListBookLocked is DeleteWishListBook for the callers that hold the lock.
+func (dao *memoryBookDAO) deleteWishListBookLocked(id int) error {
+	if _, ok := (*dao.wishListBooks)[id]; !ok {
+		return fmt.Errorf("wish list book %d does not exist", id)
+	}
+
+	delete(*dao.wishListBooks, id)
+	delete(*dao.reservations, id)
+
 	return nil
 }
 
-func (dao *memoryBookDAO) RemoveImage(imageID int) error {
-	delete(*dao.images, imageID)
+func (dao *memoryBookDAO) DeleteQuote(id int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := (*dao.quotes)[id]; !ok {
+		return fmt.Errorf("quote %d does not exist", id)
+	}
+
+	delete(*dao.quotes, id)
 
 	return nil
 }
 
-func removeIndex(elements []string, index int) []string {
-	ret := make([]string, 0)
-	ret = append(ret, elements[:index]...)
+func (dao *memoryBookDAO) DeleteReview(bookID int, userID string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-	return append(ret, elements[index+1:]...)
+	return dao.deleteReviewLocked(bookID, userID)
 }
 
-func find(elements []string, target string) int {
-	foundIndex := -1
+// deleteReviewLocked is DeleteReview for the callers that hold the lock.
+func (dao *memoryBookDAO) deleteReviewLocked(bookID int, userID string) error {
+	delete(*dao.reviews, reviewKey{bookID, userID})
 
-	for i, e := range elements {
-		if target == e {
-			foundIndex = i
+	return nil
+}
 
-			break
+// ForEachImage calls fn without holding the lock, so fn can use the DAO as well.
+func (dao *memoryBookDAO) ForEachImage(fn func(image book.BookImage) error) error {
+	dao.mu.RLock()
+	var images []book.BookImageInfo
+	for _, bookImages := range *dao.images {
+		images = append(images, bookImages...)
+	}
+	dao.mu.RUnlock()
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].ImageID < images[j].ImageID
+	})
+
+	for _, image := range images {
+		bookImage, err := dao.GetImage(image.ImageID)
+		if err != nil {
+			return err
+		}
+		bookImage.Position = image.Position
+		bookImage.IsCover = image.IsCover
+
+		if err := fn(bookImage); err != nil {
+			return err
 		}
 	}
 
-	return foundIndex
+	return nil
 }
 
-func (dao *memoryBookDAO) UnlikeBook(bookID, userID string) error {
-	// Remove the like made by the user
-	bookLikes, exists := (*dao.bookLikes)[userID]
-	if !exists {
-		return fmt.Errorf("error: user (%s) does not have liked books", userID)
+func (dao *memoryBookDAO) GetAllAuthors() ([]book.Author, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return dao.getAllAuthorsLocked()
+}
+
+// getAllAuthorsLocked is GetAllAuthors for the callers that hold the lock.
+func (dao *memoryBookDAO) getAllAuthorsLocked() ([]book.Author, error) {
+	bookIDs := map[int]map[int]bool{}
+	for _, bookInfo := range *dao.books {
+		for _, contributor := range bookInfo.Contributors {
+			if bookIDs[contributor.AuthorID] == nil {
+				bookIDs[contributor.AuthorID] = map[int]bool{}
+			}
+			bookIDs[contributor.AuthorID][bookInfo.ID] = true
+		}
 	}
 
-	bookIDxToRemove := find(bookLikes, bookID)
-	if bookIDxToRemove == -1 {
-		return fmt.Errorf("error: user (%s) does not have liked books", userID)
+	authors := make([]book.Author, 0, len(*dao.authors))
+	for _, author := range *dao.authors {
+		author.Aliases = slices.Clone(author.Aliases)
+		sort.Strings(author.Aliases)
+		author.Books = len(bookIDs[author.ID])
+		authors = append(authors, author)
 	}
 
-	(*dao.bookLikes)[userID] = removeIndex(bookLikes, bookIDxToRemove)
+	sort.Slice(authors, func(i, j int) bool {
+		sortI, sortJ := strings.ToLower(authors[i].SortKey()), strings.ToLower(authors[j].SortKey())
+		if sortI != sortJ {
+			return sortI < sortJ
+		}
 
-	return nil
+		return authors[i].ID < authors[j].ID
+	})
+
+	return authors, nil
 }
 
-func (dao *memoryBookDAO) UpdateBook(title string, author string, description string, read bool, goodreadsLink string, id int) error {
-	book := (*dao.books)[id]
-	book.Title = title
-	book.Author = author
-	book.Description = description
-	book.HasBeenRead = read
-	book.GoodreadsLink = goodreadsLink
+func (dao *memoryBookDAO) GetAllBooks() ([]book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	books := make([]book.BookInfo, 0, len(*dao.books))
+	for _, bookInfo := range *dao.books {
+		books = append(books, bookInfo)
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].ID < books[j].ID
+	})
+
+	return books, nil
+}
+
+func (dao *memoryBookDAO) GetAllCategories() ([]book.CategoryCount, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	counts := map[string]int{}
+	for _, bookInfo := range *dao.books {
+		if len(bookInfo.Category) > 0 {
+			counts[bookInfo.Category.String()]++
+		}
+	}
+
+	categories := make([]book.CategoryCount, 0, len(counts))
+	for category, count := range counts {
+		categories = append(categories, book.CategoryCount{Category: book.ParseCategory(category), Books: count})
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Category.String() < categories[j].Category.String()
+	})
+
+	return categories, nil
+}
+
+func (dao *memoryBookDAO) GetAllLikes() ([]book.BookLike, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	likes := []book.BookLike{}
+	for userID, bookIDs := range *dao.bookLikes {
+		for _, bookID := range bookIDs {
+			id, err := strconv.Atoi(bookID)
+			if err != nil {
+				return nil, err
+			}
+
+			likes = append(likes, book.BookLike{
+				BookID:    id,
+				UserID:    userID,
+				CreatedAt: (*dao.likedOn)[likeKey(bookID, userID)],
+			})
+		}
+	}
+
+	sort.Slice(likes, func(i, j int) bool {
+		if !likes[i].CreatedAt.Equal(likes[j].CreatedAt) {
+			return likes[i].CreatedAt.Before(likes[j].CreatedAt)
+		}
+		if likes[i].BookID != likes[j].BookID {
+			return likes[i].BookID < likes[j].BookID
+		}
+		return likes[i].UserID < likes[j].UserID
+	})
+
+	for i := range likes {
+		likes[i].ID = i + 1
+	}
+
+	return likes, nil
+}
+
+func (dao *memoryBookDAO) GetAllLoans() ([]book.Loan, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	loans := make([]book.Loan, 0, len(*dao.loans))
+	for _, loan := range *dao.loans {
+		loans = append(loans, loan)
+	}
+
+	sort.Slice(loans, func(i, j int) bool {
+		return loans[i].ID < loans[j].ID
+	})
+
+	return loans, nil
+}
+
+func (dao *memoryBookDAO) GetAllQuotes() ([]book.Quote, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return dao.getAllQuotesLocked()
+}
+
+// getAllQuotesLocked is GetAllQuotes for the callers that hold the lock.
+func (dao *memoryBookDAO) getAllQuotesLocked() ([]book.Quote, error) {
+	quotes := dao.quotesWhere(func(quote book.Quote) bool { return true })
+	sort.Slice(quotes, func(i, j int) bool {
+		return quotes[i].ID < quotes[j].ID
+	})
+
+	return quotes, nil
+}
+
+// quotesWhere returns the quotes that match by book and page, like the databases sort them.
+func (dao *memoryBookDAO) quotesWhere(match func(quote book.Quote) bool) []book.Quote {
+	quotes := []book.Quote{}
+	for _, quote := range *dao.quotes {
+		if match(quote) {
+			quotes = append(quotes, quote)
+		}
+	}
+
+	sort.Slice(quotes, func(i, j int) bool {
+		if quotes[i].BookID != quotes[j].BookID {
+			return quotes[i].BookID < quotes[j].BookID
+		}
+		if quotes[i].Page != quotes[j].Page {
+			return quotes[i].Page < quotes[j].Page
+		}
+		return quotes[i].ID < quotes[j].ID
+	})
+
+	return quotes
+}
+
+func (dao *memoryBookDAO) GetAllReviews() ([]book.Review, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	reviews := dao.reviewsWhere(func(review book.Review) bool { return true })
+	sort.Slice(reviews, func(i, j int) bool {
+		if reviews[i].BookID != reviews[j].BookID {
+			return reviews[i].BookID < reviews[j].BookID
+		}
+		return reviews[i].UserID < reviews[j].UserID
+	})
+
+	return reviews, nil
+}
+
+// reviewsWhere returns the reviews that match, with the names of their users.
+func (dao *memoryBookDAO) reviewsWhere(match func(review book.Review) bool) []book.Review {
+	reviews := []book.Review{}
+	for _, review := range *dao.reviews {
+		if match(review) {
+			review.UserName = (*dao.users)[review.UserID].Name
+			reviews = append(reviews, review)
+		}
+	}
+
+	return reviews
+}
+
+// sortLatestFirst sorts reviews the latest first, like the databases do.
+func sortLatestFirst(reviews []book.Review) {
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].UpdatedOn.Equal(reviews[j].UpdatedOn) {
+			return reviews[i].UpdatedOn.After(reviews[j].UpdatedOn)
+		}
+		if reviews[i].BookID != reviews[j].BookID {
+			return reviews[i].BookID < reviews[j].BookID
+		}
+		return reviews[i].UserID < reviews[j].UserID
+	})
+}
+
+func (dao *memoryBookDAO) GetAllShelves() ([]book.Shelf, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	shelves := make([]book.Shelf, 0, len(*dao.shelves))
+	for id := range *dao.shelves {
+		shelf, err := dao.getShelfByIDLocked(id)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, shelf)
+	}
+
+	sort.Slice(shelves, func(i, j int) bool {
+		for _, pair := range [][2]string{
+			{shelves[i].Room, shelves[j].Room},
+			{shelves[i].Bookcase, shelves[j].Bookcase},
+			{shelves[i].Name, shelves[j].Name},
+		} {
+			if a, b := strings.ToLower(pair[0]), strings.ToLower(pair[1]); a != b {
+				return a < b
+			}
+		}
+
+		return shelves[i].ID < shelves[j].ID
+	})
+
+	return shelves, nil
+}
+
+func (dao *memoryBookDAO) GetAllTags() ([]book.Tag, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	counts := map[string]int{}
+	for _, bookInfo := range *dao.books {
+		for _, tag := range bookInfo.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]book.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, book.Tag{Name: name, Books: count})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+func (dao *memoryBookDAO) GetAllUsers() ([]user.User, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	users := make([]user.User, 0, len(*dao.users))
+	for _, userInfo := range *dao.users {
+		users = append(users, userInfo)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].UserID < users[j].UserID
+	})
+
+	return users, nil
+}
+
+func (dao *memoryBookDAO) GetAllWorks() ([]book.Work, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	works := make([]book.Work, 0, len(*dao.works))
+	for _, work := range *dao.works {
+		works = append(works, work)
+	}
+
+	sort.Slice(works, func(i, j int) bool {
+		return works[i].ID < works[j].ID
+	})
+
+	return works, nil
+}
+
+func (dao *memoryBookDAO) GetAuthorByID(id int) (book.Author, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	authors, err := dao.getAllAuthorsLocked()
+	if err != nil {
+		return book.Author{}, err
+	}
+
+	for _, author := range authors {
+		if author.ID == id {
+			return author, nil
+		}
+	}
+
+	return book.Author{}, nil
+}
+
+func (dao *memoryBookDAO) GetBooksByAuthorID(authorID int) ([]book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	books := []book.BookInfo{}
+	for _, bookInfo := range *dao.books {
+		if !slices.ContainsFunc(bookInfo.Contributors, func(contributor book.Contributor) bool {
+			return contributor.AuthorID == authorID
+		}) {
+			continue
+		}
+
+		bookImages, err := dao.getImagesByBookIDLocked(bookInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		bookInfo.SetImages(bookImages)
+		books = append(books, bookInfo)
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].Title != books[j].Title {
+			return books[i].Title < books[j].Title
+		}
+
+		return books[i].ID < books[j].ID
+	})
+
+	return books, nil
+}
+
+// GetBooksByShelf returns the books of a shelf with their images, in the order they are on it.
+func (dao *memoryBookDAO) GetBooksByShelf(shelfID int) ([]book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	books := dao.booksOnShelf(shelfID)
+	for i := range books {
+		bookImages, err := dao.getImagesByBookIDLocked(books[i].ID)
+		if err != nil {
+			return nil, err
+		}
+
+		books[i].SetImages(bookImages)
+	}
+
+	return books, nil
+}
+
+// booksOnShelf returns the books of a shelf in the order they are on it.
+func (dao *memoryBookDAO) booksOnShelf(shelfID int) []book.BookInfo {
+	books := []book.BookInfo{}
+	for _, bookInfo := range *dao.books {
+		if bookInfo.Location.ShelfID == shelfID {
+			books = append(books, bookInfo)
+		}
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].Location.Position != books[j].Location.Position {
+			return books[i].Location.Position < books[j].Location.Position
+		}
+		if books[i].Title != books[j].Title {
+			return books[i].Title < books[j].Title
+		}
+
+		return books[i].ID < books[j].ID
+	})
+
+	return books
+}
+
+func (dao *memoryBookDAO) GetBookByID(id int) (book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	bookInfo, ok := (*dao.books)[id]
+	if !ok {
+		return book.BookInfo{}, fmt.Errorf("id %d does not exist", id)
+	}
+
+	bookImages, err := dao.getImagesByBookIDLocked(id)
+	if err != nil {
+		return book.BookInfo{}, err
+	}
+
+	bookInfo.SetImages(bookImages)
+
+	return bookInfo, nil
+}
+
+// GetBookIDsByImageHash only knows the images added while running, the files of the library in
+// images/ are not in the store.
+func (dao *memoryBookDAO) GetBookIDsByImageHash(hash string) ([]int, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	var bookIDs []int
+	for bookID, images := range *dao.images {
+		for _, image := range images {
+			if (*dao.imageHashes)[image.ImageID] == hash {
+				bookIDs = append(bookIDs, bookID)
+				break
+			}
+		}
+	}
+	sort.Ints(bookIDs)
+
+	return bookIDs, nil
+}
+
+func (dao *memoryBookDAO) GetBookCount() (int, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return len(*dao.books), nil
+}
+
+func (dao *memoryBookDAO) GetBooksWithPagination(offset, limit int) ([]book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	if offset > len(*dao.books) {
+		offset = len(*dao.books)
+	}
+
+	end := offset + limit
+	if end > len(*dao.books) {
+		end = len(*dao.books)
+	}
+
+	var books []book.BookInfo
+
+	for _, bookInfo := range *dao.books {
+		books = append(books, bookInfo)
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].Title < books[j].Title
+	})
+
+	return books[offset:end], nil
+}
+
+func (dao *memoryBookDAO) GetBooksBySearchTypeCoincidence(titleSearchText string, bookSearchType book.BookSearchType) ([]book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	var err error
+
+	var found *[]book.BookInfo
+
+	switch bookSearchType {
+	case book.ByAuthor:
+		found, err = searchByAuthor(titleSearchText, dao.books, dao.authors)
+		if err != nil {
+			return []book.BookInfo{}, err
+		}
+	case book.ByTitle:
+		found, err = searchByTitle(titleSearchText, dao.books)
+		if err != nil {
+			return []book.BookInfo{}, err
+		}
+	}
+
+	for i := range *found {
+		bookInfo := &(*found)[i]
+		bookImages, err := dao.getImagesByBookIDLocked(bookInfo.ID)
+		if err != nil {
+			return []book.BookInfo{}, err
+		}
+
+		bookInfo.SetImages(bookImages)
+	}
+
+	return *found, nil
+}
+
+func (dao *memoryBookDAO) GetBooksByISBN(isbnText string) ([]book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	normalized, err := isbn.Normalize(isbnText)
+	if err != nil {
+		return nil, err
+	} else if normalized == "" {
+		return nil, fmt.Errorf("%w: it is empty", isbn.ErrInvalid)
+	}
+
+	books := []book.BookInfo{}
+	for _, bookInfo := range *dao.books {
+		if bookInfo.ISBN != normalized {
+			continue
+		}
+
+		bookImages, err := dao.getImagesByBookIDLocked(bookInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		bookInfo.SetImages(bookImages)
+		books = append(books, bookInfo)
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].ID < books[j].ID
+	})
+
+	return books, nil
+}
+
+func (dao *memoryBookDAO) GetEditions(workID int) ([]book.BookInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	books := []book.BookInfo{}
+	for _, bookInfo := range *dao.books {
+		if bookInfo.WorkID != workID {
+			continue
+		}
+
+		bookImages, err := dao.getImagesByBookIDLocked(bookInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		bookInfo.SetImages(bookImages)
+		books = append(books, bookInfo)
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].Year != books[j].Year {
+			return books[i].Year < books[j].Year
+		}
+
+		return books[i].ID < books[j].ID
+	})
+
+	return books, nil
+}
+
+func (dao *memoryBookDAO) GetImage(imageID int) (book.BookImage, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	for bookID, images := range *dao.images {
+		for _, image := range images {
+			if image.ImageID != imageID {
+				continue
+			}
+
+			if hash, ok := (*dao.imageHashes)[imageID]; ok {
+				data, err := dao.imageStore.Get(hash)
+				return book.BookImage{ImageID: imageID, BookID: bookID, Data: data}, err
+			}
+
+			data, err := os.ReadFile((*dao.imageFiles)[imageID])
+			if err != nil {
+				return book.BookImage{}, err
+			}
+
+			return book.BookImage{ImageID: imageID, BookID: bookID, Data: data}, nil
+		}
+	}
+
+	return book.BookImage{}, fmt.Errorf("image %d does not exist", imageID)
+}
+
+func (dao *memoryBookDAO) GetImageVariant(imageID int, variant imaging.Variant) (book.BookImage, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	data, ok := (*dao.imageVariants)[imageID][variant]
+	if !ok {
+		return book.BookImage{}, ErrImageVariantNotFound
+	}
+
+	for bookID, images := range *dao.images {
+		for _, image := range images {
+			if image.ImageID == imageID {
+				return book.BookImage{ImageID: imageID, BookID: bookID, Data: data}, nil
+			}
+		}
+	}
+
+	return book.BookImage{}, fmt.Errorf("image %d does not exist", imageID)
+}
+
+func (dao *memoryBookDAO) GetImagesByBookID(bookID int) ([]book.BookImageInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return dao.getImagesByBookIDLocked(bookID)
+}
+
+// getImagesByBookIDLocked is GetImagesByBookID for the callers that hold the lock.
+func (dao *memoryBookDAO) getImagesByBookIDLocked(bookID int) ([]book.BookImageInfo, error) {
+	images, ok := (*dao.images)[bookID]
+	if !ok {
+		return []book.BookImageInfo{}, nil
+	}
+
+	return images, nil
+}
+
+// GetLoansByBookID returns the loans of a book, the latest first.
+func (dao *memoryBookDAO) GetLoansByBookID(bookID int) ([]book.Loan, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	loans := []book.Loan{}
+	for _, loan := range *dao.loans {
+		if loan.BookID == bookID {
+			loans = append(loans, loan)
+		}
+	}
+
+	sort.Slice(loans, func(i, j int) bool {
+		if !loans[i].LoanedOn.Equal(loans[j].LoanedOn) {
+			return loans[i].LoanedOn.After(loans[j].LoanedOn)
+		}
+
+		return loans[i].ID > loans[j].ID
+	})
+
+	return loans, nil
+}
+
+// GetShelfByID returns the shelf. A shelf that does not exist comes back empty, without an error.
+func (dao *memoryBookDAO) GetQuoteByID(id int) (book.Quote, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	quote, ok := (*dao.quotes)[id]
+	if !ok {
+		return book.Quote{}, fmt.Errorf("quote %d does not exist", id)
+	}
+
+	return quote, nil
+}
+
+// GetQuotesByBookID returns the quotes of a book in the order of their pages.
+func (dao *memoryBookDAO) GetQuotesByBookID(bookID int) ([]book.Quote, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return dao.quotesWhere(func(quote book.Quote) bool { return quote.BookID == bookID }), nil
+}
+
+// GetRandomQuote returns any of the quotes, an empty one when there are none.
+func (dao *memoryBookDAO) GetRandomQuote() (book.Quote, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	quotes, _ := dao.getAllQuotesLocked()
+	if len(quotes) == 0 {
+		return book.Quote{}, nil
+	}
+
+	return quotes[rand.Intn(len(quotes))], nil
+}
+
+func (dao *memoryBookDAO) GetReview(bookID int, userID string) (book.Review, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	review, ok := (*dao.reviews)[reviewKey{bookID, userID}]
+	if !ok {
+		return book.Review{BookID: bookID, UserID: userID}, nil
+	}
+	review.UserName = (*dao.users)[userID].Name
+
+	return review, nil
+}
+
+func (dao *memoryBookDAO) GetReviewsByBookID(bookID int) ([]book.Review, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	reviews := dao.reviewsWhere(func(review book.Review) bool { return review.BookID == bookID })
+	sortLatestFirst(reviews)
+
+	return reviews, nil
+}
+
+func (dao *memoryBookDAO) GetReviewsByUserID(userID string) ([]book.Review, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	reviews := dao.reviewsWhere(func(review book.Review) bool { return review.UserID == userID })
+	sortLatestFirst(reviews)
+
+	return reviews, nil
+}
+
+func (dao *memoryBookDAO) GetShelfByID(id int) (book.Shelf, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return dao.getShelfByIDLocked(id)
+}
+
+// getShelfByIDLocked is GetShelfByID for the callers that hold the lock.
+func (dao *memoryBookDAO) getShelfByIDLocked(id int) (book.Shelf, error) {
+	shelf, ok := (*dao.shelves)[id]
+	if !ok {
+		return book.Shelf{}, nil
+	}
+
+	shelf.Books = len(dao.booksOnShelf(id))
+
+	return shelf, nil
+}
+
+func (dao *memoryBookDAO) GetUserInfoByID(userID string) (user.UserInfo, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	userInfo, ok := (*dao.users)[userID]
+	if !ok {
+		return user.UserInfo{}, nil
+	}
+
+	return user.UserInfo{Sub: userInfo.UserID, Email: userInfo.Email, Name: userInfo.Name}, nil
+}
+
+func (dao *memoryBookDAO) LikedBy(bookID, userID string) (bool, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	likesPerUser := (*dao.bookLikes)[userID]
+
+	return exists(&likesPerUser, bookID), nil
+}
+
+// reviewKey is a review in memory: a user has one review per book.
+type reviewKey struct {
+	bookID int
+	userID string
+}
+
+func likeKey(bookID, userID string) string {
+	return bookID + "/" + userID
+}
+
+func exists(IDs *[]string, target string) bool {
+	for _, id := range *IDs {
+		if target == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasBeenLiked(IDs *[]string, target string) bool {
+	for _, id := range *IDs {
+		if target == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (dao *memoryBookDAO) LikeBook(bookID, userID string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookLikes, exists := (*dao.bookLikes)[userID]
+	if !exists {
+		(*dao.bookLikes)[userID] = make([]string, 0)
+		(*dao.bookLikes)[userID] = append((*dao.bookLikes)[userID], bookID)
+		(*dao.likedOn)[likeKey(bookID, userID)] = time.Now()
+
+		return nil
+	}
+
+	if hasLike := hasBeenLiked(&bookLikes, bookID); !hasLike {
+		(*dao.bookLikes)[userID] = append((*dao.bookLikes)[userID], bookID)
+		(*dao.likedOn)[likeKey(bookID, userID)] = time.Now()
+	}
+
+	return nil
+}
+
+func (dao *memoryBookDAO) LikesCount(bookID int) (int, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	count := 0
+	id := strconv.Itoa(bookID)
+	for _, bookLikesPerUser := range *dao.bookLikes {
+		if exists(&bookLikesPerUser, id) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (dao *memoryBookDAO) MergeAuthors(authorID, variantID int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if authorID == variantID {
+		return fmt.Errorf("author %d cannot be merged with itself", authorID)
+	}
+
+	author, ok := (*dao.authors)[authorID]
+	if !ok {
+		return fmt.Errorf("author %d does not exist", authorID)
+	}
+	variant, ok := (*dao.authors)[variantID]
+	if !ok {
+		return fmt.Errorf("author %d does not exist", variantID)
+	}
+
+	for _, alias := range append([]string{variant.Name}, variant.Aliases...) {
+		if !author.HasName(alias) {
+			author.Aliases = append(author.Aliases, alias)
+		}
+	}
+	if author.Bio == "" {
+		author.Bio = variant.Bio
+	}
+	if author.PhotoURL == "" {
+		author.PhotoURL = variant.PhotoURL
+	}
+	(*dao.authors)[authorID] = author
+	delete(*dao.authors, variantID)
+
+	for bookID, bookInfo := range *dao.books {
+		bookInfo.Contributors = slices.Clone(bookInfo.Contributors)
+		for i := range bookInfo.Contributors {
+			if bookInfo.Contributors[i].AuthorID == variantID {
+				bookInfo.Contributors[i].AuthorID = authorID
+			}
+		}
+		(*dao.books)[bookID] = bookInfo
+	}
+
+	return nil
+}
+
+func (dao *memoryBookDAO) Ping() error {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return nil
+}
+
+// PurchaseWishListBook adds the book to the catalogue and takes the wish list book out of the list.
+// The wish list book is looked up first so that a missing one adds nothing.
+func (dao *memoryBookDAO) PurchaseWishListBook(wishListBookID int, bookInfo book.BookInfo) (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := (*dao.wishListBooks)[wishListBookID]; !ok {
+		return 0, fmt.Errorf("wish list book %d does not exist", wishListBookID)
+	}
+
+	bookID, err := dao.createBookLocked(bookInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	return bookID, dao.deleteWishListBookLocked(wishListBookID)
+}
+
+// RelocateBooks makes the books the content of the shelf, in that order. The books that were on the
+// shelf and are not among them are left without a location.
+func (dao *memoryBookDAO) RelocateBooks(shelfID int, bookIDs []int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := checkRelocation(bookIDs); err != nil {
+		return err
+	}
+
+	if _, ok := (*dao.shelves)[shelfID]; !ok {
+		return fmt.Errorf("shelf %d does not exist", shelfID)
+	}
+
+	for _, bookID := range bookIDs {
+		if _, ok := (*dao.books)[bookID]; !ok {
+			return fmt.Errorf("book %d does not exist", bookID)
+		}
+	}
+
+	for _, bookInfo := range dao.booksOnShelf(shelfID) {
+		bookInfo.Location = book.Location{}
+		(*dao.books)[bookInfo.ID] = bookInfo
+	}
+
+	for i, bookID := range bookIDs {
+		bookInfo := (*dao.books)[bookID]
+		bookInfo.Location = book.Location{ShelfID: shelfID, Position: i + 1}
+		(*dao.books)[bookID] = bookInfo
+	}
+
+	return nil
+}
+
+func (dao *memoryBookDAO) RemoveImage(imageID int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	for bookID, images := range *dao.images {
+		for i, image := range images {
+			if image.ImageID == imageID {
+				hash, stored := (*dao.imageHashes)[imageID]
+				(*dao.images)[bookID] = append(images[:i:i], images[i+1:]...)
+				delete(*dao.imageHashes, imageID)
+				delete(*dao.imageFiles, imageID)
+				delete(*dao.imageVariants, imageID)
+
+				if !stored {
+					return nil
+				}
+				for _, otherHash := range *dao.imageHashes {
+					if otherHash == hash {
+						return nil
+					}
+				}
+
+				return dao.imageStore.Delete(hash)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (dao *memoryBookDAO) ReorderImages(bookID int, imageIDs []int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	images := (*dao.images)[bookID]
+	if !sameImages(images, imageIDs) {
+		return ErrImagesMismatch
+	}
+
+	positions := make(map[int]int, len(imageIDs))
+	for position, imageID := range imageIDs {
+		positions[imageID] = position
+	}
+
+	for i := range images {
+		images[i].Position = positions[images[i].ImageID]
+	}
+	sortImages(images)
+
+	return nil
+}
+
+// sortImages keeps the images of a book in the order they are shown.
+func sortImages(images []book.BookImageInfo) {
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Position != images[j].Position {
+			return images[i].Position < images[j].Position
+		}
+
+		return images[i].ImageID < images[j].ImageID
+	})
+}
+
+// ReserveWishListBook reserves a gift for a user. Reserving again what the same user reserved
+// changes nothing; what somebody else reserved is ErrWishListBookReserved.
+func (dao *memoryBookDAO) ReserveWishListBook(reservation book.WishListReservation) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if reservation.UserID == "" {
+		return fmt.Errorf("a reservation needs a user")
+	}
+
+	wishListBook, ok := (*dao.wishListBooks)[reservation.WishListBookID]
+	if !ok {
+		return fmt.Errorf("wish list book %d does not exist", reservation.WishListBookID)
+	}
+
+	if err := wishListBook.CheckReservation(reservation.UserID); err != nil {
+		return err
+	}
+
+	if reserved, ok := (*dao.reservations)[reservation.WishListBookID]; ok {
+		if reserved.UserID != reservation.UserID {
+			return ErrWishListBookReserved
+		}
+
+		return nil
+	}
+
+	if reservation.ReservedOn.IsZero() {
+		reservation.ReservedOn = time.Now()
+	}
+	(*dao.reservations)[reservation.WishListBookID] = reservation
+
+	return nil
+}
+
+func (dao *memoryBookDAO) RestoreImage(image book.BookImage) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := (*dao.books)[image.BookID]; !ok {
+		return fmt.Errorf("id %d does not exist", image.BookID)
+	}
+
+	for _, images := range *dao.images {
+		for _, existing := range images {
+			if existing.ImageID == image.ImageID {
+				return nil
+			}
+		}
+	}
+
+	hash, err := dao.imageStore.Put(image.Data)
+	if err != nil {
+		return err
+	}
+
+	bookImageInfo := book.NewBookImageInfo(image.ImageID, image.BookID)
+	bookImageInfo.Position = image.Position
+	bookImageInfo.IsCover = image.IsCover
+	(*dao.images)[image.BookID] = append((*dao.images)[image.BookID], bookImageInfo)
+	sortImages((*dao.images)[image.BookID])
+	(*dao.imageHashes)[image.ImageID] = hash
+	if variants := generateImageVariants(image.ImageID, image.Data); variants != nil {
+		(*dao.imageVariants)[image.ImageID] = variants
+	}
+
+	return nil
+}
+
+// ReturnLoan records that the book of a loan is back.
+func (dao *memoryBookDAO) ReturnLoan(loanID int, returnedOn time.Time) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	loan, ok := (*dao.loans)[loanID]
+	if !ok || loan.Returned() {
+		return fmt.Errorf("loan %d does not exist or was already returned", loanID)
+	}
+
+	loan.ReturnedOn = returnedOn
+	dao.saveLoan(loan)
+
+	return nil
+}
+
+func (dao *memoryBookDAO) SaveImageVariant(imageID int, variant imaging.Variant, data []byte) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if (*dao.imageVariants)[imageID] == nil {
+		(*dao.imageVariants)[imageID] = map[imaging.Variant][]byte{}
+	}
+	(*dao.imageVariants)[imageID][variant] = data
+
+	return nil
+}
+
+func removeIndex(elements []string, index int) []string {
+	ret := make([]string, 0)
+	ret = append(ret, elements[:index]...)
+
+	return append(ret, elements[index+1:]...)
+}
+
+func find(elements []string, target string) int {
+	foundIndex := -1
+
+	for i, e := range elements {
+		if target == e {
+			foundIndex = i
+
+			break
+		}
+	}
+
+	return foundIndex
+}
+
+func (dao *memoryBookDAO) SaveReview(review book.Review) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := review.Validate(); err != nil {
+		return err
+	}
+
+	if review.IsEmpty() {
+		return dao.deleteReviewLocked(review.BookID, review.UserID)
+	}
+
+	if _, ok := (*dao.books)[review.BookID]; !ok {
+		return fmt.Errorf("book %d does not exist", review.BookID)
+	}
+
+	if review.UpdatedOn.IsZero() {
+		review.UpdatedOn = time.Now()
+	}
+	review.UserName = ""
+	review.Text = strings.TrimSpace(review.Text)
+	review.Notes = strings.TrimSpace(review.Notes)
+	(*dao.reviews)[reviewKey{review.BookID, review.UserID}] = review
+
+	return nil
+}
+
+func (dao *memoryBookDAO) UnlikeBook(bookID, userID string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	// Remove the like made by the user
+	bookLikes, exists := (*dao.bookLikes)[userID]
+	if !exists {
+		return fmt.Errorf("error: user (%s) does not have liked books", userID)
+	}
+
+	bookIDxToRemove := find(bookLikes, bookID)
+	if bookIDxToRemove == -1 {
+		return fmt.Errorf("error: user (%s) does not have liked books", userID)
+	}
+
+	(*dao.bookLikes)[userID] = removeIndex(bookLikes, bookIDxToRemove)
+	delete(*dao.likedOn, likeKey(bookID, userID))
+
+	return nil
+}
+
+func (dao *memoryBookDAO) UpdateAuthor(author book.Author) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	previous, ok := (*dao.authors)[author.ID]
+	if !ok {
+		return fmt.Errorf("author %d does not exist", author.ID)
+	}
+
+	if author.SortName == "" {
+		author.SortName = book.SortName(author.Name)
+	}
+
+	// The name they had becomes an alias, so the books that write it that way keep finding them.
+	author.Aliases = nil
+	if !strings.EqualFold(previous.Name, author.Name) {
+		author.Aliases = append(author.Aliases, previous.Name)
+	}
+	for _, alias := range previous.Aliases {
+		if !strings.EqualFold(alias, author.Name) {
+			author.Aliases = append(author.Aliases, alias)
+		}
+	}
+	author.Books = 0
+	(*dao.authors)[author.ID] = author
+
+	return nil
+}
+
+func (dao *memoryBookDAO) UpdateBook(title string, author string, description string, read bool, goodreadsLink string, isbnText string, id int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	normalized, err := isbn.Normalize(isbnText)
+	if err != nil {
+		return err
+	}
+
+	book := (*dao.books)[id]
+	book.Title = title
+	book.SetAuthor(author)
+	resolveAuthors(dao.authors, book.Contributors)
+	book.Description = description
+	book.MarkRead(read)
+	book.GoodreadsLink = goodreadsLink
+	book.ISBN = normalized
 
 	(*dao.books)[id] = book
 
 	return nil
 }
 
-func (dao *memoryBookDAO) GetWishListBooks() ([]book.WishListBook, error) {
-	return dao.wishListBooks, nil
+func (dao *memoryBookDAO) UpdateEdition(bookID, workID int, edition book.Edition) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookInfo, ok := (*dao.books)[bookID]
+	if !ok {
+		return fmt.Errorf("id %d does not exist", bookID)
+	}
+
+	bookInfo.WorkID = workID
+	bookInfo.Edition = edition
+	(*dao.books)[bookID] = bookInfo
+
+	return nil
+}
+
+func (dao *memoryBookDAO) UpdateLoan(loan book.Loan) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	previous, ok := (*dao.loans)[loan.ID]
+	if !ok {
+		return fmt.Errorf("loan %d does not exist", loan.ID)
+	}
+
+	loan.BookID = previous.BookID
+	if err := dao.checkLoan(loan); err != nil {
+		return err
+	}
+
+	dao.saveLoan(loan)
+
+	return nil
+}
+
+// UpdateQuote changes the text, the page and the tags of a quote, its book and date stay the same.
+func (dao *memoryBookDAO) UpdateQuote(quote book.Quote) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	previous, ok := (*dao.quotes)[quote.ID]
+	if !ok {
+		return fmt.Errorf("quote %d does not exist", quote.ID)
+	}
+
+	quote.BookID = previous.BookID
+	quote.AddedOn = previous.AddedOn
+	if err := quote.Validate(); err != nil {
+		return err
+	}
+
+	dao.saveQuote(quote)
+
+	return nil
+}
+
+func (dao *memoryBookDAO) UpdateShelf(shelf book.Shelf) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := (*dao.shelves)[shelf.ID]; !ok {
+		return fmt.Errorf("shelf %d does not exist", shelf.ID)
+	}
+
+	shelf.Books = 0
+	(*dao.shelves)[shelf.ID] = shelf
+
+	return nil
+}
+
+func (dao *memoryBookDAO) UpdateWishListBook(wishListBook book.WishListBook) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	saved, ok := (*dao.wishListBooks)[wishListBook.ID]
+	if !ok {
+		return fmt.Errorf("wish list book %d does not exist", wishListBook.ID)
+	}
+
+	if err := wishListBook.Validate(); err != nil {
+		return err
+	}
+	wishListBook.Normalize()
+	wishListBook.UserID = saved.UserID
+	(*dao.wishListBooks)[wishListBook.ID] = wishListBook
+
+	return nil
+}
+
+func (dao *memoryBookDAO) GetWishListBooks(userID string) ([]book.WishListBook, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	wishListBooks := []book.WishListBook{}
+	for _, wishListBook := range *dao.wishListBooks {
+		if wishListBook.UserID == userID {
+			wishListBooks = append(wishListBooks, wishListBook)
+		}
+	}
+
+	sort.Slice(wishListBooks, func(i, j int) bool {
+		a, b := wishListBooks[i], wishListBooks[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if title, other := strings.ToLower(a.Title), strings.ToLower(b.Title); title != other {
+			return title < other
+		}
+
+		return a.ID < b.ID
+	})
+
+	return wishListBooks, nil
+}
+
+func (dao *memoryBookDAO) GetWishListBookByID(id int) (book.WishListBook, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	wishListBook, ok := (*dao.wishListBooks)[id]
+	if !ok {
+		return book.WishListBook{}, fmt.Errorf("wish list book %d does not exist", id)
+	}
+
+	return wishListBook, nil
+}
+
+// GetWishListReservations returns the reservations of the books in the wish list of a user.
+func (dao *memoryBookDAO) GetWishListReservations(ownerID string) ([]book.WishListReservation, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	reservations := []book.WishListReservation{}
+	for _, reservation := range *dao.reservations {
+		if (*dao.wishListBooks)[reservation.WishListBookID].UserID == ownerID {
+			reservations = append(reservations, reservation)
+		}
+	}
+
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].WishListBookID < reservations[j].WishListBookID
+	})
+
+	return reservations, nil
+}
+
+func (dao *memoryBookDAO) SetAcquisition(bookID int, acquisition book.Acquisition) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookInfo, ok := (*dao.books)[bookID]
+	if !ok {
+		return fmt.Errorf("id %d does not exist", bookID)
+	}
+
+	if err := acquisition.Validate(); err != nil {
+		return err
+	}
+	acquisition.Normalize()
+	bookInfo.Acquisition = acquisition
+	(*dao.books)[bookID] = bookInfo
+
+	return nil
+}
+
+// SetBookLocation puts a book on a shelf. Without a position it goes after the last book of the
+// shelf; at the position of another book, that book and the ones after it move one place to the
+// right. A location without shelf takes the book off its shelf.
+// SearchQuotes returns the quotes with every word of the search in their text or their tags, by book
+// and page. An empty search returns them all.
+func (dao *memoryBookDAO) SearchQuotes(search string) ([]book.Quote, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	return dao.quotesWhere(func(quote book.Quote) bool { return quote.Matches(search) }), nil
+}
+
+func (dao *memoryBookDAO) SetBookLocation(bookID int, location book.Location) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return dao.setBookLocationLocked(bookID, location)
+}
+
+// setBookLocationLocked is SetBookLocation for the callers that hold the lock.
+func (dao *memoryBookDAO) setBookLocationLocked(bookID int, location book.Location) error {
+	bookInfo, ok := (*dao.books)[bookID]
+	if !ok {
+		return fmt.Errorf("book %d does not exist", bookID)
+	}
+
+	if location.IsZero() {
+		bookInfo.Location = book.Location{}
+		(*dao.books)[bookID] = bookInfo
+
+		return nil
+	}
+
+	if _, ok := (*dao.shelves)[location.ShelfID]; !ok {
+		return fmt.Errorf("shelf %d does not exist", location.ShelfID)
+	}
+
+	var others []book.BookInfo
+	for _, other := range dao.booksOnShelf(location.ShelfID) {
+		if other.ID != bookID {
+			others = append(others, other)
+		}
+	}
+
+	if location.Position <= 0 {
+		location.Position = 1
+		for _, other := range others {
+			if other.Location.Position >= location.Position {
+				location.Position = other.Location.Position + 1
+			}
+		}
+	} else if slices.ContainsFunc(others, func(other book.BookInfo) bool {
+		return other.Location.Position == location.Position
+	}) {
+		for _, other := range others {
+			if other.Location.Position >= location.Position {
+				other.Location.Position++
+				(*dao.books)[other.ID] = other
+			}
+		}
+	}
+
+	bookInfo.Location = location
+	(*dao.books)[bookID] = bookInfo
+
+	return nil
+}
+
+func (dao *memoryBookDAO) SetCategory(bookID int, category book.Category) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookInfo, ok := (*dao.books)[bookID]
+	if !ok {
+		return fmt.Errorf("id %d does not exist", bookID)
+	}
+
+	bookInfo.Category = slices.Clone(category)
+	(*dao.books)[bookID] = bookInfo
+
+	return nil
+}
+
+func (dao *memoryBookDAO) SetContributors(bookID int, contributors []book.Contributor) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookInfo, ok := (*dao.books)[bookID]
+	if !ok {
+		return fmt.Errorf("id %d does not exist", bookID)
+	}
+
+	bookInfo.Contributors = slices.Clone(contributors)
+	bookInfo.Author = ""
+	bookInfo.NormalizeContributors()
+	if len(bookInfo.Contributors) == 0 {
+		return fmt.Errorf("book %d: it needs at least one contributor", bookID)
+	}
+	resolveAuthors(dao.authors, bookInfo.Contributors)
+	(*dao.books)[bookID] = bookInfo
+
+	return nil
+}
+
+func (dao *memoryBookDAO) SetCoverImage(bookID, imageID int) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	images := (*dao.images)[bookID]
+
+	found := false
+	for i := range images {
+		if images[i].ImageID == imageID {
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("image %d of book %d: %w", imageID, bookID, ErrImagesMismatch)
+	}
+
+	for i := range images {
+		images[i].IsCover = images[i].ImageID == imageID
+	}
+
+	return nil
+}
+
+func (dao *memoryBookDAO) SetReading(bookID int, status book.ReadingStatus, pages int, readings []book.Reading) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookInfo, ok := (*dao.books)[bookID]
+	if !ok {
+		return fmt.Errorf("id %d does not exist", bookID)
+	}
+
+	bookInfo.ReadingStatus = status
+	bookInfo.Pages = pages
+	bookInfo.Readings = slices.Clone(readings)
+	if err := bookInfo.ValidateReading(); err != nil {
+		return err
+	}
+	bookInfo.HasBeenRead = false
+	bookInfo.NormalizeReading()
+	(*dao.books)[bookID] = bookInfo
+
+	return nil
+}
+
+func (dao *memoryBookDAO) SetTags(bookID int, tags []string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	bookInfo, ok := (*dao.books)[bookID]
+	if !ok {
+		return fmt.Errorf("id %d does not exist", bookID)
+	}
+
+	bookInfo.Tags = book.NormalizeTags(tags)
+	(*dao.books)[bookID] = bookInfo
+
+	return nil
 }
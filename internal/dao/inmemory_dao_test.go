@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	book "leonlib/internal/types"
+	"sync"
+	"testing"
+)
+
+// TestMemoryDAOConcurrentRequests runs what the web application does for several requests at once,
+// under go test -race it fails when a map is used without the lock.
+func TestMemoryDAOConcurrentRequests(t *testing.T) {
+	bookDAO, err := OpenDAO("memory", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bookDAO.Close()
+
+	const requests = 20
+
+	images := make([][]byte, requests)
+	for i := range images {
+		images[i] = testJPEG(t, i+1)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests*3)
+	for i := 0; i < requests; i++ {
+		i := i
+		wg.Add(3)
+
+		go func() {
+			defer wg.Done()
+			bookID, err := bookDAO.CreateBook(book.BookInfo{Title: fmt.Sprintf("Libro %d", i), Author: "Julio Cortázar",
+				Image: images[i]})
+			if err == nil {
+				err = bookDAO.LikeBook(fmt.Sprint(bookID), fmt.Sprintf("user%d", i))
+			}
+			errs <- err
+		}()
+
+		go func() {
+			defer wg.Done()
+			_, err := bookDAO.GetAllBooks()
+			errs <- err
+		}()
+
+		go func() {
+			defer wg.Done()
+			_, err := bookDAO.CreateWishListBook(book.WishListBook{Title: fmt.Sprintf("Deseo %d", i), Author: "Italo Calvino"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != requests {
+		t.Errorf("the catalogue has %d books, want %d", len(books), requests)
+	}
+
+	stored := 0
+	if err := bookDAO.ForEachImage(func(image book.BookImage) error {
+		stored++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if stored != requests {
+		t.Errorf("ForEachImage went through %d images, want %d", stored, requests)
+	}
+}
+
+// testJPEG is a JPEG of a different size for every width, so that each one is a different image.
+func testJPEG(t *testing.T, width int) []byte {
+	t.Helper()
+
+	var data bytes.Buffer
+	if err := jpeg.Encode(&data, image.NewRGBA(image.Rect(0, 0, width, 1)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return data.Bytes()
+}
@@ -1,149 +1,195 @@
 package dao
 
 import (
-	"database/sql"
+	"fmt"
+	"leonlib/internal/imaging"
 	book "leonlib/internal/types"
 	user "leonlib/internal/types"
 	"log"
-	"os"
-	"path/filepath"
 	"time"
 )
 
 func (dao *postgresBookDAO) AddAll(books []book.BookInfo) error {
-	for _, book := range books {
-		log.Printf("Reading: (%s)", book)
-		bookInfo, err := dao.GetBookByID(book.ID)
-		if err == nil && bookInfo.ID == book.ID {
-			log.Printf("Book with ID: %d already exists, skipping", book.ID)
+	for _, bookInfo := range books {
+		log.Printf("Reading: (%s)", bookInfo)
+		existing, err := dao.GetBookByID(bookInfo.ID)
+		if err == nil && existing.ID == bookInfo.ID {
+			log.Printf("Book with ID: %d already exists, skipping", bookInfo.ID)
 			continue
 		}
 
-		var bookID int
-		stmt, err := dao.db.Prepare("INSERT INTO books(id, title, author, description, read, added_on, goodreads_link) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id")
+		bookID, err := dao.CreateBook(bookInfo)
 		if err != nil {
 			return err
 		}
 
-		err = stmt.QueryRow(book.ID, book.Title, book.Author, book.Description, book.HasBeenRead, book.AddedOn, book.GoodreadsLink).Scan(&bookID)
-		if err != nil {
+		if err := addImagesToBook(bookID, &bookInfo.ImageNames, dao.db, dao.imageStore); err != nil {
 			return err
 		}
-
-		for _, imageName := range book.ImageNames {
-			imgBytes, err := os.ReadFile(filepath.Join("images", imageName))
-			if err != nil {
-				return err
-			}
-
-			imgStmt, err := dao.db.Prepare("INSERT INTO book_images(book_id, image) VALUES($1, $2)")
-			if err != nil {
-				return err
-			}
-
-			_, err = imgStmt.Exec(bookID, imgBytes)
-			if err != nil {
-				return err
-			}
-		}
 	}
 
 	return nil
 }
 
 func (dao *postgresBookDAO) AddImageToBook(bookID int, imageData []byte) error {
-	return addImageToBook(bookID, imageData, dao.db)
+	return addImageToBook(bookID, imageData, dao.db, dao.imageStore)
+}
+
+func (dao *postgresBookDAO) AddLike(like book.BookLike) error {
+	return addLike(dao.db, like)
 }
 
 func (dao *postgresBookDAO) AddUser(userID, email, name, oauthIdentifier string) error {
 	return addUser(dao.db, userID, email, name, oauthIdentifier)
 }
 
+func (dao *postgresBookDAO) CancelWishListReservation(wishListBookID int, userID string) error {
+	return cancelWishListReservation(dao.db, wishListBookID, userID)
+}
+
 func (dao *postgresBookDAO) Close() error {
 	return nil
 }
 
-func (dao *postgresBookDAO) CreateBook(book book.BookInfo) error {
-	stmt, err := dao.db.Prepare("INSERT INTO books (title, author, description, read, goodreads_link) VALUES ($1, $2, $3, $4, $5)")
-	if err != nil {
-		return err
+func (dao *postgresBookDAO) CollectImageGarbage() (int, error) {
+	return collectImageGarbage(dao.db, dao.imageStore)
+}
+
+// CreateBook keeps the ID the book carries, as the CSV import and the migrations do, and then moves
+// the sequence past it so the next book without one does not collide.
+func (dao *postgresBookDAO) CreateBook(book book.BookInfo) (int, error) {
+	bookID, err := createBook(dao.db, dao.imageStore, book)
+	if err != nil || book.ID <= 0 {
+		return bookID, err
 	}
-	defer stmt.Close()
 
-	insertedBookIDResult, err := stmt.Exec(book.Title, book.Author, book.Description, book.HasBeenRead, book.GoodreadsLink)
-	if err != nil {
-		return err
+	return bookID, dao.resetSequence("books", "id")
+}
+
+func (dao *postgresBookDAO) CreateAuthor(author book.Author) (int, error) {
+	authorID, err := createAuthor(dao.db, author)
+	if err != nil || author.ID <= 0 {
+		return authorID, err
 	}
 
-	insertedBookID, err := insertedBookIDResult.LastInsertId()
-	if err != nil {
-		return err
+	return authorID, dao.resetSequence("authors", "id")
+}
+
+func (dao *postgresBookDAO) CreateLoan(loan book.Loan) (int, error) {
+	loanID, err := createLoan(dao.db, loan)
+	if err != nil || loan.ID <= 0 {
+		return loanID, err
 	}
 
-	imgStmt, err := dao.db.Prepare("INSERT INTO book_images(book_id, image) VALUES($1, $2)")
-	if err != nil {
-		return err
+	return loanID, dao.resetSequence("loans", "id")
+}
+
+func (dao *postgresBookDAO) CreateQuote(quote book.Quote) (int, error) {
+	quoteID, err := createQuote(dao.db, quote)
+	if err != nil || quote.ID <= 0 {
+		return quoteID, err
 	}
 
-	_, err = imgStmt.Exec(insertedBookID, book.Image)
-	if err != nil {
-		return err
+	return quoteID, dao.resetSequence("quotes", "id")
+}
+
+func (dao *postgresBookDAO) CreateShelf(shelf book.Shelf) (int, error) {
+	shelfID, err := createShelf(dao.db, shelf)
+	if err != nil || shelf.ID <= 0 {
+		return shelfID, err
 	}
 
-	return nil
+	return shelfID, dao.resetSequence("shelves", "id")
+}
+
+func (dao *postgresBookDAO) CreateWishListBook(wishListBook book.WishListBook) (int, error) {
+	wishListBookID, err := createWishListBook(dao.db, wishListBook)
+	if err != nil || wishListBook.ID <= 0 {
+		return wishListBookID, err
+	}
+
+	return wishListBookID, dao.resetSequence("wish_list_books", "id")
+}
+
+func (dao *postgresBookDAO) CreateWork(work book.Work) (int, error) {
+	workID, err := createWork(dao.db, work)
+	if err != nil || work.ID <= 0 {
+		return workID, err
+	}
+
+	return workID, dao.resetSequence("works", "id")
+}
+
+func (dao *postgresBookDAO) DeleteShelf(id int) error {
+	return deleteShelf(dao.db, id)
+}
+
+func (dao *postgresBookDAO) DeleteWishListBook(id int) error {
+	return deleteWishListBook(dao.db, id)
 }
 
-func (dao *postgresBookDAO) GetAllAuthors() ([]string, error) {
+func (dao *postgresBookDAO) DeleteQuote(id int) error {
+	return deleteQuote(dao.db, id)
+}
+
+func (dao *postgresBookDAO) DeleteReview(bookID int, userID string) error {
+	return deleteReview(dao.db, bookID, userID)
+}
+
+func (dao *postgresBookDAO) ForEachImage(fn func(image book.BookImage) error) error {
+	return forEachImage(dao.db, dao.imageStore, fn)
+}
+
+func (dao *postgresBookDAO) GetAllAuthors() ([]book.Author, error) {
 	return getAllAuthors(dao.db)
 }
 
-func (dao *postgresBookDAO) GetBookByID(id int) (book.BookInfo, error) {
-	var err error
-	var queryStr = `SELECT b.id, b.title, b.author, b.description, b.read, b.added_on, b.goodreads_link FROM books b WHERE b.id=$1`
+func (dao *postgresBookDAO) GetAllBooks() ([]book.BookInfo, error) {
+	return getAllBooks(dao.db)
+}
 
-	bookRows, err := dao.db.Query(queryStr, id)
-	if err != nil {
-		return book.BookInfo{}, err
-	}
+func (dao *postgresBookDAO) GetAllCategories() ([]book.CategoryCount, error) {
+	return getAllCategories(dao.db)
+}
 
-	defer func() {
-		_ = bookRows.Close()
-	}()
+func (dao *postgresBookDAO) GetAllLikes() ([]book.BookLike, error) {
+	return getAllLikes(dao.db)
+}
 
-	var bookInfo book.BookInfo
-	var bookID int
-	var title string
-	var author string
-	var description string
-	var hasBeenRead bool
-	var addedOn time.Time
-	var goodreadsLink sql.NullString
-	if bookRows.Next() {
-		if err := bookRows.Scan(&bookID, &title, &author, &description, &hasBeenRead, &addedOn, &goodreadsLink); err != nil {
-			return book.BookInfo{}, err
-		}
+func (dao *postgresBookDAO) GetAllLoans() ([]book.Loan, error) {
+	return getAllLoans(dao.db)
+}
 
-		bookInfo.ID = bookID
-		bookInfo.Title = title
-		bookInfo.Author = author
-		bookInfo.Description = description
-		bookInfo.HasBeenRead = hasBeenRead
-		bookInfo.AddedOn = addedOn.Format("2006-01-02")
-		if goodreadsLink.Valid {
-			bookInfo.GoodreadsLink = goodreadsLink.String
-		} else {
-			bookInfo.GoodreadsLink = ""
-		}
-	}
+func (dao *postgresBookDAO) GetAllQuotes() ([]book.Quote, error) {
+	return getAllQuotes(dao.db)
+}
 
-	bookImages, err := dao.GetImagesByBookID(id)
-	if err != nil {
-		return book.BookInfo{}, err
-	}
+func (dao *postgresBookDAO) GetAllReviews() ([]book.Review, error) {
+	return getAllReviews(dao.db)
+}
+
+func (dao *postgresBookDAO) GetAllShelves() ([]book.Shelf, error) {
+	return getAllShelves(dao.db)
+}
+
+func (dao *postgresBookDAO) GetAllTags() ([]book.Tag, error) {
+	return getAllTags(dao.db)
+}
+
+func (dao *postgresBookDAO) GetAllUsers() ([]user.User, error) {
+	return getAllUsers(dao.db)
+}
+
+func (dao *postgresBookDAO) GetBookByID(id int) (book.BookInfo, error) {
+	return getBookByID(dao.db, id)
+}
 
-	bookInfo.Base64Images = bookImages
+func (dao *postgresBookDAO) GetAllWorks() ([]book.Work, error) {
+	return getAllWorks(dao.db)
+}
 
-	return bookInfo, nil
+func (dao *postgresBookDAO) GetBookIDsByImageHash(hash string) ([]int, error) {
+	return getBookIDsByImageHash(dao.db, hash)
 }
 
 func (dao *postgresBookDAO) GetBookCount() (int, error) {
@@ -155,56 +201,73 @@ func (dao *postgresBookDAO) GetBooksWithPagination(offset, limit int) ([]book.Bo
 }
 
 func (dao *postgresBookDAO) GetBooksBySearchTypeCoincidence(titleSearchText string, bookSearchType book.BookSearchType) ([]book.BookInfo, error) {
-	var err error
-	queryStr := `SELECT b.id, b.title, b.author, b.description, b.read, b.added_on, b.goodreads_link FROM books b WHERE LOWER(b.title) LIKE '%' || LOWER($1) || '%' ORDER BY b.title`
+	return getBooksBySearchTypeCoincidence(dao.db, titleSearchText, bookSearchType)
+}
 
-	if bookSearchType == book.ByAuthor {
-		queryStr = `SELECT b.id, b.title, b.author, b.description, b.read, b.added_on, b.goodreads_link FROM books b WHERE LOWER(b.author) LIKE '%' || LOWER($1) || '%' ORDER BY b.title`
-	}
+func (dao *postgresBookDAO) GetAuthorByID(id int) (book.Author, error) {
+	return getAuthorByID(dao.db, id)
+}
 
-	booksByTitleRows, err := dao.db.Query(queryStr, "%"+titleSearchText+"%")
-	if err != nil {
-		return []book.BookInfo{}, err
-	}
+func (dao *postgresBookDAO) GetBooksByAuthorID(authorID int) ([]book.BookInfo, error) {
+	return getBooksByAuthorID(dao.db, authorID)
+}
 
-	defer booksByTitleRows.Close()
-
-	var books []book.BookInfo
-	var id int
-	var title string
-	var author string
-	var description string
-	var hasBeenRead bool
-	var addedOn time.Time
-	var goodreadsLink string
-	for booksByTitleRows.Next() {
-		var bookInfo book.BookInfo
-		if err := booksByTitleRows.Scan(&id, &title, &author, &description, &hasBeenRead, &addedOn, &goodreadsLink); err != nil {
-			return []book.BookInfo{}, err
-		}
+func (dao *postgresBookDAO) GetBooksByShelf(shelfID int) ([]book.BookInfo, error) {
+	return getBooksByShelf(dao.db, shelfID)
+}
 
-		bookInfo.ID = id
-		bookInfo.Title = title
-		bookInfo.Author = author
-		bookImages, err := dao.GetImagesByBookID(id)
-		if err != nil {
-			return []book.BookInfo{}, err
-		}
+func (dao *postgresBookDAO) GetBooksByISBN(isbn string) ([]book.BookInfo, error) {
+	return getBooksByISBN(dao.db, isbn)
+}
 
-		bookInfo.Base64Images = bookImages
-		bookInfo.Description = description
-		bookInfo.HasBeenRead = hasBeenRead
-		bookInfo.AddedOn = addedOn.Format("2006-01-02")
-		books = append(books, bookInfo)
-	}
+func (dao *postgresBookDAO) GetEditions(workID int) ([]book.BookInfo, error) {
+	return getEditions(dao.db, workID)
+}
 
-	return books, nil
+func (dao *postgresBookDAO) GetImage(imageID int) (book.BookImage, error) {
+	return getImage(dao.db, dao.imageStore, imageID)
+}
+
+func (dao *postgresBookDAO) GetImageVariant(imageID int, variant imaging.Variant) (book.BookImage, error) {
+	return getImageVariant(dao.db, imageID, variant)
 }
 
 func (dao *postgresBookDAO) GetImagesByBookID(bookID int) ([]book.BookImageInfo, error) {
 	return getImagesByBookID(bookID, dao.db)
 }
 
+func (dao *postgresBookDAO) GetLoansByBookID(bookID int) ([]book.Loan, error) {
+	return getLoansByBookID(dao.db, bookID)
+}
+
+func (dao *postgresBookDAO) GetQuoteByID(id int) (book.Quote, error) {
+	return getQuoteByID(dao.db, id)
+}
+
+func (dao *postgresBookDAO) GetQuotesByBookID(bookID int) ([]book.Quote, error) {
+	return getQuotesByBookID(dao.db, bookID)
+}
+
+func (dao *postgresBookDAO) GetRandomQuote() (book.Quote, error) {
+	return getRandomQuote(dao.db)
+}
+
+func (dao *postgresBookDAO) GetReview(bookID int, userID string) (book.Review, error) {
+	return getReview(dao.db, bookID, userID)
+}
+
+func (dao *postgresBookDAO) GetReviewsByBookID(bookID int) ([]book.Review, error) {
+	return getReviewsByBookID(dao.db, bookID)
+}
+
+func (dao *postgresBookDAO) GetReviewsByUserID(userID string) ([]book.Review, error) {
+	return getReviewsByUserID(dao.db, userID)
+}
+
+func (dao *postgresBookDAO) GetShelfByID(id int) (book.Shelf, error) {
+	return getShelfByID(dao.db, id)
+}
+
 func (dao *postgresBookDAO) GetUserInfoByID(id string) (user.UserInfo, error) {
 	var err error
 	var queryStr = `SELECT u.user_id, u.email, u.name FROM users u WHERE u.user_id=$1`
@@ -269,6 +332,10 @@ func (dao *postgresBookDAO) LikeBook(bookID, userID string) error {
 	return nil
 }
 
+func (dao *postgresBookDAO) MergeAuthors(authorID, variantID int) error {
+	return mergeAuthors(dao.db, authorID, variantID)
+}
+
 func (dao *postgresBookDAO) LikesCount(bookID int) (int, error) {
 	var count int
 	if err := dao.db.QueryRow("SELECT COUNT(*) FROM book_likes WHERE book_id = $1", bookID).Scan(&count); err != nil {
@@ -282,12 +349,84 @@ func (dao *postgresBookDAO) Ping() error {
 	return dao.db.Ping()
 }
 
+func (dao *postgresBookDAO) PurchaseWishListBook(wishListBookID int, bookInfo book.BookInfo) (int, error) {
+	return purchaseWishListBook(dao.db, wishListBookID, bookInfo)
+}
+
+func (dao *postgresBookDAO) RelocateBooks(shelfID int, bookIDs []int) error {
+	return relocateBooks(dao.db, shelfID, bookIDs)
+}
+
 func (dao *postgresBookDAO) RemoveImage(imageID int) error {
-	if _, err := dao.db.Exec("DELETE FROM book_images WHERE image_id=$1", imageID); err != nil {
+	return removeImage(dao.db, dao.imageStore, imageID)
+}
+
+func (dao *postgresBookDAO) ReorderImages(bookID int, imageIDs []int) error {
+	return reorderImages(dao.db, bookID, imageIDs)
+}
+
+func (dao *postgresBookDAO) ReserveWishListBook(reservation book.WishListReservation) error {
+	return reserveWishListBook(dao.db, reservation)
+}
+
+func (dao *postgresBookDAO) RestoreImage(image book.BookImage) error {
+	if err := restoreImage(dao.db, dao.imageStore, image); err != nil {
 		return err
 	}
 
-	return nil
+	return dao.resetSequence("book_images", "image_id")
+}
+
+// resetSequence moves the SERIAL sequence of the column past the highest ID, rows inserted with an
+// explicit ID do not advance it.
+func (dao *postgresBookDAO) resetSequence(table, column string) error {
+	_, err := dao.db.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s`, table, column, column, table))
+
+	return err
+}
+
+func (dao *postgresBookDAO) ReturnLoan(loanID int, returnedOn time.Time) error {
+	return returnLoan(dao.db, loanID, returnedOn)
+}
+
+func (dao *postgresBookDAO) SaveImageVariant(imageID int, variant imaging.Variant, data []byte) error {
+	return saveImageVariant(dao.db, imageID, variant, data)
+}
+
+func (dao *postgresBookDAO) SaveReview(review book.Review) error {
+	return saveReview(dao.db, review)
+}
+
+func (dao *postgresBookDAO) SearchQuotes(search string) ([]book.Quote, error) {
+	return searchQuotes(dao.db, search)
+}
+
+func (dao *postgresBookDAO) SetAcquisition(bookID int, acquisition book.Acquisition) error {
+	return setAcquisition(dao.db, bookID, acquisition)
+}
+
+func (dao *postgresBookDAO) SetBookLocation(bookID int, location book.Location) error {
+	return setBookLocation(dao.db, bookID, location)
+}
+
+func (dao *postgresBookDAO) SetCategory(bookID int, category book.Category) error {
+	return setCategory(dao.db, bookID, category)
+}
+
+func (dao *postgresBookDAO) SetContributors(bookID int, contributors []book.Contributor) error {
+	return setContributors(dao.db, bookID, contributors)
+}
+
+func (dao *postgresBookDAO) SetCoverImage(bookID, imageID int) error {
+	return setCoverImage(dao.db, bookID, imageID)
+}
+
+func (dao *postgresBookDAO) SetReading(bookID int, status book.ReadingStatus, pages int, readings []book.Reading) error {
+	return setReading(dao.db, bookID, status, pages, readings)
+}
+
+func (dao *postgresBookDAO) SetTags(bookID int, tags []string) error {
+	return setTags(dao.db, bookID, tags)
 }
 
 func (dao *postgresBookDAO) UnlikeBook(bookID, userID string) error {
@@ -298,10 +437,42 @@ func (dao *postgresBookDAO) UnlikeBook(bookID, userID string) error {
 	return nil
 }
 
-func (dao *postgresBookDAO) UpdateBook(title string, author string, description string, read bool, goodreadsLink string, id int) error {
-	return updateBook(title, author, description, read, goodreadsLink, id, dao.db)
+func (dao *postgresBookDAO) UpdateAuthor(author book.Author) error {
+	return updateAuthor(dao.db, author)
+}
+
+func (dao *postgresBookDAO) UpdateBook(title string, author string, description string, read bool, goodreadsLink string, isbn string, id int) error {
+	return updateBook(title, author, description, read, goodreadsLink, isbn, id, dao.db)
+}
+
+func (dao *postgresBookDAO) UpdateEdition(bookID, workID int, edition book.Edition) error {
+	return updateEdition(dao.db, bookID, workID, edition)
+}
+
+func (dao *postgresBookDAO) UpdateLoan(loan book.Loan) error {
+	return updateLoan(dao.db, loan)
+}
+
+func (dao *postgresBookDAO) UpdateQuote(quote book.Quote) error {
+	return updateQuote(dao.db, quote)
+}
+
+func (dao *postgresBookDAO) UpdateShelf(shelf book.Shelf) error {
+	return updateShelf(dao.db, shelf)
+}
+
+func (dao *postgresBookDAO) UpdateWishListBook(wishListBook book.WishListBook) error {
+	return updateWishListBook(dao.db, wishListBook)
+}
+
+func (dao *postgresBookDAO) GetWishListBookByID(id int) (book.WishListBook, error) {
+	return getWishListBookByID(dao.db, id)
+}
+
+func (dao *postgresBookDAO) GetWishListBooks(userID string) ([]book.WishListBook, error) {
+	return getWishListBooks(dao.db, userID)
 }
 
-func (dao *postgresBookDAO) GetWishListBooks() ([]book.WishListBook, error) {
-	return dao.wishListBooks, nil
+func (dao *postgresBookDAO) GetWishListReservations(ownerID string) ([]book.WishListReservation, error) {
+	return getWishListReservations(dao.db, ownerID)
 }
@@ -2,7 +2,10 @@ package dao
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/BurntSushi/toml"
+	"leonlib/internal/imagestore"
+	"leonlib/internal/imaging"
 	book "leonlib/internal/types"
 	user "leonlib/internal/types"
 	"log"
@@ -11,7 +14,7 @@ import (
 	"time"
 )
 
-func addBooksToDatabase(db *sql.DB, dao *DAO) error {
+func addBooksToDatabase(bookDAO DAO) error {
 	libraryDir := "library"
 	libraryDirPath := filepath.Join(libraryDir, "books_db.toml")
 
@@ -23,52 +26,88 @@ func addBooksToDatabase(db *sql.DB, dao *DAO) error {
 
 	startTime := time.Now()
 
-	for _, book := range library.Book {
-		log.Printf("Reading: (%s)", book)
-		bookInfo, err := (*dao).GetBookByID(book.ID)
-		if err == nil && bookInfo.ID == book.ID {
-			log.Printf("Book with ID: %d already exists, skipping", book.ID)
-			continue
-		}
+	if err := AddLibrary(bookDAO, library); err != nil {
+		return err
+	}
 
-		var bookID int
-		stmt, err := db.Prepare("INSERT INTO books(id, title, author, description, read, added_on, goodreads_link) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id")
-		if err != nil {
-			return err
-		}
+	elapsedTime := time.Since(startTime)
 
-		err = stmt.QueryRow(book.ID, book.Title, book.Author, book.Description, book.HasBeenRead, book.AddedOn, book.GoodreadsLink).Scan(&bookID)
-		if err != nil {
-			return err
+	log.Printf("Books loaded in: %.2f seconds\n", elapsedTime.Seconds())
+
+	return nil
+}
+
+// addWishListToDatabase loads the wish list of library/ into the database.
+func addWishListToDatabase(bookDAO DAO) error {
+	wishListBooks, err := readWishListBooks()
+	if err != nil {
+		return err
+	}
+
+	for _, wishListBook := range wishListBooks {
+		if _, err := bookDAO.CreateWishListBook(wishListBook); err != nil {
+			return fmt.Errorf("wish list book %d: %v", wishListBook.ID, err)
 		}
+	}
 
-		err = addImagesToBook(book.ID, &book.ImageNames, db)
-		if err != nil {
-			return err
+	return nil
+}
+
+// AddWishList loads the wish list of library/ into a database whose wish list is empty, one that
+// already has books keeps them as they are.
+func AddWishList(bookDAO DAO) error {
+	wishListBooks, err := bookDAO.GetWishListBooks("")
+	if err != nil {
+		return err
+	}
+
+	if len(wishListBooks) > 0 {
+		return nil
+	}
+
+	return addWishListToDatabase(bookDAO)
+}
+
+// sqliteTableExists tells if the database already has the table.
+func sqliteTableExists(db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=$1`, table).Scan(&count)
+
+	return count > 0, err
+}
+
+// AddLibrary adds the authors, the shelves, the works and the books of a library file, the ones
+// that already exist are left as they are.
+func AddLibrary(bookDAO DAO, library book.Library) error {
+	for _, author := range library.Author {
+		if _, err := bookDAO.CreateAuthor(author); err != nil {
+			return fmt.Errorf("author %d: %v", author.ID, err)
 		}
 	}
 
-	elapsedTime := time.Since(startTime)
+	for _, shelf := range library.Shelf {
+		if _, err := bookDAO.CreateShelf(shelf); err != nil {
+			return fmt.Errorf("shelf %d: %v", shelf.ID, err)
+		}
+	}
 
-	log.Printf("Books loaded in: %.2f seconds\n", elapsedTime.Seconds())
+	for _, work := range library.Work {
+		if _, err := bookDAO.CreateWork(work); err != nil {
+			return fmt.Errorf("work %d: %v", work.ID, err)
+		}
+	}
 
-	return nil
+	return bookDAO.AddAll(library.Book)
 }
 
-func addImagesToBook(id int, imageNames *[]string, db *sql.DB) error {
+func addImagesToBook(id int, imageNames *[]string, db *sql.DB, imageStore imagestore.Store) error {
 	for _, imageName := range *imageNames {
 		imgBytes, err := os.ReadFile(filepath.Join("images", imageName))
 		if err != nil {
 			return err
 		}
 
-		imgStmt, err := db.Prepare("INSERT INTO book_images(book_id, image) VALUES($1, $2)")
-		if err != nil {
-			return err
-		}
-
-		_, err = imgStmt.Exec(id, imgBytes)
-		if err != nil {
+		if err := addImageToBook(id, imgBytes, db, imageStore); err != nil {
 			return err
 		}
 	}
@@ -76,8 +115,34 @@ func addImagesToBook(id int, imageNames *[]string, db *sql.DB) error {
 	return nil
 }
 
-func createDB(db *sql.DB) error {
+// bookImagesTable is the layout of book_images since the images moved to the image store, the
+// rows only reference the hash of their image.
+const bookImagesTable = `CREATE TABLE IF NOT EXISTS %s (
+			image_id INTEGER PRIMARY KEY AUTOINCREMENT,
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			image_hash TEXT NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0,
+			is_cover BOOLEAN NOT NULL DEFAULT FALSE,
+			added_on TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`
+
+// imagePositionsByID numbers the images of every book in the order they were added, for tables
+// that had no position yet.
+const imagePositionsByID = `(SELECT count(*) FROM book_images o WHERE o.book_id = book_images.book_id AND o.image_id < book_images.image_id)`
+
+func createDB(db *sql.DB, imageStore imagestore.Store) error {
 	sqlCommands := []string{
+		`CREATE TABLE IF NOT EXISTS works (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			author TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS shelves (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			room TEXT NOT NULL DEFAULT '',
+			bookcase TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL
+		)`,
 		`CREATE TABLE IF NOT EXISTS books (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			title TEXT NOT NULL,
@@ -85,12 +150,97 @@ func createDB(db *sql.DB) error {
 			description TEXT,
 			read BOOLEAN DEFAULT FALSE,
 			added_on TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			goodreads_link TEXT
+			goodreads_link TEXT,
+			isbn TEXT,
+			work_id INTEGER REFERENCES works(id),
+			publisher TEXT,
+			published_year INTEGER,
+			language TEXT,
+			format TEXT,
+			category TEXT,
+			shelf_id INTEGER REFERENCES shelves(id),
+			shelf_position INTEGER,
+			reading_status TEXT NOT NULL DEFAULT '',
+			pages INTEGER,
+			purchased_on DATE,
+			purchase_price INTEGER,
+			currency TEXT NOT NULL DEFAULT '',
+			purchased_from TEXT NOT NULL DEFAULT '',
+			book_condition TEXT NOT NULL DEFAULT '',
+			estimated_value INTEGER
 		)`,
-		`CREATE TABLE IF NOT EXISTS book_images (
-			image_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		`CREATE TABLE IF NOT EXISTS book_readings (
 			book_id INTEGER NOT NULL REFERENCES books(id),
-			image BLOB NOT NULL,
+			position INTEGER NOT NULL,
+			started_on DATE,
+			finished_on DATE,
+			current_page INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (book_id, position)
+		)`,
+		`CREATE TABLE IF NOT EXISTS loans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			borrower TEXT NOT NULL,
+			loaned_on DATE NOT NULL,
+			due_on DATE,
+			returned_on DATE,
+			notes TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS quotes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			quote TEXT NOT NULL,
+			page INTEGER,
+			added_on DATE NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS quote_tags (
+			quote_id INTEGER NOT NULL REFERENCES quotes(id),
+			tag TEXT NOT NULL,
+			PRIMARY KEY (quote_id, tag)
+		)`,
+		`CREATE TABLE IF NOT EXISTS wish_list_books (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			author TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			image_link TEXT NOT NULL DEFAULT '',
+			goodreads_link TEXT NOT NULL DEFAULT '',
+			priority INTEGER NOT NULL DEFAULT 2,
+			user_id TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS wish_list_reservations (
+			wish_list_book_id INTEGER PRIMARY KEY REFERENCES wish_list_books(id),
+			user_id TEXT NOT NULL REFERENCES users(user_id),
+			reserved_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS book_tags (
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			tag TEXT NOT NULL,
+			PRIMARY KEY (book_id, tag)
+		)`,
+		`CREATE TABLE IF NOT EXISTS authors (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			sort_name TEXT NOT NULL,
+			bio TEXT,
+			photo_url TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS author_aliases (
+			alias TEXT PRIMARY KEY,
+			author_id INTEGER NOT NULL REFERENCES authors(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS book_contributors (
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			position INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			role TEXT NOT NULL,
+			author_id INTEGER REFERENCES authors(id),
+			PRIMARY KEY (book_id, position)
+		)`,
+		fmt.Sprintf(bookImagesTable, "book_images"),
+		`CREATE TABLE IF NOT EXISTS image_blobs (
+			hash TEXT PRIMARY KEY,
+			data BLOB NOT NULL,
 			added_on TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
 		`CREATE TABLE IF NOT EXISTS users (
@@ -106,10 +256,30 @@ func createDB(db *sql.DB) error {
 			user_id TEXT REFERENCES users(user_id),
 			UNIQUE(book_id, user_id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS book_reviews (
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			user_id TEXT NOT NULL REFERENCES users(user_id),
+			rating INTEGER NOT NULL DEFAULT 0,
+			review TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			updated_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (book_id, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS book_image_variants (
+			image_id INTEGER NOT NULL REFERENCES book_images(image_id),
+			variant TEXT NOT NULL,
+			image BLOB NOT NULL,
+			PRIMARY KEY (image_id, variant)
+		)`,
 		`CREATE INDEX IF NOT EXISTS idx_books_title ON books (title)`,
 		`CREATE INDEX IF NOT EXISTS idx_books_author ON books (author)`,
 		`CREATE INDEX IF NOT EXISTS idx_books_added_on ON books (added_on)`,
 		`CREATE INDEX IF NOT EXISTS idx_book_images_book_id ON book_images (book_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_book_contributors_name ON book_contributors (name)`,
+		`CREATE INDEX IF NOT EXISTS idx_author_aliases_author_id ON author_aliases (author_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_book_reviews_user_id ON book_reviews (user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_quotes_book_id ON quotes (book_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_quote_tags_tag ON quote_tags (tag)`,
 	}
 
 	for _, sqlCommand := range sqlCommands {
@@ -120,143 +290,471 @@ func createDB(db *sql.DB) error {
 		log.Printf("SQL command: (%.35s...) executed correctly", sqlCommand)
 	}
 
+	if err := moveImagesToStore(db, imageStore); err != nil {
+		return fmt.Errorf("moving the images to the image store: %v", err)
+	}
+
+	if err := addImageOrderColumns(db); err != nil {
+		return fmt.Errorf("adding the order of the images: %v", err)
+	}
+
+	if err := addBookColumns(db); err != nil {
+		return fmt.Errorf("adding the new columns of the books: %v", err)
+	}
+
+	// The books that were only marked as read are read.
+	if _, err := db.Exec(`UPDATE books SET reading_status = 'read'
+		WHERE read AND reading_status = '' AND NOT EXISTS (SELECT 1 FROM book_readings WHERE book_id = books.id)`); err != nil {
+		return fmt.Errorf("adding the reading status of the books: %v", err)
+	}
+
+	if err := addContributorAuthors(db); err != nil {
+		return fmt.Errorf("adding the authors of the contributors: %v", err)
+	}
+
+	if err := addWishListOwners(db); err != nil {
+		return fmt.Errorf("adding the owners of the wish lists: %v", err)
+	}
+
+	if err := addBookContributors(db); err != nil {
+		return fmt.Errorf("adding the contributors of the books: %v", err)
+	}
+
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_books_isbn ON books (isbn)`,
+		`CREATE INDEX IF NOT EXISTS idx_books_work_id ON books (work_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_book_contributors_author_id ON book_contributors (author_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_book_tags_tag ON book_tags (tag)`,
+		`CREATE INDEX IF NOT EXISTS idx_books_category ON books (category)`,
+		`CREATE INDEX IF NOT EXISTS idx_books_shelf_id ON books (shelf_id, shelf_position)`,
+		`CREATE INDEX IF NOT EXISTS idx_loans_book_id ON loans (book_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_wish_list_books_user_id ON wish_list_books (user_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_loans_lent ON loans (book_id) WHERE returned_on IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_book_images_image_hash ON book_images (image_hash)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_book_images_cover ON book_images (book_id) WHERE is_cover`,
+	}
+	for _, index := range indexes {
+		if _, err := db.Exec(index); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (dao *sqliteBookDAO) AddAll(books []book.BookInfo) error {
-	for _, book := range books {
-		log.Printf("Reading: (%s)", book)
-		bookInfo, err := dao.GetBookByID(book.ID)
-		if err == nil && bookInfo.ID == book.ID {
-			log.Printf("Book with ID: %d already exists, skipping", book.ID)
-			continue
-		}
+// addImageOrderColumns adds position and is_cover to a book_images table created without them.
+func addImageOrderColumns(db *sql.DB) error {
+	var exists bool
+	err := db.QueryRow(`SELECT count(*) > 0 FROM pragma_table_info('book_images') WHERE name = 'position'`).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
 
-		var bookID int
-		stmt, err := dao.db.Prepare("INSERT INTO books(id, title, author, description, read, added_on, goodreads_link) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id")
-		if err != nil {
+	commands := []string{
+		`ALTER TABLE book_images ADD COLUMN position INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE book_images ADD COLUMN is_cover BOOLEAN NOT NULL DEFAULT FALSE`,
+		`UPDATE book_images SET position = ` + imagePositionsByID,
+	}
+	for _, command := range commands {
+		if _, err := db.Exec(command); err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+// bookColumnsAdded are the columns of books that came after the table, in the order they did.
+var bookColumnsAdded = []struct{ name, definition string }{
+	{"isbn", "TEXT"},
+	{"work_id", "INTEGER REFERENCES works(id)"},
+	{"publisher", "TEXT"},
+	{"published_year", "INTEGER"},
+	{"language", "TEXT"},
+	{"format", "TEXT"},
+	{"category", "TEXT"},
+	{"shelf_id", "INTEGER REFERENCES shelves(id)"},
+	{"shelf_position", "INTEGER"},
+	{"reading_status", "TEXT NOT NULL DEFAULT ''"},
+	{"pages", "INTEGER"},
+	{"purchased_on", "DATE"},
+	{"purchase_price", "INTEGER"},
+	{"currency", "TEXT NOT NULL DEFAULT ''"},
+	{"purchased_from", "TEXT NOT NULL DEFAULT ''"},
+	{"book_condition", "TEXT NOT NULL DEFAULT ''"},
+	{"estimated_value", "INTEGER"},
+}
 
-		err = stmt.QueryRow(book.ID, book.Title, book.Author, book.Description, book.HasBeenRead, book.AddedOn, book.GoodreadsLink).Scan(&bookID)
+// addBookColumns adds to a books table created before them the columns it lacks.
+func addBookColumns(db *sql.DB) error {
+	for _, column := range bookColumnsAdded {
+		var exists bool
+		err := db.QueryRow(`SELECT count(*) > 0 FROM pragma_table_info('books') WHERE name = $1`, column.name).Scan(&exists)
 		if err != nil {
 			return err
 		}
 
-		for _, imageName := range book.ImageNames {
-			imgBytes, err := os.ReadFile(filepath.Join("images", imageName))
-			if err != nil {
-				return err
-			}
-
-			imgStmt, err := dao.db.Prepare("INSERT INTO book_images(book_id, image) VALUES($1, $2)")
-			if err != nil {
-				return err
-			}
-
-			_, err = imgStmt.Exec(bookID, imgBytes)
-			if err != nil {
-				return err
-			}
+		if exists {
+			continue
+		}
+
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE books ADD COLUMN %s %s`, column.name, column.definition)); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (dao *sqliteBookDAO) AddImageToBook(bookID int, imageData []byte) error {
-	return addImageToBook(bookID, imageData, dao.db)
-}
+// addWishListOwners adds user_id to a wish_list_books table created when there was only the wish
+// list of the library.
+func addWishListOwners(db *sql.DB) error {
+	var exists bool
+	err := db.QueryRow(`SELECT count(*) > 0 FROM pragma_table_info('wish_list_books') WHERE name = 'user_id'`).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
 
-func (dao *sqliteBookDAO) AddUser(userID, email, name, oauthIdentifier string) error {
-	return addUser(dao.db, userID, email, name, oauthIdentifier)
-}
+	_, err = db.Exec(`ALTER TABLE wish_list_books ADD COLUMN user_id TEXT NOT NULL DEFAULT ''`)
 
-func (dao *sqliteBookDAO) Close() error {
-	return dao.db.Close()
+	return err
 }
 
-func (dao *sqliteBookDAO) CreateBook(book book.BookInfo) error {
-	stmt, err := dao.db.Prepare("INSERT INTO books (title, author, description, read, goodreads_link) VALUES ($1, $2, $3, $4, $5)")
+// addContributorAuthors adds author_id to a book_contributors table created without it, and gives
+// the contributors without an author the one of their name.
+func addContributorAuthors(db *sql.DB) error {
+	var exists bool
+	err := db.QueryRow(`SELECT count(*) > 0 FROM pragma_table_info('book_contributors') WHERE name = 'author_id'`).Scan(&exists)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
-	insertedBookIDResult, err := stmt.Exec(book.Title, book.Author, book.Description, book.HasBeenRead, book.GoodreadsLink)
+	if !exists {
+		if _, err := db.Exec(`ALTER TABLE book_contributors ADD COLUMN author_id INTEGER REFERENCES authors(id)`); err != nil {
+			return err
+		}
+	}
+
+	rows, err := db.Query(`SELECT DISTINCT name FROM book_contributors WHERE author_id IS NULL ORDER BY name`)
 	if err != nil {
 		return err
 	}
 
-	insertedBookID, err := insertedBookIDResult.LastInsertId()
-	if err != nil {
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil || len(names) == 0 {
 		return err
 	}
 
-	imgStmt, err := dao.db.Prepare("INSERT INTO book_images(book_id, image) VALUES($1, $2)")
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, name := range names {
+		authorID, err := resolveAuthorID(tx, book.Contributor{Name: name})
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		if _, err := tx.Exec(`UPDATE book_contributors SET author_id=$1 WHERE author_id IS NULL AND name=$2`, authorID, name); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("authors given to %d contributor names", len(names))
 
-	_, err = imgStmt.Exec(insertedBookID, book.Image)
+	return tx.Commit()
+}
+
+// addBookContributors gives the books without contributors the ones of their author.
+func addBookContributors(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, author FROM books
+		WHERE id NOT IN (SELECT book_id FROM book_contributors) ORDER BY id`)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	var books []book.BookInfo
+	for rows.Next() {
+		var bookInfo book.BookInfo
+		if err := rows.Scan(&bookInfo.ID, &bookInfo.Author); err != nil {
+			rows.Close()
+			return err
+		}
 
-func (dao *sqliteBookDAO) GetAllAuthors() ([]string, error) {
-	return getAllAuthors(dao.db)
+		bookInfo.NormalizeContributors()
+		books = append(books, bookInfo)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, bookInfo := range books {
+		if len(bookInfo.Contributors) == 0 {
+			continue
+		}
+
+		if err := setContributors(db, bookInfo.ID, bookInfo.Contributors); err != nil {
+			return fmt.Errorf("book %d: %v", bookInfo.ID, err)
+		}
+	}
+
+	if len(books) > 0 {
+		log.Printf("contributors added to %d books", len(books))
+	}
+
+	return nil
 }
 
-func (dao *sqliteBookDAO) GetBookByID(id int) (book.BookInfo, error) {
-	var err error
-	var queryStr = `SELECT b.id, b.title, b.author, b.description, b.read, b.added_on, b.goodreads_link FROM books b WHERE b.id=$1`
+// moveImagesToStore upgrades a database created before the image store, whose book_images rows
+// held the image bytes: every image is put in the store and the table is rebuilt around their
+// hashes. Rows without an image are dropped.
+func moveImagesToStore(db *sql.DB, imageStore imagestore.Store) error {
+	var legacy bool
+	err := db.QueryRow(`SELECT count(*) > 0 FROM pragma_table_info('book_images') WHERE name = 'image'`).Scan(&legacy)
+	if err != nil || !legacy {
+		return err
+	}
 
-	bookRows, err := dao.db.Query(queryStr, id)
+	rows, err := db.Query(`SELECT image_id FROM book_images WHERE length(image) > 0 ORDER BY image_id`)
 	if err != nil {
-		return book.BookInfo{}, err
+		return err
+	}
+
+	var imageIDs []int
+	for rows.Next() {
+		var imageID int
+		if err := rows.Scan(&imageID); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		imageIDs = append(imageIDs, imageID)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	log.Printf("Moving %d images to the image store", len(imageIDs))
+
+	// One image at a time: the store may write to this same database.
+	hashes := make(map[int]string, len(imageIDs))
+	for _, imageID := range imageIDs {
+		var data []byte
+		if err := db.QueryRow(`SELECT image FROM book_images WHERE image_id=$1`, imageID).Scan(&data); err != nil {
+			return err
+		}
+
+		if hashes[imageID], err = imageStore.Put(data); err != nil {
+			return fmt.Errorf("image %d: %v", imageID, err)
+		}
 	}
 
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
 	defer func() {
-		_ = bookRows.Close()
+		_ = tx.Rollback()
 	}()
 
-	var bookInfo book.BookInfo
-	var bookID int
-	var title string
-	var author string
-	var description string
-	var hasBeenRead bool
-	var addedOn time.Time
-	var goodreadsLink sql.NullString
-	if bookRows.Next() {
-		if err := bookRows.Scan(&bookID, &title, &author, &description, &hasBeenRead, &addedOn, &goodreadsLink); err != nil {
-			return book.BookInfo{}, err
+	commands := []string{
+		fmt.Sprintf(bookImagesTable, "book_images_new"),
+		`INSERT INTO book_images_new(image_id, book_id, image_hash, position, added_on)
+			SELECT image_id, book_id, '', ` + imagePositionsByID + `, added_on FROM book_images WHERE length(image) > 0`,
+	}
+	for _, command := range commands {
+		if _, err := tx.Exec(command); err != nil {
+			return err
 		}
+	}
 
-		bookInfo.ID = bookID
-		bookInfo.Title = title
-		bookInfo.Author = author
-		bookInfo.Description = description
-		bookInfo.HasBeenRead = hasBeenRead
-		bookInfo.AddedOn = addedOn.Format("2006-01-02")
-		if goodreadsLink.Valid {
-			bookInfo.GoodreadsLink = goodreadsLink.String
-		} else {
-			bookInfo.GoodreadsLink = ""
+	for imageID, hash := range hashes {
+		if _, err := tx.Exec(`UPDATE book_images_new SET image_hash=$1 WHERE image_id=$2`, hash, imageID); err != nil {
+			return err
 		}
 	}
 
-	bookImages, err := dao.GetImagesByBookID(id)
-	if err != nil {
-		return book.BookInfo{}, err
+	commands = []string{
+		`DROP TABLE book_images`,
+		`ALTER TABLE book_images_new RENAME TO book_images`,
+		`CREATE INDEX IF NOT EXISTS idx_book_images_book_id ON book_images (book_id)`,
+	}
+	for _, command := range commands {
+		if _, err := tx.Exec(command); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// The old table took the space of every image, give it back.
+	_, err = db.Exec(`VACUUM`)
+
+	return err
+}
+
+func (dao *sqliteBookDAO) AddAll(books []book.BookInfo) error {
+	for _, bookInfo := range books {
+		log.Printf("Reading: (%s)", bookInfo)
+		existing, err := dao.GetBookByID(bookInfo.ID)
+		if err == nil && existing.ID == bookInfo.ID {
+			log.Printf("Book with ID: %d already exists, skipping", bookInfo.ID)
+			continue
+		}
+
+		bookID, err := createBook(dao.db, dao.imageStore, bookInfo)
+		if err != nil {
+			return err
+		}
+
+		if err := addImagesToBook(bookID, &bookInfo.ImageNames, dao.db, dao.imageStore); err != nil {
+			return err
+		}
 	}
 
-	bookInfo.Base64Images = bookImages
+	return nil
+}
+
+func (dao *sqliteBookDAO) AddImageToBook(bookID int, imageData []byte) error {
+	return addImageToBook(bookID, imageData, dao.db, dao.imageStore)
+}
+
+func (dao *sqliteBookDAO) AddLike(like book.BookLike) error {
+	return addLike(dao.db, like)
+}
+
+func (dao *sqliteBookDAO) AddUser(userID, email, name, oauthIdentifier string) error {
+	return addUser(dao.db, userID, email, name, oauthIdentifier)
+}
 
-	return bookInfo, nil
+func (dao *sqliteBookDAO) CancelWishListReservation(wishListBookID int, userID string) error {
+	return cancelWishListReservation(dao.db, wishListBookID, userID)
+}
+
+func (dao *sqliteBookDAO) Close() error {
+	return dao.db.Close()
+}
+
+func (dao *sqliteBookDAO) CollectImageGarbage() (int, error) {
+	return collectImageGarbage(dao.db, dao.imageStore)
+}
+
+func (dao *sqliteBookDAO) CreateBook(book book.BookInfo) (int, error) {
+	return createBook(dao.db, dao.imageStore, book)
+}
+
+func (dao *sqliteBookDAO) CreateAuthor(author book.Author) (int, error) {
+	return createAuthor(dao.db, author)
+}
+
+func (dao *sqliteBookDAO) CreateLoan(loan book.Loan) (int, error) {
+	return createLoan(dao.db, loan)
+}
+
+func (dao *sqliteBookDAO) CreateQuote(quote book.Quote) (int, error) {
+	return createQuote(dao.db, quote)
+}
+
+func (dao *sqliteBookDAO) CreateShelf(shelf book.Shelf) (int, error) {
+	return createShelf(dao.db, shelf)
+}
+
+func (dao *sqliteBookDAO) CreateWishListBook(wishListBook book.WishListBook) (int, error) {
+	return createWishListBook(dao.db, wishListBook)
+}
+
+func (dao *sqliteBookDAO) CreateWork(work book.Work) (int, error) {
+	return createWork(dao.db, work)
+}
+
+func (dao *sqliteBookDAO) DeleteShelf(id int) error {
+	return deleteShelf(dao.db, id)
+}
+
+func (dao *sqliteBookDAO) DeleteWishListBook(id int) error {
+	return deleteWishListBook(dao.db, id)
+}
+
+func (dao *sqliteBookDAO) DeleteQuote(id int) error {
+	return deleteQuote(dao.db, id)
+}
+
+func (dao *sqliteBookDAO) DeleteReview(bookID int, userID string) error {
+	return deleteReview(dao.db, bookID, userID)
+}
+
+func (dao *sqliteBookDAO) ForEachImage(fn func(image book.BookImage) error) error {
+	return forEachImage(dao.db, dao.imageStore, fn)
+}
+
+func (dao *sqliteBookDAO) GetAllAuthors() ([]book.Author, error) {
+	return getAllAuthors(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllBooks() ([]book.BookInfo, error) {
+	return getAllBooks(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllCategories() ([]book.CategoryCount, error) {
+	return getAllCategories(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllLikes() ([]book.BookLike, error) {
+	return getAllLikes(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllLoans() ([]book.Loan, error) {
+	return getAllLoans(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllQuotes() ([]book.Quote, error) {
+	return getAllQuotes(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllReviews() ([]book.Review, error) {
+	return getAllReviews(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllShelves() ([]book.Shelf, error) {
+	return getAllShelves(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllTags() ([]book.Tag, error) {
+	return getAllTags(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetAllUsers() ([]user.User, error) {
+	return getAllUsers(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetBookByID(id int) (book.BookInfo, error) {
+	return getBookByID(dao.db, id)
+}
+
+func (dao *sqliteBookDAO) GetAllWorks() ([]book.Work, error) {
+	return getAllWorks(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetBookIDsByImageHash(hash string) ([]int, error) {
+	return getBookIDsByImageHash(dao.db, hash)
 }
 
 func (dao *sqliteBookDAO) GetBookCount() (int, error) {
@@ -268,56 +766,73 @@ func (dao *sqliteBookDAO) GetBooksWithPagination(offset, limit int) ([]book.Book
 }
 
 func (dao *sqliteBookDAO) GetBooksBySearchTypeCoincidence(titleSearchText string, bookSearchType book.BookSearchType) ([]book.BookInfo, error) {
-	var err error
-	queryStr := `SELECT b.id, b.title, b.author, b.description, b.read, b.added_on, b.goodreads_link FROM books b WHERE LOWER(b.title) LIKE '%' || LOWER($1) || '%' ORDER BY b.title`
+	return getBooksBySearchTypeCoincidence(dao.db, titleSearchText, bookSearchType)
+}
 
-	if bookSearchType == book.ByAuthor {
-		queryStr = `SELECT b.id, b.title, b.author, b.description, b.read, b.added_on, b.goodreads_link FROM books b WHERE LOWER(b.author) LIKE '%' || LOWER($1) || '%' ORDER BY b.title`
-	}
+func (dao *sqliteBookDAO) GetAuthorByID(id int) (book.Author, error) {
+	return getAuthorByID(dao.db, id)
+}
 
-	booksByTitleRows, err := dao.db.Query(queryStr, "%"+titleSearchText+"%")
-	if err != nil {
-		return []book.BookInfo{}, err
-	}
+func (dao *sqliteBookDAO) GetBooksByAuthorID(authorID int) ([]book.BookInfo, error) {
+	return getBooksByAuthorID(dao.db, authorID)
+}
 
-	defer booksByTitleRows.Close()
+func (dao *sqliteBookDAO) GetBooksByShelf(shelfID int) ([]book.BookInfo, error) {
+	return getBooksByShelf(dao.db, shelfID)
+}
 
-	var books []book.BookInfo
-	var id int
-	var title string
-	var author string
-	var description string
-	var hasBeenRead bool
-	var addedOn time.Time
-	var goodreadsLink string
-	for booksByTitleRows.Next() {
-		var bookInfo book.BookInfo
-		if err := booksByTitleRows.Scan(&id, &title, &author, &description, &hasBeenRead, &addedOn, &goodreadsLink); err != nil {
-			return []book.BookInfo{}, err
-		}
+func (dao *sqliteBookDAO) GetBooksByISBN(isbn string) ([]book.BookInfo, error) {
+	return getBooksByISBN(dao.db, isbn)
+}
 
-		bookInfo.ID = id
-		bookInfo.Title = title
-		bookInfo.Author = author
-		bookImages, err := dao.GetImagesByBookID(id)
-		if err != nil {
-			return []book.BookInfo{}, err
-		}
+func (dao *sqliteBookDAO) GetEditions(workID int) ([]book.BookInfo, error) {
+	return getEditions(dao.db, workID)
+}
 
-		bookInfo.Base64Images = bookImages
-		bookInfo.Description = description
-		bookInfo.HasBeenRead = hasBeenRead
-		bookInfo.AddedOn = addedOn.Format("2006-01-02")
-		books = append(books, bookInfo)
-	}
+func (dao *sqliteBookDAO) GetImage(imageID int) (book.BookImage, error) {
+	return getImage(dao.db, dao.imageStore, imageID)
+}
 
-	return books, nil
+func (dao *sqliteBookDAO) GetImageVariant(imageID int, variant imaging.Variant) (book.BookImage, error) {
+	return getImageVariant(dao.db, imageID, variant)
 }
 
 func (dao *sqliteBookDAO) GetImagesByBookID(bookID int) ([]book.BookImageInfo, error) {
 	return getImagesByBookID(bookID, dao.db)
 }
 
+func (dao *sqliteBookDAO) GetLoansByBookID(bookID int) ([]book.Loan, error) {
+	return getLoansByBookID(dao.db, bookID)
+}
+
+func (dao *sqliteBookDAO) GetQuoteByID(id int) (book.Quote, error) {
+	return getQuoteByID(dao.db, id)
+}
+
+func (dao *sqliteBookDAO) GetQuotesByBookID(bookID int) ([]book.Quote, error) {
+	return getQuotesByBookID(dao.db, bookID)
+}
+
+func (dao *sqliteBookDAO) GetRandomQuote() (book.Quote, error) {
+	return getRandomQuote(dao.db)
+}
+
+func (dao *sqliteBookDAO) GetReview(bookID int, userID string) (book.Review, error) {
+	return getReview(dao.db, bookID, userID)
+}
+
+func (dao *sqliteBookDAO) GetReviewsByBookID(bookID int) ([]book.Review, error) {
+	return getReviewsByBookID(dao.db, bookID)
+}
+
+func (dao *sqliteBookDAO) GetReviewsByUserID(userID string) ([]book.Review, error) {
+	return getReviewsByUserID(dao.db, userID)
+}
+
+func (dao *sqliteBookDAO) GetShelfByID(id int) (book.Shelf, error) {
+	return getShelfByID(dao.db, id)
+}
+
 func (dao *sqliteBookDAO) GetUserInfoByID(id string) (user.UserInfo, error) {
 	var err error
 	var queryStr = `SELECT u.user_id, u.email, u.name FROM users u WHERE u.user_id=$1`
@@ -382,6 +897,10 @@ func (dao *sqliteBookDAO) LikeBook(bookID, userID string) error {
 	return nil
 }
 
+func (dao *sqliteBookDAO) MergeAuthors(authorID, variantID int) error {
+	return mergeAuthors(dao.db, authorID, variantID)
+}
+
 func (dao *sqliteBookDAO) LikesCount(bookID int) (int, error) {
 	var count int
 	if err := dao.db.QueryRow("SELECT COUNT(*) FROM book_likes WHERE book_id = $1", bookID).Scan(&count); err != nil {
@@ -395,12 +914,72 @@ func (dao *sqliteBookDAO) Ping() error {
 	return nil
 }
 
+func (dao *sqliteBookDAO) PurchaseWishListBook(wishListBookID int, bookInfo book.BookInfo) (int, error) {
+	return purchaseWishListBook(dao.db, wishListBookID, bookInfo)
+}
+
+func (dao *sqliteBookDAO) RelocateBooks(shelfID int, bookIDs []int) error {
+	return relocateBooks(dao.db, shelfID, bookIDs)
+}
+
 func (dao *sqliteBookDAO) RemoveImage(imageID int) error {
-	if _, err := dao.db.Exec("DELETE FROM book_images WHERE image_id=$1", imageID); err != nil {
-		return err
-	}
+	return removeImage(dao.db, dao.imageStore, imageID)
+}
 
-	return nil
+func (dao *sqliteBookDAO) ReorderImages(bookID int, imageIDs []int) error {
+	return reorderImages(dao.db, bookID, imageIDs)
+}
+
+func (dao *sqliteBookDAO) ReserveWishListBook(reservation book.WishListReservation) error {
+	return reserveWishListBook(dao.db, reservation)
+}
+
+func (dao *sqliteBookDAO) RestoreImage(image book.BookImage) error {
+	return restoreImage(dao.db, dao.imageStore, image)
+}
+
+func (dao *sqliteBookDAO) ReturnLoan(loanID int, returnedOn time.Time) error {
+	return returnLoan(dao.db, loanID, returnedOn)
+}
+
+func (dao *sqliteBookDAO) SaveImageVariant(imageID int, variant imaging.Variant, data []byte) error {
+	return saveImageVariant(dao.db, imageID, variant, data)
+}
+
+func (dao *sqliteBookDAO) SaveReview(review book.Review) error {
+	return saveReview(dao.db, review)
+}
+
+func (dao *sqliteBookDAO) SearchQuotes(search string) ([]book.Quote, error) {
+	return searchQuotes(dao.db, search)
+}
+
+func (dao *sqliteBookDAO) SetAcquisition(bookID int, acquisition book.Acquisition) error {
+	return setAcquisition(dao.db, bookID, acquisition)
+}
+
+func (dao *sqliteBookDAO) SetBookLocation(bookID int, location book.Location) error {
+	return setBookLocation(dao.db, bookID, location)
+}
+
+func (dao *sqliteBookDAO) SetCategory(bookID int, category book.Category) error {
+	return setCategory(dao.db, bookID, category)
+}
+
+func (dao *sqliteBookDAO) SetContributors(bookID int, contributors []book.Contributor) error {
+	return setContributors(dao.db, bookID, contributors)
+}
+
+func (dao *sqliteBookDAO) SetCoverImage(bookID, imageID int) error {
+	return setCoverImage(dao.db, bookID, imageID)
+}
+
+func (dao *sqliteBookDAO) SetReading(bookID int, status book.ReadingStatus, pages int, readings []book.Reading) error {
+	return setReading(dao.db, bookID, status, pages, readings)
+}
+
+func (dao *sqliteBookDAO) SetTags(bookID int, tags []string) error {
+	return setTags(dao.db, bookID, tags)
 }
 
 func (dao *sqliteBookDAO) UnlikeBook(bookID, userID string) error {
@@ -411,10 +990,42 @@ func (dao *sqliteBookDAO) UnlikeBook(bookID, userID string) error {
 	return nil
 }
 
-func (dao *sqliteBookDAO) UpdateBook(title string, author string, description string, read bool, goodreadsLink string, id int) error {
-	return updateBook(title, author, description, read, goodreadsLink, id, dao.db)
+func (dao *sqliteBookDAO) UpdateAuthor(author book.Author) error {
+	return updateAuthor(dao.db, author)
+}
+
+func (dao *sqliteBookDAO) UpdateBook(title string, author string, description string, read bool, goodreadsLink string, isbn string, id int) error {
+	return updateBook(title, author, description, read, goodreadsLink, isbn, id, dao.db)
+}
+
+func (dao *sqliteBookDAO) UpdateEdition(bookID, workID int, edition book.Edition) error {
+	return updateEdition(dao.db, bookID, workID, edition)
+}
+
+func (dao *sqliteBookDAO) UpdateLoan(loan book.Loan) error {
+	return updateLoan(dao.db, loan)
+}
+
+func (dao *sqliteBookDAO) UpdateQuote(quote book.Quote) error {
+	return updateQuote(dao.db, quote)
+}
+
+func (dao *sqliteBookDAO) UpdateShelf(shelf book.Shelf) error {
+	return updateShelf(dao.db, shelf)
+}
+
+func (dao *sqliteBookDAO) UpdateWishListBook(wishListBook book.WishListBook) error {
+	return updateWishListBook(dao.db, wishListBook)
+}
+
+func (dao *sqliteBookDAO) GetWishListBookByID(id int) (book.WishListBook, error) {
+	return getWishListBookByID(dao.db, id)
+}
+
+func (dao *sqliteBookDAO) GetWishListBooks(userID string) ([]book.WishListBook, error) {
+	return getWishListBooks(dao.db, userID)
 }
 
-func (dao *sqliteBookDAO) GetWishListBooks() ([]book.WishListBook, error) {
-	return dao.wishListBooks, nil
+func (dao *sqliteBookDAO) GetWishListReservations(ownerID string) ([]book.WishListReservation, error) {
+	return getWishListReservations(dao.db, ownerID)
 }
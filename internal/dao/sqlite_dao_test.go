@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// inLibraryDir runs the test from a directory whose library/wish_list.toml has the content.
+func inLibraryDir(t *testing.T, wishList string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "library"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "library", "wish_list.toml"), []byte(wishList), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestAddWishList(t *testing.T) {
+	inLibraryDir(t, `
+[[book]]
+id = 4
+title = "Crafting Interpreters"
+author = "Robert Nystrom"
+
+[[book]]
+id = 7
+title = "The Go Programming Language"
+author = "Alan Donovan"
+`)
+
+	bookDAO, err := OpenDAO("memory", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bookDAO.Close()
+
+	if err := AddWishList(bookDAO); err != nil {
+		t.Fatal(err)
+	}
+
+	wishListBooks, err := bookDAO.GetWishListBooks("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wishListBooks) != 2 {
+		t.Fatalf("the empty wish list got %+v, want the 2 books of library/", wishListBooks)
+	}
+
+	// A bought book does not come back while the list has others.
+	if err := bookDAO.DeleteWishListBook(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddWishList(bookDAO); err != nil {
+		t.Fatal(err)
+	}
+
+	wishListBooks, err = bookDAO.GetWishListBooks("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wishListBooks) != 1 || wishListBooks[0].ID != 7 {
+		t.Errorf("the wish list is %+v, want only book 7", wishListBooks)
+	}
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"leonlib/internal/captcha"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthorBook is a book of the author page with what the author did for it.
+type AuthorBook struct {
+	book.BookInfo
+	// Roles are the labels of the roles of the author in the book, when they are not only its author.
+	Roles []string
+	Likes int
+}
+
+type PageVariablesForAuthor struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Author       book.Author
+	Books        []AuthorBook
+	// ReadBooks and Likes are the stats of the books of the author.
+	ReadBooks int
+	Likes     int
+}
+
+type PageVariablesForAuthorsAdmin struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Authors      []book.Author
+	Variants     []catalog.AuthorVariant
+	// Unsorted are the authors without a sort name, whose surname could not be guessed.
+	Unsorted     []book.Author
+	Message      string
+	ErrorMessage string
+}
+
+func renderTemplate(w http.ResponseWriter, name string, pageVariables any) {
+	t, err := template.ParseFiles(getTemplatePath(name))
+	if err != nil {
+		log.Printf("template error: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, fmt.Sprintf("template error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := t.Execute(w, pageVariables); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("template error: %v", err)
+	}
+}
+
+func readAuthorID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["author_id"])
+}
+
+// AuthorPage shows an author, /author/{author_id}: their names, bio and photo, and their books with
+// how many have been read and liked.
+func AuthorPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	authorID, err := readAuthorID(r)
+	if err != nil {
+		redirectToErrorPage(w, r)
+		return
+	}
+
+	author, err := (*dao).GetAuthorByID(authorID)
+	if err != nil {
+		log.Printf("error getting author %d: %v", authorID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	} else if author.ID == 0 {
+		redirectToErrorPageWithMessageAndStatusCode(w, "Autor no encontrado", http.StatusNotFound)
+		return
+	}
+
+	books, err := (*dao).GetBooksByAuthorID(authorID)
+	if err != nil {
+		log.Printf("error getting the books of author %d: %v", authorID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	pageVariables := PageVariablesForAuthor{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		UseAnalytics: useAnalytics,
+		Author:       author,
+	}
+
+	pageResults := PageResultsVariables{}
+	setAuthenticationForPageResults(r, &pageResults, dao)
+	pageVariables.LoggedIn, pageVariables.IsAdmin = pageResults.LoggedIn, pageResults.IsAdmin
+
+	for _, bookInfo := range books {
+		authorBook := AuthorBook{BookInfo: bookInfo}
+		for _, contributor := range bookInfo.Contributors {
+			if contributor.AuthorID == authorID && contributor.Role != book.RoleAuthor {
+				authorBook.Roles = append(authorBook.Roles, contributor.Role.Label())
+			}
+		}
+
+		authorBook.Likes, err = (*dao).LikesCount(bookInfo.ID)
+		if err != nil {
+			log.Printf("error counting the likes of book %d: %v", bookInfo.ID, err)
+			redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+			return
+		}
+
+		if bookInfo.HasBeenRead {
+			pageVariables.ReadBooks++
+		}
+		pageVariables.Likes += authorBook.Likes
+		pageVariables.Books = append(pageVariables.Books, authorBook)
+	}
+
+	renderTemplate(w, "author.html", pageVariables)
+}
+
+// UpdateAuthor saves the edit form of the author page.
+func UpdateAuthor(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	authorID, err := readAuthorID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	author := book.Author{
+		ID:       authorID,
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		SortName: strings.TrimSpace(r.FormValue("sort_name")),
+		Bio:      strings.TrimSpace(r.FormValue("bio")),
+		PhotoURL: strings.TrimSpace(r.FormValue("photo_url")),
+	}
+	if author.Name == "" {
+		http.Error(w, "El nombre es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	if err := (*dao).UpdateAuthor(author); err != nil {
+		log.Printf("error updating author %d: %v", authorID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/author/%d", authorID), http.StatusSeeOther)
+}
+
+func renderAuthorsAdminPage(dao *dao.DAO, w http.ResponseWriter, pageVariables PageVariablesForAuthorsAdmin) {
+	authors, err := (*dao).GetAllAuthors()
+	if err != nil {
+		log.Printf("error getting authors: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	pageVariables.Year = time.Now().Format("2006")
+	pageVariables.SiteKey = captcha.SiteKey
+	pageVariables.LoggedIn = true
+	pageVariables.IsAdmin = true
+	pageVariables.UseAnalytics = useAnalytics
+	pageVariables.Authors = authors
+	pageVariables.Variants = catalog.FindAuthorVariants(authors)
+	for _, author := range authors {
+		if author.SortName == "" {
+			pageVariables.Unsorted = append(pageVariables.Unsorted, author)
+		}
+	}
+
+	renderTemplate(w, "authors_admin.html", pageVariables)
+}
+
+// AuthorsAdminPage lists the authors with the ones that look like variants of others, to merge them.
+func AuthorsAdminPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	renderAuthorsAdminPage(dao, w, PageVariablesForAuthorsAdmin{})
+}
+
+// MergeAuthors makes variant_id one more name of author_id.
+func MergeAuthors(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	fail := func(message string) {
+		w.WriteHeader(http.StatusBadRequest)
+		renderAuthorsAdminPage(dao, w, PageVariablesForAuthorsAdmin{ErrorMessage: message})
+	}
+
+	authorID, err := strconv.Atoi(r.FormValue("author_id"))
+	if err != nil {
+		fail("Elige el autor que se conserva")
+		return
+	}
+
+	variantID, err := strconv.Atoi(r.FormValue("variant_id"))
+	if err != nil {
+		fail("Elige la variante que se fusiona")
+		return
+	}
+
+	author, err := (*dao).GetAuthorByID(authorID)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+
+	variant, err := (*dao).GetAuthorByID(variantID)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+
+	if err := (*dao).MergeAuthors(authorID, variantID); err != nil {
+		log.Printf("error merging author %d into %d: %v", variantID, authorID, err)
+		fail(err.Error())
+		return
+	}
+
+	pageVariables := PageVariablesForAuthorsAdmin{
+		Message: fmt.Sprintf("%q es ahora otro nombre de %q", variant.Name, author.Name),
+	}
+	renderAuthorsAdminPage(dao, w, pageVariables)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"leonlib/internal/captcha"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type PageVariablesForCatalog struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Report       *catalog.Report
+	ErrorMessage string
+}
+
+func renderCatalogPage(w http.ResponseWriter, pageVariables PageVariablesForCatalog) {
+	templatePath := getTemplatePath("catalog.html")
+
+	t, err := template.ParseFiles(templatePath)
+	if err != nil {
+		log.Printf("template error: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, fmt.Sprintf("template error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = t.Execute(w, pageVariables)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("template error: %v", err)
+		return
+	}
+}
+
+func newCatalogPageVariables() PageVariablesForCatalog {
+	now := time.Now()
+
+	return PageVariablesForCatalog{
+		Year:         now.Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		LoggedIn:     true,
+		IsAdmin:      true,
+		UseAnalytics: useAnalytics,
+	}
+}
+
+func CatalogPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	renderCatalogPage(w, newCatalogPageVariables())
+}
+
+func ExportCatalogCSV(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="leonlib-%s.csv"`, time.Now().Format("2006-01-02")))
+
+	if err := catalog.ExportCSV(*dao, w); err != nil {
+		log.Printf("error exporting catalogue: %v", err)
+		http.Error(w, "error exporting the catalogue", http.StatusInternalServerError)
+	}
+}
+
+func ImportCatalogCSV(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	pageVariables := newCatalogPageVariables()
+
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		log.Printf("error importing catalogue: %v", err)
+		pageVariables.ErrorMessage = err.Error()
+		renderCatalogPage(w, pageVariables)
+		return
+	}
+
+	file, _, err := r.FormFile("csv")
+	if err != nil {
+		pageVariables.ErrorMessage = "Selecciona un archivo CSV"
+		renderCatalogPage(w, pageVariables)
+		return
+	}
+	defer file.Close()
+
+	dryRun := r.FormValue("dryRun") == "on"
+
+	report, err := catalog.ImportCSV(*dao, file, dryRun)
+	if err != nil {
+		log.Printf("error importing catalogue: %v", err)
+		pageVariables.ErrorMessage = err.Error()
+		renderCatalogPage(w, pageVariables)
+		return
+	}
+
+	log.Printf("CSV import finished:\n%s", report)
+
+	pageVariables.Report = &report
+	renderCatalogPage(w, pageVariables)
+}
+
+func ImportGoodreadsExport(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	pageVariables := newCatalogPageVariables()
+
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		log.Printf("error importing Goodreads export: %v", err)
+		pageVariables.ErrorMessage = err.Error()
+		renderCatalogPage(w, pageVariables)
+		return
+	}
+
+	file, _, err := r.FormFile("goodreads")
+	if err != nil {
+		pageVariables.ErrorMessage = "Selecciona el archivo goodreads_library_export.csv"
+		renderCatalogPage(w, pageVariables)
+		return
+	}
+	defer file.Close()
+
+	commit := r.FormValue("commit") == "on"
+
+	report, err := catalog.ImportGoodreads(*dao, file, commit)
+	if err != nil {
+		log.Printf("error importing Goodreads export: %v", err)
+		pageVariables.ErrorMessage = err.Error()
+		renderCatalogPage(w, pageVariables)
+		return
+	}
+
+	log.Printf("Goodreads import finished:\n%s", report)
+
+	pageVariables.Report = &report
+	renderCatalogPage(w, pageVariables)
+}
+
+// ExportBibliography exports a single book (id), the results of a search (textSearch and searchType)
+// or, without parameters, the whole catalogue as BibTeX, RIS or MARCXML.
+func ExportBibliography(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	format, err := catalog.ParseBibliographicFormat(mux.Vars(r)["format"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	fileName := "leonlib"
+	var books []book.BookInfo
+
+	switch {
+	case query.Get("id") != "":
+		bookID, err := strconv.Atoi(query.Get("id"))
+		if err != nil {
+			http.Error(w, "invalid book id", http.StatusBadRequest)
+			return
+		}
+
+		bookInfo, err := (*dao).GetBookByID(bookID)
+		if err != nil {
+			log.Printf("error exporting book %d: %v", bookID, err)
+			http.Error(w, "book not found", http.StatusNotFound)
+			return
+		}
+		books = []book.BookInfo{bookInfo}
+		fileName = fmt.Sprintf("leonlib-%d", bookID)
+
+	case query.Has("textSearch"):
+		results, err := searchBooks(dao, query.Get("textSearch"), query.Get("searchType"), readBookFilters(r))
+		if errors.Is(err, errWrongSearch) {
+			http.Error(w, "wrong search", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.Printf("error exporting search results: %v", err)
+			http.Error(w, "error getting info from the database", http.StatusInternalServerError)
+			return
+		}
+
+		// A book can match both by title and by author.
+		seen := map[int]bool{}
+		for _, result := range results {
+			if !seen[result.ID] {
+				seen[result.ID] = true
+				books = append(books, result)
+			}
+		}
+		fileName = "leonlib-busqueda"
+
+	default:
+		books, err = (*dao).GetAllBooks()
+		if err != nil {
+			log.Printf("error exporting catalogue: %v", err)
+			http.Error(w, "error getting info from the database", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, fileName, format.Extension()))
+
+	if err := catalog.WriteBibliography(w, format, books); err != nil {
+		log.Printf("error exporting %s: %v", format, err)
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DuplicateBook is a likely match of the duplicate detector as the API returns it.
+type DuplicateBook struct {
+	ID           int      `json:"id"`
+	Title        string   `json:"title"`
+	Author       string   `json:"author"`
+	ISBN         string   `json:"isbn,omitempty"`
+	URL          string   `json:"url"`
+	ThumbnailURL string   `json:"thumbnailURL,omitempty"`
+	Score        float64  `json:"score"`
+	Reasons      []string `json:"reasons"`
+}
+
+func newDuplicateBooks(duplicates []catalog.Duplicate) []DuplicateBook {
+	duplicateBooks := make([]DuplicateBook, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		duplicateBook := DuplicateBook{
+			ID:      duplicate.Book.ID,
+			Title:   duplicate.Book.Title,
+			Author:  duplicate.Book.Author,
+			ISBN:    duplicate.Book.ISBN,
+			URL:     "/book_info?id=" + strconv.Itoa(duplicate.Book.ID),
+			Score:   duplicate.Score,
+			Reasons: duplicate.Reasons,
+		}
+		if duplicate.Book.Cover != nil {
+			duplicateBook.ThumbnailURL = duplicate.Book.Cover.ThumbnailURL
+		}
+
+		duplicateBooks = append(duplicateBooks, duplicateBook)
+	}
+
+	return duplicateBooks
+}
+
+// writeDuplicates answers an /addbook that needs to be confirmed: the book may already be in the
+// catalogue. Sending the form again with confirm=true adds it anyway.
+func writeDuplicates(w http.ResponseWriter, duplicates []catalog.Duplicate) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"message": "Parece que este libro ya está en la biblioteca, ¿agregarlo de todos modos?",
+		"matches": newDuplicateBooks(duplicates),
+	})
+}
+
+// OwnedBook is the "do I own this?" check for a bookstore: /api/owned?isbn=... or
+// /api/owned?title=...&author=..., the author being optional.
+func OwnedBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	isbn, err := readISBN(r)
+	if err != nil {
+		http.Error(w, "ISBN rechazado: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	candidate := book.BookInfo{
+		Title:  strings.TrimSpace(query.Get("title")),
+		Author: strings.TrimSpace(query.Get("author")),
+		ISBN:   isbn,
+	}
+	if candidate.ISBN == "" && candidate.Title == "" {
+		http.Error(w, "isbn or title is required", http.StatusBadRequest)
+		return
+	}
+
+	duplicates, err := catalog.FindDuplicates(*dao, candidate, nil)
+	if err != nil {
+		log.Printf("error looking for duplicates: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"owned":   len(duplicates) > 0,
+		"matches": newDuplicateBooks(duplicates),
+	})
+}
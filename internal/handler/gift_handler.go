@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Masterminds/sprig/v3"
+	"github.com/gorilla/mux"
+	"html/template"
+	"leonlib/internal/auth"
+	"leonlib/internal/captcha"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type PageVariablesForMyWishList struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Results      []book.WishListBook
+	Priorities   []book.Priority
+	NewBook      book.WishListBook
+	// GiftListPath is the link to share the list as a gift list.
+	GiftListPath string
+}
+
+// Gift is a book of a gift list as a friend of its owner sees it.
+type Gift struct {
+	Book         book.WishListBook
+	Reserved     bool
+	ReservedByMe bool
+}
+
+type PageVariablesForGiftList struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	OwnerName    string
+	GiftListPath string
+	Gifts        []Gift
+}
+
+// renderWishListTemplate renders a page with the fields of the wish list forms.
+func renderWishListTemplate(w http.ResponseWriter, name string, pageVariables any) {
+	t, err := template.New("").Funcs(sprig.TxtFuncMap()).ParseFiles(getTemplatePath(name), getTemplatePath(wishListFormTemplate))
+	if err != nil {
+		log.Printf("template error: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, fmt.Sprintf("template error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := t.ExecuteTemplate(w, name, pageVariables); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("template error: %v", err)
+	}
+}
+
+// errWishListBookReserved names dao.ErrWishListBookReserved inside the handlers, like errImagesMismatch.
+var errWishListBookReserved = dao.ErrWishListBookReserved
+
+// giftListPath is the link to the gift list of a user, signed so that only who gets it sees the list.
+func giftListPath(userID string) string {
+	return "/gifts/" + url.PathEscape(userID) + "/" + auth.GiftListToken(userID)
+}
+
+// MyWishListPage shows the wish list of the user that is logged in, /my_wishlist, with the link to
+// share it as a gift list. The gifts friends reserved are never shown here.
+func MyWishListPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBooks, err := (*dao).GetWishListBooks(userID)
+	if err != nil {
+		log.Printf("error getting the wish list of %s: %v", userID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	renderWishListTemplate(w, "my_wishlist.html", PageVariablesForMyWishList{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		LoggedIn:     true,
+		IsAdmin:      isAdminUser(r, dao),
+		UseAnalytics: useAnalytics,
+		Results:      wishListBooks,
+		Priorities:   book.Priorities,
+		NewBook:      book.WishListBook{Priority: book.PriorityNormal},
+		GiftListPath: giftListPath(userID),
+	})
+}
+
+// CreateMyWishListBook adds a book to the wish list of the user that is logged in.
+func CreateMyWishListBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBook, err := readWishListBook(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wishListBook.UserID = userID
+
+	if _, err := (*dao).CreateWishListBook(wishListBook); err != nil {
+		log.Printf("error adding %q to the wish list of %s: %v", wishListBook.Title, userID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/my_wishlist", http.StatusSeeOther)
+}
+
+// UpdateMyWishListBook saves the edit form of a book of the wish list of the user that is logged in.
+func UpdateMyWishListBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBookID, err := readWishListBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wishListBook, err := readWishListBook(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wishListBook.ID = wishListBookID
+
+	if _, err := getWishListBookOf(dao, wishListBookID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := (*dao).UpdateWishListBook(wishListBook); err != nil {
+		log.Printf("error updating wish list book %d: %v", wishListBookID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/my_wishlist#wish-%d", wishListBookID), http.StatusSeeOther)
+}
+
+// DeleteMyWishListBook takes a book out of the wish list of the user that is logged in, with the
+// reservation a friend could have made of it.
+func DeleteMyWishListBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBookID, err := readWishListBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := getWishListBookOf(dao, wishListBookID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := (*dao).DeleteWishListBook(wishListBookID); err != nil {
+		log.Printf("error deleting wish list book %d: %v", wishListBookID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/my_wishlist", http.StatusSeeOther)
+}
+
+// readGiftListOwner returns the owner of the gift list of /gifts/{user_id}/{token}, when the token
+// is the one of the link.
+func readGiftListOwner(r *http.Request) (string, bool) {
+	vars := mux.Vars(r)
+	ownerID := vars["user_id"]
+
+	return ownerID, ownerID != "" && auth.ValidGiftListToken(ownerID, vars["token"])
+}
+
+// GiftListPage shows the wish list of a user to the friends it was shared with, /gifts/{user_id}/{token}.
+// The friends that are logged in see which books are reserved and reserve the others. Nobody else
+// sees the reservations, not even the owner after logging out, and the owner is sent to their own list.
+func GiftListPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := readGiftListOwner(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	viewerID, err := getCurrentUserID(r)
+	loggedIn := err == nil
+	if loggedIn && viewerID == ownerID {
+		http.Redirect(w, r, "/my_wishlist", http.StatusSeeOther)
+		return
+	}
+
+	owner, err := (*dao).GetUserInfoByID(ownerID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	wishListBooks, err := (*dao).GetWishListBooks(ownerID)
+	if err != nil {
+		log.Printf("error getting the wish list of %s: %v", ownerID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	reservedBy := map[int]string{}
+	if loggedIn {
+		reservations, err := (*dao).GetWishListReservations(ownerID)
+		if err != nil {
+			log.Printf("error getting the reservations of the wish list of %s: %v", ownerID, err)
+			redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+			return
+		}
+
+		for _, reservation := range reservations {
+			reservedBy[reservation.WishListBookID] = reservation.UserID
+		}
+	}
+
+	pageVariables := PageVariablesForGiftList{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		LoggedIn:     loggedIn,
+		IsAdmin:      isAdminUser(r, dao),
+		UseAnalytics: useAnalytics,
+		OwnerName:    owner.Name,
+		GiftListPath: giftListPath(ownerID),
+	}
+
+	for _, wishListBook := range wishListBooks {
+		reserver, reserved := reservedBy[wishListBook.ID]
+		pageVariables.Gifts = append(pageVariables.Gifts, Gift{
+			Book:         wishListBook,
+			Reserved:     reserved,
+			ReservedByMe: reserved && reserver == viewerID,
+		})
+	}
+
+	renderTemplate(w, "giftlist.html", pageVariables)
+}
+
+// readGift reads the gift list and the book of the reservation forms and the user that is logged in,
+// writing the error when any is wrong.
+func readGift(dao *dao.DAO, w http.ResponseWriter, r *http.Request) (book.WishListBook, string, bool) {
+	ownerID, ok := readGiftListOwner(r)
+	if !ok {
+		http.NotFound(w, r)
+		return book.WishListBook{}, "", false
+	}
+
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return book.WishListBook{}, "", false
+	}
+
+	wishListBookID, err := readWishListBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return book.WishListBook{}, "", false
+	}
+
+	wishListBook, err := getWishListBookOf(dao, wishListBookID, ownerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return book.WishListBook{}, "", false
+	}
+
+	return wishListBook, userID, true
+}
+
+// ReserveGift reserves a book of a gift list for the user that is logged in, so nobody else buys it.
+func ReserveGift(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	wishListBook, userID, ok := readGift(dao, w, r)
+	if !ok {
+		return
+	}
+
+	if err := wishListBook.CheckReservation(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := (*dao).ReserveWishListBook(book.WishListReservation{WishListBookID: wishListBook.ID, UserID: userID, ReservedOn: time.Now()})
+	if errors.Is(err, errWishListBookReserved) {
+		http.Error(w, "alguien más ya reservó este regalo", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("error reserving wish list book %d for %s: %v", wishListBook.ID, userID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s#wish-%d", giftListPath(wishListBook.UserID), wishListBook.ID), http.StatusSeeOther)
+}
+
+// CancelGift frees a book of a gift list the user that is logged in had reserved.
+func CancelGift(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	wishListBook, userID, ok := readGift(dao, w, r)
+	if !ok {
+		return
+	}
+
+	if err := (*dao).CancelWishListReservation(wishListBook.ID, userID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s#wish-%d", giftListPath(wishListBook.UserID), wishListBook.ID), http.StatusSeeOther)
+}
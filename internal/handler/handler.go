@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
@@ -12,14 +14,19 @@ import (
 	"io"
 	"leonlib/internal/auth"
 	"leonlib/internal/captcha"
+	"leonlib/internal/catalog"
 	"leonlib/internal/dao"
+	"leonlib/internal/imaging"
+	"leonlib/internal/isbn"
 	book "leonlib/internal/types"
 	user "leonlib/internal/types"
 	"log"
 	"math"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -44,12 +51,14 @@ type PageVariables struct {
 	SiteKey      string
 	LoggedIn     bool
 	UseAnalytics bool
+	// Quote is the random quote of the index page, empty when there are no quotes.
+	Quote QuoteOfTheDay
 }
 
 type PageVariablesForAuthors struct {
 	Year         string
 	SiteKey      string
-	Authors      []string
+	Authors      []book.Author
 	LoggedIn     bool
 	UseAnalytics bool
 }
@@ -61,6 +70,9 @@ type PageResultsVariablesForWishList struct {
 	LoggedIn     bool
 	IsAdmin      bool
 	UseAnalytics bool
+	// Priorities are the ones the admin forms offer, and NewBook is the blank one to add a book.
+	Priorities []book.Priority
+	NewBook    book.WishListBook
 }
 
 type PageResultsVariables struct {
@@ -78,7 +90,22 @@ type PageResultsVariables struct {
 	StartPage    int
 	EndPage      int
 	Pages        []int
+	TextSearch   string
+	SearchType   string
+	Filters      bookFilters
 	UseAnalytics bool
+	// OtherEditions are the other books of the work of the book page.
+	OtherEditions []book.BookInfo
+	// Shelf is the shelf the book of the book page is on.
+	Shelf book.Shelf
+	// Reviews are the reviews of the book of the book page, without their notes; MyReview is the one
+	// of the user that is logged in, notes included.
+	Reviews  []RenderedReview
+	MyReview RenderedReview
+	// Quotes are the quotes of the book of the book page, by page.
+	Quotes []book.Quote
+	// QuotedBooks are the books with quotes that match the text of a search.
+	QuotedBooks []catalog.QuotedBook
 }
 
 func generateRandomString(length int) string {
@@ -117,6 +144,8 @@ func parseBookSearchType(input string) book.BookSearchType {
 		return book.ByTitle
 	case "byauthor":
 		return book.ByAuthor
+	case "byisbn":
+		return book.ByISBN
 	default:
 		return book.Unknown
 	}
@@ -217,6 +246,12 @@ func redirectToErrorPageWithMessageAndStatusCode(w http.ResponseWriter, errorMes
 	}
 }
 
+// writeForbidden answers the requests of the page scripts that only an admin can make. Their error
+// handlers show the text of the response, so it is not the login page.
+func writeForbidden(w http.ResponseWriter) {
+	http.Error(w, "Solo un administrador puede hacerlo", http.StatusForbidden)
+}
+
 func redirectToErrorLoginPage(w http.ResponseWriter) {
 	templatePath := getTemplatePath("errorLogin.html")
 
@@ -291,7 +326,7 @@ func getCurrentUserID(r *http.Request) (string, error) {
 func getAllAuthors(db *sql.DB) ([]string, error) {
 	var err error
 
-	allAuthorsRows, err := db.Query("SELECT DISTINCT author FROM books ORDER BY author")
+	allAuthorsRows, err := db.Query("SELECT DISTINCT name FROM book_contributors ORDER BY name")
 	if err != nil {
 		return []string{}, err
 	}
@@ -324,7 +359,7 @@ func uniqueSearchTypes(searchTypes []string) []string {
 	return result
 }
 
-func IndexPage(w http.ResponseWriter, r *http.Request) {
+func IndexPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 
 	pageVariables := PageVariables{
@@ -333,7 +368,14 @@ func IndexPage(w http.ResponseWriter, r *http.Request) {
 		UseAnalytics: useAnalytics,
 	}
 
-	_, err := getCurrentUserID(r)
+	// The page works without its quote.
+	quote, err := randomQuote(dao)
+	if err != nil {
+		log.Printf("error getting a random quote: %v", err)
+	}
+	pageVariables.Quote = quote
+
+	_, err = getCurrentUserID(r)
 	if err != nil {
 		log.Printf("User is not logged in: %v", err)
 		pageVariables.LoggedIn = false
@@ -500,6 +542,16 @@ func AllBooksPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for i := range books {
+		images, err := (*dao).GetImagesByBookID(books[i].ID)
+		books[i].SetImages(images)
+		if err != nil {
+			log.Printf("Error getting images of book %d: %v", books[i].ID, err)
+			redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	pageVariables := PageResultsVariables{}
 	pageVariables.UseAnalytics = useAnalytics
 	pageVariables.Results = books
@@ -555,22 +607,50 @@ func AllBooksPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 //	})
 //}
 
+// BooksList returns the books of an author as JSON: the ones of ?author_id=, or the ones with an
+// author or contributor whose name has ?start_with= in it. ?tag= and ?category= filter them, and
+// without an author they list every book they let through.
 func BooksList(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	authorParam := r.URL.Query().Get("start_with")
 
-	booksByAuthor, err := (*dao).GetBooksBySearchTypeCoincidence(authorParam, book.ByAuthor)
+	var booksByAuthor []book.BookInfo
+	var err error
+	if authorIDParam := r.URL.Query().Get("author_id"); authorIDParam != "" {
+		authorID, atoiErr := strconv.Atoi(authorIDParam)
+		if atoiErr != nil {
+			http.Error(w, "author_id must be a number", http.StatusBadRequest)
+			return
+		}
+		booksByAuthor, err = (*dao).GetBooksByAuthorID(authorID)
+	} else if filters := readBookFilters(r); authorParam == "" && !filters.IsZero() {
+		booksByAuthor, err = filteredBooks(dao, filters)
+	} else {
+		booksByAuthor, err = (*dao).GetBooksBySearchTypeCoincidence(authorParam, book.ByAuthor)
+	}
 	if err != nil {
 		log.Printf("error: %v", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	booksByAuthor = readBookFilters(r).apply(booksByAuthor)
+
+	type Contributor struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+		// Label is the role as the pages show it.
+		Label string `json:"label"`
+	}
 
 	type BookDetail struct {
 		ID           int                  `json:"id"`
 		Title        string               `json:"title"`
 		Author       string               `json:"author"`
+		Contributors []Contributor        `json:"contributors"`
 		Description  string               `json:"description"`
-		Base64Images []book.BookImageInfo `json:"images"`
+		Tags         []string             `json:"tags"`
+		Category     book.Category        `json:"category"`
+		OnLoan       bool                 `json:"on_loan"`
+		Images       []book.BookImageInfo `json:"images"`
 	}
 
 	var results []BookDetail
@@ -580,8 +660,18 @@ func BooksList(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 		bookDetail.ID = book.ID
 		bookDetail.Title = book.Title
 		bookDetail.Author = book.Author
+		for _, contributor := range book.OtherContributors() {
+			bookDetail.Contributors = append(bookDetail.Contributors, Contributor{
+				Name:  contributor.Name,
+				Role:  string(contributor.Role),
+				Label: contributor.Role.Label(),
+			})
+		}
 		bookDetail.Description = book.Description
-		bookDetail.Base64Images = book.Base64Images
+		bookDetail.Tags = book.Tags
+		bookDetail.Category = book.Category
+		bookDetail.OnLoan = book.OnLoan()
+		bookDetail.Images = book.Images
 
 		results = append(results, bookDetail)
 	}
@@ -622,46 +712,143 @@ func BooksCount(dao *dao.DAO, w http.ResponseWriter) {
 	})
 }
 
-func SearchBooksPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
-	bookQuery := r.URL.Query().Get("textSearch")
-	searchTypesStr := r.URL.Query().Get("searchType")
-	searchTypesParams := uniqueSearchTypes(strings.Split(searchTypesStr, ","))
+var errWrongSearch = errors.New("wrong search")
+
+// errImagesMismatch names dao.ErrImagesMismatch inside the handlers, whose dao parameter shadows
+// the package.
+var errImagesMismatch = dao.ErrImagesMismatch
+
+// addLibrary is dao.AddLibrary, for the same reason.
+var addLibrary = dao.AddLibrary
+
+// addWishList is dao.AddWishList, for the same reason.
+var addWishList = dao.AddWishList
+
+// bookFilters narrow a list of books down to the ones with every tag of Tags and within Category.
+// They are the ?tag= (one or more) and ?category= of the search page and /api/books.
+type bookFilters struct {
+	Tags     []string
+	Category book.Category
+}
+
+func readBookFilters(r *http.Request) bookFilters {
+	query := r.URL.Query()
+
+	return bookFilters{
+		Tags:     book.NormalizeTags(query["tag"]),
+		Category: book.ParseCategory(query.Get("category")),
+	}
+}
+
+// IsZero tells if there is nothing to filter by.
+func (f bookFilters) IsZero() bool {
+	return len(f.Tags) == 0 && len(f.Category) == 0
+}
+
+func (f bookFilters) apply(books []book.BookInfo) []book.BookInfo {
+	if f.IsZero() {
+		return books
+	}
+
+	filtered := []book.BookInfo{}
+	for _, bookInfo := range books {
+		if f.matches(bookInfo) {
+			filtered = append(filtered, bookInfo)
+		}
+	}
+
+	return filtered
+}
+
+func (f bookFilters) matches(bookInfo book.BookInfo) bool {
+	for _, tag := range f.Tags {
+		if !bookInfo.HasTag(tag) {
+			return false
+		}
+	}
+
+	return bookInfo.Category.Within(f.Category)
+}
+
+// filteredBooks returns every book the filters let through with its images, sorted by title. It
+// is how the books of a tag or a category are browsed.
+func filteredBooks(dao *dao.DAO, filters bookFilters) ([]book.BookInfo, error) {
+	books, err := (*dao).GetAllBooks()
+	if err != nil {
+		return nil, err
+	}
+
+	books = filters.apply(books)
+	for i := range books {
+		images, err := (*dao).GetImagesByBookID(books[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		books[i].SetImages(images)
+	}
+
+	sort.SliceStable(books, func(i, j int) bool {
+		return books[i].Title < books[j].Title
+	})
+
+	return books, nil
+}
+
+// searchBooks runs the search of the search page, searchTypes is a comma separated list of
+// byTitle, byAuthor and byISBN. Without a bookQuery, the filters alone choose the books.
+func searchBooks(dao *dao.DAO, bookQuery, searchTypes string, filters bookFilters) ([]book.BookInfo, error) {
+	if strings.TrimSpace(bookQuery) == "" && !filters.IsZero() {
+		return filteredBooks(dao, filters)
+	}
+
+	searchTypesParams := uniqueSearchTypes(strings.Split(searchTypes, ","))
 
 	if len(searchTypesParams) == 0 || (len(searchTypesParams) == 1 && searchTypesParams[0] == "") {
 		searchTypesParams = []string{"byTitle"}
 	}
 
 	var results []book.BookInfo
-	var err error
 
 	for _, searchTypeParam := range searchTypesParams {
 		searchType := parseBookSearchType(searchTypeParam)
-		switch searchType {
-		case book.ByTitle:
-			booksByTitle, err := (*dao).GetBooksBySearchTypeCoincidence(bookQuery, book.ByTitle)
-			if err != nil {
-				log.Printf("error: %v", err)
-				redirectToErrorPageWithMessageAndStatusCode(w, "Error getting information from the database", http.StatusInternalServerError)
+		if searchType == book.Unknown {
+			return nil, errWrongSearch
+		}
 
-				return
+		var books []book.BookInfo
+		var err error
+		if searchType == book.ByISBN {
+			books, err = (*dao).GetBooksByISBN(bookQuery)
+			// Something that is not an ISBN finds no book.
+			if errors.Is(err, isbn.ErrInvalid) {
+				continue
 			}
-			results = append(results, booksByTitle...)
+		} else {
+			books, err = (*dao).GetBooksBySearchTypeCoincidence(bookQuery, searchType)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, filters.apply(books)...)
+	}
 
-		case book.ByAuthor:
-			booksByAuthor, err := (*dao).GetBooksBySearchTypeCoincidence(bookQuery, book.ByAuthor)
-			if err != nil {
-				log.Printf("error getting info from the database: %v", err)
-				redirectToErrorPageWithMessageAndStatusCode(w, "error getting info from the database", http.StatusInternalServerError)
-				return
-			}
-			results = append(results, booksByAuthor...)
+	return results, nil
+}
 
-		case book.Unknown:
-			log.Printf("Tipo de búsqueda en libros desconocido.")
-			redirectToErrorPageWithMessageAndStatusCode(w, "Wrong search", http.StatusInternalServerError)
+func SearchBooksPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	bookQuery := r.URL.Query().Get("textSearch")
+	searchTypesStr := r.URL.Query().Get("searchType")
+	filters := readBookFilters(r)
 
-			return
-		}
+	results, err := searchBooks(dao, bookQuery, searchTypesStr, filters)
+	if errors.Is(err, errWrongSearch) {
+		log.Printf("Tipo de búsqueda en libros desconocido.")
+		redirectToErrorPageWithMessageAndStatusCode(w, "Wrong search", http.StatusInternalServerError)
+		return
+	} else if err != nil {
+		log.Printf("error getting info from the database: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting info from the database", http.StatusInternalServerError)
+		return
 	}
 
 	now := time.Now()
@@ -669,9 +856,25 @@ func SearchBooksPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 		Year:         now.Format("2006"),
 		SiteKey:      captcha.SiteKey,
 		Results:      results,
+		TextSearch:   bookQuery,
+		SearchType:   searchTypesStr,
+		Filters:      filters,
 		UseAnalytics: useAnalytics,
 	}
 
+	// The quotes are searched by the text alone, the filters are about books.
+	if strings.TrimSpace(bookQuery) != "" {
+		quotes, err := (*dao).SearchQuotes(bookQuery)
+		if err == nil {
+			pageVariables.QuotedBooks, err = catalog.QuotesByBook(*dao, quotes)
+		}
+		if err != nil {
+			log.Printf("error searching quotes: %v", err)
+			redirectToErrorPageWithMessageAndStatusCode(w, "error getting info from the database", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	templatePath := getTemplatePath("search_books.html")
 
 	t, err := template.ParseFiles(templatePath)
@@ -847,6 +1050,26 @@ func Auth0Callback(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// isAdminUser tells if the current session belongs to the main app user.
+func isAdminUser(r *http.Request, dao *dao.DAO) bool {
+	dbID, err := getCurrentUserID(r)
+	if err != nil {
+		return false
+	}
+
+	if isDevMode() {
+		return true
+	}
+
+	userInfo, err := (*dao).GetUserInfoByID(dbID)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return false
+	}
+
+	return userInfo.Email == os.Getenv("LEONLIB_MAINAPP_USER")
+}
+
 func setAuthenticationForPageResults(r *http.Request, pageResultsVariables *PageResultsVariables, dao *dao.DAO) {
 	dbID, err := getCurrentUserID(r)
 	if err != nil {
@@ -930,27 +1153,61 @@ func AddBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	book.HasBeenRead = r.FormValue("read") == "on"
 	book.GoodreadsLink = r.FormValue("goodreadsLink")
 
-	var imageData []byte
-	file, _, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-		imageData, err = io.ReadAll(file)
+	book.ISBN, err = readISBN(r)
+	if err != nil {
+		http.Error(w, "ISBN rechazado: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	book.Edition, err = readEdition(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	book.Contributors, err = readContributors(r, book.Author)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	book.Tags, book.Category = readClassification(r)
+
+	images, err := readImageUploads(r)
+	if err != nil {
+		writeImageUploadError(w, err)
+		return
+	}
+	if len(images) > 0 {
+		book.Image = images[0]
+	}
+
+	if r.FormValue("confirm") != "true" {
+		duplicates, err := catalog.FindDuplicates(*dao, book, images)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-	} else if !errors.Is(err, http.ErrMissingFile) {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+
+		if len(duplicates) > 0 {
+			writeDuplicates(w, duplicates)
+			return
+		}
 	}
-	book.Image = imageData
 
-	err = (*dao).CreateBook(book)
+	bookID, err := (*dao).CreateBook(book)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	for _, imageData := range images[min(1, len(images)):] {
+		if err := (*dao).AddImageToBook(bookID, imageData); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Write([]byte("Libro agregado con éxito"))
 }
 
@@ -1025,7 +1282,13 @@ func CreateDBFromFile(dao *dao.DAO, w http.ResponseWriter) {
 
 	startTime := time.Now()
 
-	err := (*dao).AddAll(library.Book)
+	err := addLibrary(*dao, library)
+	if err != nil {
+		writeErrorGeneralStatus(w, err)
+		return
+	}
+
+	err = addWishList(*dao)
 	if err != nil {
 		writeErrorGeneralStatus(w, err)
 		return
@@ -1054,17 +1317,47 @@ func InfoBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	otherEditions, err := getOtherEditions(dao, bookByID)
+	if err != nil {
+		log.Printf("error: getting the editions of book %d: %v", id, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	shelf, err := (*dao).GetShelfByID(bookByID.Location.ShelfID)
+	if err != nil {
+		log.Printf("error: getting the shelf of book %d: %v", id, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
 	now := time.Now()
 
 	pageVariables := &PageResultsVariables{
-		Year:         now.Format("2006"),
-		SiteKey:      captcha.SiteKey,
-		Results:      []book.BookInfo{bookByID},
-		UseAnalytics: useAnalytics,
+		Year:          now.Format("2006"),
+		SiteKey:       captcha.SiteKey,
+		Results:       []book.BookInfo{bookByID},
+		UseAnalytics:  useAnalytics,
+		OtherEditions: otherEditions,
+		Shelf:         shelf,
 	}
 
 	setAuthenticationForPageResults(r, pageVariables, dao)
 
+	pageVariables.Reviews, pageVariables.MyReview, err = bookReviews(dao, r, id)
+	if err != nil {
+		log.Printf("error: getting the reviews of book %d: %v", id, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	pageVariables.Quotes, err = (*dao).GetQuotesByBookID(id)
+	if err != nil {
+		log.Printf("error: getting the quotes of book %d: %v", id, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
 	templatePath := getTemplatePath("book_info.html")
 
 	t, err := template.ParseFiles(templatePath)
@@ -1081,8 +1374,35 @@ func InfoBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getOtherEditions returns the books of the same work as bookInfo, but bookInfo.
+func getOtherEditions(dao *dao.DAO, bookInfo book.BookInfo) ([]book.BookInfo, error) {
+	if bookInfo.WorkID <= 0 {
+		return nil, nil
+	}
+
+	editions, err := (*dao).GetEditions(bookInfo.WorkID)
+	if err != nil {
+		return nil, err
+	}
+
+	var otherEditions []book.BookInfo
+	for _, edition := range editions {
+		if edition.ID != bookInfo.ID {
+			otherEditions = append(otherEditions, edition)
+		}
+	}
+
+	return otherEditions, nil
+}
+
+// ModifyBook saves the modify form of a book. The fields, and the groups of fields, the form does
+// not send keep what the book has, so a form with only some of them does not wipe the rest.
 func ModifyBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
-	// TODO: check auth here
+	if !isAdminUser(r, dao) {
+		writeForbidden(w)
+		return
+	}
+
 	err := r.ParseMultipartForm(2 << 20)
 	if err != nil {
 		writeErrorGeneralStatus(w, err)
@@ -1091,11 +1411,6 @@ func ModifyBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	}
 
 	bookIDParam := r.FormValue("book_id")
-	title := r.FormValue("title")
-	author := r.FormValue("author")
-	description := r.FormValue("description")
-	read := r.FormValue("read") == "on"
-	goodreadsLink := r.FormValue("goodreadsLink")
 
 	id, err := strconv.Atoi(bookIDParam)
 	if err != nil {
@@ -1104,43 +1419,479 @@ func ModifyBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = addImageToBook(dao, id, r)
+	current, err := (*dao).GetBookByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	title := formValue(r, "title", current.Title)
+	author := formValue(r, "author", current.Author)
+	description := formValue(r, "description", current.Description)
+	goodreadsLink := formValue(r, "goodreadsLink", current.GoodreadsLink)
+
+	isbn := current.ISBN
+	if formHas(r, "isbn") {
+		isbn, err = readISBN(r)
+		if err != nil {
+			http.Error(w, "ISBN rechazado: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	workID, edition := current.WorkID, current.Edition
+	saveWork := formHas(r, "work_id")
+	saveEdition := formHas(r, editionFields...)
+	if saveEdition {
+		edition, err = readEdition(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	// UpdateBook keeps the other contributors when only the author changes.
+	var contributors []book.Contributor
+	saveContributors := formHas(r, "contributor_name", "contributor_role")
+	if saveContributors {
+		contributors, err = readContributors(r, author)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	reading := current
+	saveReading := formHas(r, readingFields...)
+	if saveReading {
+		reading, err = readReading(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	// Only the admins see the acquisition fields, the form of anyone else leaves them as they are.
+	var acquisition book.Acquisition
+	saveAcquisition := r.FormValue("acquisition") != "" && isAdminUser(r, dao)
+	if saveAcquisition {
+		acquisition, err = readAcquisition(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	tags, category := readClassification(r)
+	saveTags, saveCategory := formHas(r, "tags"), formHas(r, "category")
+
+	location := current.Location
+	saveLocation := formHas(r, "shelf_id", "shelf_position")
+	if saveLocation {
+		location, err = readLocation(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	var createWork bool
+	if saveWork {
+		workID, createWork, err = readWorkID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	images, err := readImageUploads(r)
+	if err != nil {
+		writeImageUploadError(w, err)
+
+		return
+	}
+
+	// Every field is valid, nothing has been written until here.
+	if createWork {
+		workID, err = (*dao).CreateWork(book.Work{Title: title, Author: author})
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
+	err = addImagesToBook(dao, id, images)
 	if err != nil {
 		writeErrorGeneralStatus(w, err)
 
 		return
 	}
 
-	err = (*dao).UpdateBook(title, author, description, read, goodreadsLink, id)
+	err = (*dao).UpdateBook(title, author, description, reading.HasBeenRead, goodreadsLink, isbn, id)
 	if err != nil {
 		writeErrorGeneralStatus(w, err)
 
 		return
 	}
 
+	if saveWork || saveEdition {
+		err = (*dao).UpdateEdition(id, workID, edition)
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
+	if saveContributors {
+		err = (*dao).SetContributors(id, contributors)
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
+	if saveTags {
+		err = (*dao).SetTags(id, tags)
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
+	if saveCategory {
+		err = (*dao).SetCategory(id, category)
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
+	if saveLocation {
+		err = (*dao).SetBookLocation(id, location)
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
+	if saveReading {
+		err = (*dao).SetReading(id, reading.ReadingStatus, reading.Pages, reading.Readings)
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
+	if saveAcquisition {
+		err = (*dao).SetAcquisition(id, acquisition)
+		if err != nil {
+			writeErrorGeneralStatus(w, err)
+
+			return
+		}
+	}
+
 	w.Write([]byte("Libro modificado con exito"))
 }
 
-func addImageToBook(dao *dao.DAO, id int, r *http.Request) error {
-	var imageData []byte
-	file, _, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-		imageData, err = io.ReadAll(file)
+// formHas tells if the form sent any of the fields, empty or not.
+func formHas(r *http.Request, fields ...string) bool {
+	for _, field := range fields {
+		if _, ok := r.Form[field]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formValue is the field of the form, or current when the form does not send it.
+func formValue(r *http.Request, field, current string) string {
+	if !formHas(r, field) {
+		return current
+	}
+
+	return r.FormValue(field)
+}
+
+// editionFields are the fields of the edition in the book forms.
+var editionFields = []string{"publisher", "year", "language", "format"}
+
+// readEdition reads the edition fields of the book forms, all of them optional.
+func readEdition(r *http.Request) (book.Edition, error) {
+	edition := book.Edition{
+		Publisher: strings.TrimSpace(r.FormValue("publisher")),
+		Language:  strings.TrimSpace(r.FormValue("language")),
+		Format:    strings.TrimSpace(r.FormValue("format")),
+	}
+
+	if year := strings.TrimSpace(r.FormValue("year")); year != "" {
+		var err error
+		edition.Year, err = strconv.Atoi(year)
+		if err != nil || edition.Year <= 0 {
+			return book.Edition{}, fmt.Errorf("%q no es un año válido", year)
+		}
+	}
+
+	return edition, nil
+}
+
+// readContributors reads the people of the book forms: the authors of the author field and every
+// contributor_name with the contributor_role next to it. Rows without a name are ignored.
+func readContributors(r *http.Request, author string) ([]book.Contributor, error) {
+	contributors := book.NewAuthors(author)
+
+	names := r.Form["contributor_name"]
+	roles := r.Form["contributor_role"]
+	for i, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+
+		var roleText string
+		if i < len(roles) {
+			roleText = roles[i]
+		}
+
+		role, ok := book.ParseContributorRole(roleText)
+		if !ok {
+			return nil, fmt.Errorf("%q no es un rol válido", roleText)
+		}
+
+		contributors = append(contributors, book.Contributor{Name: strings.TrimSpace(name), Role: role})
+	}
+
+	return contributors, nil
+}
+
+// readingFields are the fields of the reading status in the modify form.
+var readingFields = []string{"reading_status", "pages", "reading_started", "reading_finished", "reading_page"}
+
+// readReading reads the reading status, the pages and the read-throughs of the modify form, a
+// reading_started, reading_finished and reading_page each. Rows left empty are ignored. The book
+// comes back normalized, HasBeenRead says if it is read.
+func readReading(r *http.Request) (book.BookInfo, error) {
+	status, ok := book.ParseReadingStatus(r.FormValue("reading_status"))
+	if !ok {
+		return book.BookInfo{}, fmt.Errorf("%q no es un estado de lectura válido", r.FormValue("reading_status"))
+	}
+	bookInfo := book.BookInfo{ReadingStatus: status}
+
+	if pages := strings.TrimSpace(r.FormValue("pages")); pages != "" {
+		var err error
+		bookInfo.Pages, err = strconv.Atoi(pages)
+		if err != nil || bookInfo.Pages <= 0 {
+			return book.BookInfo{}, fmt.Errorf("%q no es un número de páginas válido", pages)
+		}
+	}
+
+	started := r.Form["reading_started"]
+	finished := r.Form["reading_finished"]
+	pages := r.Form["reading_page"]
+	for i := range started {
+		var reading book.Reading
+		var err error
+		if reading.StartedOn, err = parseDateInput(started[i]); err != nil {
+			return book.BookInfo{}, err
+		}
+		if i < len(finished) {
+			if reading.FinishedOn, err = parseDateInput(finished[i]); err != nil {
+				return book.BookInfo{}, err
+			}
+		}
+		if i < len(pages) && strings.TrimSpace(pages[i]) != "" {
+			reading.CurrentPage, err = strconv.Atoi(strings.TrimSpace(pages[i]))
+			if err != nil {
+				return book.BookInfo{}, fmt.Errorf("%q no es una página válida", pages[i])
+			}
+		}
+
+		if err := reading.Validate(bookInfo.Pages); err != nil {
+			return book.BookInfo{}, fmt.Errorf("lectura %d: %v", i+1, err)
+		}
+
+		bookInfo.Readings = append(bookInfo.Readings, reading)
+	}
+
+	bookInfo.NormalizeReading()
+
+	return bookInfo, nil
+}
+
+// readAcquisition reads the purchase date, the price, the currency, the store, the condition and the
+// estimated value of the modify form, all of them optional.
+func readAcquisition(r *http.Request) (book.Acquisition, error) {
+	purchasedOn, err := parseDateInput(r.FormValue("purchased_on"))
+	if err != nil {
+		return book.Acquisition{}, err
+	}
+
+	price, err := book.ParseMoney(r.FormValue("purchase_price"))
+	if err != nil {
+		return book.Acquisition{}, fmt.Errorf("precio: %v", err)
+	}
+
+	estimatedValue, err := book.ParseMoney(r.FormValue("estimated_value"))
+	if err != nil {
+		return book.Acquisition{}, fmt.Errorf("valor estimado: %v", err)
+	}
+
+	condition, ok := book.ParseCondition(r.FormValue("condition"))
+	if !ok {
+		return book.Acquisition{}, fmt.Errorf("%q no es un estado válido", r.FormValue("condition"))
+	}
+
+	acquisition := book.Acquisition{
+		PurchasedOn:    purchasedOn,
+		Price:          price,
+		Currency:       r.FormValue("currency"),
+		Source:         r.FormValue("purchased_from"),
+		Condition:      condition,
+		EstimatedValue: estimatedValue,
+	}
+	if err := acquisition.Validate(); err != nil {
+		return book.Acquisition{}, err
+	}
+	acquisition.Normalize()
+
+	return acquisition, nil
+}
+
+// readClassification reads the comma separated tags and the category of the book forms.
+func readClassification(r *http.Request) ([]string, book.Category) {
+	return book.ParseTags(r.FormValue("tags")), book.ParseCategory(r.FormValue("category"))
+}
+
+// readLocation reads the shelf and the position of the modify form. No shelf takes the book off
+// its shelf, and no position puts it after the last book of the shelf.
+func readLocation(r *http.Request) (book.Location, error) {
+	var location book.Location
+	if shelfID := strings.TrimSpace(r.FormValue("shelf_id")); shelfID != "" {
+		var err error
+		location.ShelfID, err = strconv.Atoi(shelfID)
 		if err != nil {
-			return err
+			return book.Location{}, fmt.Errorf("%q no es un estante válido", shelfID)
 		}
-	} else if !errors.Is(err, http.ErrMissingFile) {
-		return err
 	}
 
-	if len(imageData) == 0 {
-		return nil
+	if position := strings.TrimSpace(r.FormValue("shelf_position")); position != "" && !location.IsZero() {
+		var err error
+		location.Position, err = strconv.Atoi(position)
+		if err != nil || location.Position <= 0 {
+			return book.Location{}, fmt.Errorf("%q no es una posición válida", position)
+		}
+	}
+
+	return location, nil
+}
+
+// newWork is the work_id the modify form sends to start a work with the title and author of the book.
+const newWork = "new"
+
+// readWorkID returns the work the modify form puts the book in, 0 for none. createWork is whether
+// the form asks for a new work, which is created once the whole form is valid.
+func readWorkID(r *http.Request) (workID int, createWork bool, err error) {
+	switch value := r.FormValue("work_id"); value {
+	case "":
+		return 0, false, nil
+	case newWork:
+		return 0, true, nil
+	default:
+		workID, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, false, fmt.Errorf("%q no es una obra válida", value)
+		}
+
+		return workID, false, nil
 	}
+}
 
-	err = (*dao).AddImageToBook(id, imageData)
+// readISBN validates the isbn field of the book forms and returns it as an ISBN-13, the ISBN-10 of
+// older books are converted. The error is meant for whoever filled in the form.
+func readISBN(r *http.Request) (string, error) {
+	normalized, err := isbn.Normalize(r.FormValue("isbn"))
 	if err != nil {
-		return err
+		return "", fmt.Errorf("%q no es un ISBN válido, debe tener 10 o 13 dígitos y el último es de control", r.FormValue("isbn"))
+	}
+
+	return normalized, nil
+}
+
+// readImageUploads reads the files of the image field of the form, in the order they were
+// selected, and normalizes them. Either every image is accepted or none is: a rejected image comes
+// back as an *imaging.UploadError naming the file.
+func readImageUploads(r *http.Request) ([][]byte, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+
+	var images [][]byte
+	for _, header := range r.MultipartForm.File["image"] {
+		if header.Size == 0 {
+			continue
+		}
+
+		imageData, err := readImageUpload(header)
+		var uploadError *imaging.UploadError
+		if errors.As(err, &uploadError) {
+			return nil, &imaging.UploadError{Reason: header.Filename + ": " + uploadError.Reason}
+		} else if err != nil {
+			return nil, err
+		}
+
+		images = append(images, imageData)
+	}
+
+	return images, nil
+}
+
+func readImageUpload(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	imageData, err := io.ReadAll(io.LimitReader(file, imaging.MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	return imaging.NormalizeUpload(imageData)
+}
+
+// writeImageUploadError answers a rejected image with its reason, anything else is a server error.
+func writeImageUploadError(w http.ResponseWriter, err error) {
+	var uploadError *imaging.UploadError
+	if errors.As(err, &uploadError) {
+		http.Error(w, "Imagen rechazada: "+uploadError.Reason, http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("error reading the uploaded image: %v", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func addImagesToBook(dao *dao.DAO, id int, images [][]byte) error {
+	for _, imageData := range images {
+		if err := (*dao).AddImageToBook(id, imageData); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -1163,11 +1914,41 @@ func ModifyBookPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 
 	now := time.Now()
 
+	works, err := (*dao).GetAllWorks()
+	if err != nil {
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := (*dao).GetAllTags()
+	if err != nil {
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	categories, err := (*dao).GetAllCategories()
+	if err != nil {
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	shelves, err := (*dao).GetAllShelves()
+	if err != nil {
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
 	type BookToModifyVariables struct {
 		Year          string
 		SiteKey       string
 		Book          book.BookInfo
+		Works         []book.Work
+		Tags          []book.Tag
+		Categories    []book.CategoryCount
+		Shelves       []book.Shelf
 		LoggedIn      bool
+		IsAdmin       bool
+		Conditions    []book.Condition
 		GoodreadsLink template.URL
 	}
 
@@ -1175,6 +1956,12 @@ func ModifyBookPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 		Year:          now.Format("2006"),
 		SiteKey:       captcha.SiteKey,
 		Book:          bookByID,
+		Works:         works,
+		Tags:          tags,
+		Categories:    categories,
+		Shelves:       shelves,
+		IsAdmin:       isAdminUser(r, dao),
+		Conditions:    book.Conditions,
 		GoodreadsLink: template.URL(bookByID.GoodreadsLink),
 	}
 
@@ -1300,7 +2087,11 @@ func AddBookPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 }
 
 func RemoveImage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
-	// TODO: check auth
+	if !isAdminUser(r, dao) {
+		writeForbidden(w)
+		return
+	}
+
 	r.ParseForm()
 	imageIDParam := r.PostFormValue("image_id")
 
@@ -1319,10 +2110,129 @@ func RemoveImage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Image removed OK..."))
 }
 
-func WishListBooksPage(dao *dao.DAO, w http.ResponseWriter, _ *http.Request) {
+// ReorderImages sets the order of the images of a book, image_ids lists all of them comma
+// separated in their new order.
+func ReorderImages(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		writeForbidden(w)
+		return
+	}
+
+	r.ParseForm()
+
+	bookID, err := strconv.Atoi(r.PostFormValue("book_id"))
+	if err != nil {
+		http.Error(w, "book_id inválido", http.StatusBadRequest)
+		return
+	}
+
+	var imageIDs []int
+	for _, imageIDParam := range strings.Split(r.PostFormValue("image_ids"), ",") {
+		imageID, err := strconv.Atoi(strings.TrimSpace(imageIDParam))
+		if err != nil {
+			http.Error(w, "image_ids inválido", http.StatusBadRequest)
+			return
+		}
+		imageIDs = append(imageIDs, imageID)
+	}
+
+	err = (*dao).ReorderImages(bookID, imageIDs)
+	if errors.Is(err, errImagesMismatch) {
+		http.Error(w, "Las imágenes no son las del libro, recarga la página", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("error reordering the images of book %d: %v", bookID, err)
+		http.Error(w, "Error reordering images", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte("Images reordered OK..."))
+}
+
+// SetCoverImage makes image_id the cover of the book book_id.
+func SetCoverImage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		writeForbidden(w)
+		return
+	}
+
+	r.ParseForm()
+
+	bookID, err := strconv.Atoi(r.PostFormValue("book_id"))
+	if err != nil {
+		http.Error(w, "book_id inválido", http.StatusBadRequest)
+		return
+	}
+
+	imageID, err := strconv.Atoi(r.PostFormValue("image_id"))
+	if err != nil {
+		http.Error(w, "image_id inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = (*dao).SetCoverImage(bookID, imageID)
+	if errors.Is(err, errImagesMismatch) {
+		http.Error(w, "La imagen no es de este libro, recarga la página", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("error setting the cover of book %d: %v", bookID, err)
+		http.Error(w, "Error setting the cover", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte("Cover set OK..."))
+}
+
+// getImageBySize returns the original image, or the variant named by size ("thumb" or "medium").
+// An image that cannot be resized is served in its original size.
+func getImageBySize(bookDAO dao.DAO, imageID int, size string) (book.BookImage, error) {
+	if size == "" {
+		return bookDAO.GetImage(imageID)
+	}
+
+	variant, err := imaging.ParseVariant(size)
+	if err != nil {
+		return book.BookImage{}, err
+	}
+
+	image, err := dao.GetOrCreateImageVariant(bookDAO, imageID, variant)
+	if err != nil {
+		log.Printf("image %d: serving the original instead of %s: %v", imageID, variant, err)
+		return bookDAO.GetImage(imageID)
+	}
+
+	return image, nil
+}
+
+// BookImage serves the raw bytes of an image, or of one of its variants with ?size=thumb|medium.
+// Images are never modified once stored, so the ETag is the hash of the content and browsers can
+// keep them for a day before revalidating.
+func BookImage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["image_id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	image, err := getImageBySize(*dao, imageID, r.URL.Query().Get("size"))
+	if err != nil {
+		log.Printf("error getting image %d: %v", imageID, err)
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(image.Data))
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha256.Sum256(image.Data)))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+
+	// ServeContent answers If-None-Match with a 304 and supports range requests.
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(image.Data))
+}
+
+func WishListBooksPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
 	templatePath := getTemplatePath("wishlistbooks.html")
 
-	t, err := template.ParseFiles(templatePath)
+	t, err := template.New("").Funcs(sprig.TxtFuncMap()).ParseFiles(templatePath, getTemplatePath(wishListFormTemplate))
 	if err != nil {
 		redirectToErrorPageWithMessageAndStatusCode(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1332,22 +2242,25 @@ func WishListBooksPage(dao *dao.DAO, w http.ResponseWriter, _ *http.Request) {
 
 	var results []book.WishListBook
 
-	results, err = (*dao).GetWishListBooks()
+	results, err = (*dao).GetWishListBooks("")
 	if err != nil {
 		redirectToErrorPageWithMessageAndStatusCode(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	_, err = getCurrentUserID(r)
 	pageVariables := PageResultsVariablesForWishList{
 		Year:         now.Format("2006"),
 		SiteKey:      captcha.SiteKey,
-		IsAdmin:      false, // TODO: pending
-		LoggedIn:     false,
+		IsAdmin:      isAdminUser(r, dao),
+		LoggedIn:     err == nil,
 		Results:      results,
 		UseAnalytics: useAnalytics,
+		Priorities:   book.Priorities,
+		NewBook:      book.WishListBook{Priority: book.PriorityNormal},
 	}
 
-	err = t.Execute(w, pageVariables)
+	err = t.ExecuteTemplate(w, "wishlistbooks.html", pageVariables)
 	if err != nil {
 		redirectToErrorPageWithMessageAndStatusCode(w, err.Error(), http.StatusInternalServerError)
 		return
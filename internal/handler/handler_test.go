@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gorilla/sessions"
+	"leonlib/internal/auth"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newImagesTestDAO returns a memory DAO with a book of two images, the first one its cover.
+func newImagesTestDAO(t *testing.T) (*dao.DAO, int, []book.BookImageInfo) {
+	t.Helper()
+	t.Setenv("TEMPLATE_DIR", "../template")
+	t.Setenv("RUN_MODE", "")
+	t.Setenv("LEONLIB_MAINAPP_USER", "admin@example.com")
+	auth.SessionStore = sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+
+	bookDAO, err := dao.OpenDAO("memory", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = bookDAO.Close() })
+
+	bookID, err := bookDAO.CreateBook(book.BookInfo{Title: "Rayuela", Author: "Julio Cortázar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{"first", "second"} {
+		if err := bookDAO.AddImageToBook(bookID, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bookDAO.AddUser("admin", "admin@example.com", "Admin", "Google"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bookDAO.AddUser("visitor", "visitor@example.com", "Visitor", "Google"); err != nil {
+		t.Fatal(err)
+	}
+
+	return &bookDAO, bookID, bookImages(t, &bookDAO, bookID)
+}
+
+func bookImages(t *testing.T, bookDAO *dao.DAO, bookID int) []book.BookImageInfo {
+	t.Helper()
+
+	bookInfo, err := (*bookDAO).GetBookByID(bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bookInfo.Images
+}
+
+// sessionCookie is the session cookie of the user, none when userID is empty.
+func sessionCookie(t *testing.T, userID string) *http.Cookie {
+	t.Helper()
+
+	if userID == "" {
+		return nil
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	session, _ := auth.SessionStore.Get(r, "user-session")
+	session.Values["user_id"] = userID
+	if err := session.Save(r, w); err != nil {
+		t.Fatal(err)
+	}
+
+	return w.Result().Cookies()[0]
+}
+
+func postForm(handlerFunc func(*dao.DAO, http.ResponseWriter, *http.Request), bookDAO *dao.DAO, cookie *http.Cookie,
+	form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	handlerFunc(bookDAO, w, r)
+
+	return w
+}
+
+func TestImageEndpointsNeedAnAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		status int
+	}{
+		{"anonymous", "", http.StatusForbidden},
+		{"not an admin", "visitor", http.StatusForbidden},
+		{"admin", "admin", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookDAO, bookID, images := newImagesTestDAO(t)
+			cookie := sessionCookie(t, tt.userID)
+			first, second := images[0].ImageID, images[1].ImageID
+
+			w := postForm(ReorderImages, bookDAO, cookie, url.Values{
+				"book_id":   {fmt.Sprint(bookID)},
+				"image_ids": {fmt.Sprintf("%d,%d", second, first)},
+			})
+			if w.Code != tt.status {
+				t.Errorf("ReorderImages answered %d, want %d", w.Code, tt.status)
+			}
+
+			w = postForm(SetCoverImage, bookDAO, cookie, url.Values{
+				"book_id":  {fmt.Sprint(bookID)},
+				"image_id": {fmt.Sprint(second)},
+			})
+			if w.Code != tt.status {
+				t.Errorf("SetCoverImage answered %d, want %d", w.Code, tt.status)
+			}
+
+			after := bookImages(t, bookDAO, bookID)
+			if tt.status != http.StatusOK {
+				if !reflect.DeepEqual(after, images) {
+					t.Errorf("images after the requests = %+v, want them unchanged %+v", after, images)
+				}
+			} else if after[0].ImageID != second || !after[0].IsCover {
+				t.Errorf("images after the requests = %+v, want %d first and cover", after, second)
+			}
+
+			w = postForm(RemoveImage, bookDAO, cookie, url.Values{"image_id": {fmt.Sprint(first)}})
+			if w.Code != tt.status {
+				t.Errorf("RemoveImage answered %d, want %d", w.Code, tt.status)
+			}
+
+			remaining := bookImages(t, bookDAO, bookID)
+			if tt.status != http.StatusOK {
+				if !reflect.DeepEqual(remaining, after) {
+					t.Errorf("images after RemoveImage = %+v, want them unchanged %+v", remaining, after)
+				}
+			} else if len(remaining) != 1 || remaining[0].ImageID != second {
+				t.Errorf("images after RemoveImage = %+v, want only %d", remaining, second)
+			}
+		})
+	}
+}
+
+// postMultipartForm posts the fields as the book forms do, with a file for each image, as the user of the cookie.
+func postMultipartForm(t *testing.T, handlerFunc func(*dao.DAO, http.ResponseWriter, *http.Request), bookDAO *dao.DAO,
+	cookie *http.Cookie, form url.Values, images ...[]byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, values := range form {
+		for _, value := range values {
+			if err := writer.WriteField(name, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for i, image := range images {
+		part, err := writer.CreateFormFile("image", fmt.Sprintf("%d.jpg", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write(image); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	handlerFunc(bookDAO, w, r)
+
+	return w
+}
+
+func TestModifyBookValidatesTheFormBeforeSaving(t *testing.T) {
+	tests := []struct {
+		name   string
+		form   url.Values
+		images [][]byte
+	}{
+		{"shelf", url.Values{"shelf_id": {"Estudio"}}, nil},
+		{"position", url.Values{"shelf_id": {"1"}, "shelf_position": {"-1"}}, nil},
+		{"work", url.Values{"work_id": {"Ulises"}}, nil},
+		{"new work and shelf", url.Values{"work_id": {newWork}, "shelf_id": {"Estudio"}}, nil},
+		{"image", url.Values{"work_id": {newWork}}, [][]byte{[]byte("not an image")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookDAO, bookID, images := newImagesTestDAO(t)
+
+			form := url.Values{
+				"book_id": {fmt.Sprint(bookID)},
+				"title":   {"Rayuela (edición conmemorativa)"},
+				"author":  {"Julio Cortázar"},
+				"tags":    {"argentina"},
+			}
+			for name, values := range tt.form {
+				form[name] = values
+			}
+
+			w := postMultipartForm(t, ModifyBook, bookDAO, sessionCookie(t, "admin"), form, tt.images...)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("ModifyBook answered %d, want %d", w.Code, http.StatusBadRequest)
+			}
+
+			bookInfo, err := (*bookDAO).GetBookByID(bookID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bookInfo.Title != "Rayuela" || len(bookInfo.Tags) != 0 || !reflect.DeepEqual(bookInfo.Images, images) {
+				t.Errorf("the book changed: %q, tags %v, images %+v", bookInfo.Title, bookInfo.Tags, bookInfo.Images)
+			}
+
+			works, err := (*bookDAO).GetAllWorks()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(works) != 0 {
+				t.Errorf("the request created the works %+v", works)
+			}
+		})
+	}
+}
+
+// newModifyTestDAO is newImagesTestDAO with an ISBN, a reading status, a translator, tags, a
+// category, a shelf, a work and an edition on the book, so the tests can see whether ModifyBook
+// keeps them.
+func newModifyTestDAO(t *testing.T) (*dao.DAO, book.BookInfo) {
+	t.Helper()
+
+	bookDAO, bookID, _ := newImagesTestDAO(t)
+	if err := (*bookDAO).UpdateBook("Rayuela", "Julio Cortázar", "Novela", false, "", "9780306406157", bookID); err != nil {
+		t.Fatal(err)
+	}
+	if err := (*bookDAO).SetReading(bookID, book.StatusReading, 600, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := (*bookDAO).SetContributors(bookID, []book.Contributor{
+		{Name: "Julio Cortázar", Role: book.RoleAuthor},
+		{Name: "Gregory Rabassa", Role: book.RoleTranslator},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := (*bookDAO).SetTags(bookID, []string{"argentina", "novela"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := (*bookDAO).SetCategory(bookID, book.ParseCategory("Ficción/Novela")); err != nil {
+		t.Fatal(err)
+	}
+	shelfID, err := (*bookDAO).CreateShelf(book.Shelf{Room: "Salón", Bookcase: "Izquierda", Name: "Arriba"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (*bookDAO).SetBookLocation(bookID, book.Location{ShelfID: shelfID, Position: 1}); err != nil {
+		t.Fatal(err)
+	}
+	workID, err := (*bookDAO).CreateWork(book.Work{Title: "Rayuela", Author: "Julio Cortázar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (*bookDAO).UpdateEdition(bookID, workID, book.Edition{Publisher: "Sudamericana", Year: 1963}); err != nil {
+		t.Fatal(err)
+	}
+
+	bookInfo, err := (*bookDAO).GetBookByID(bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bookDAO, bookInfo
+}
+
+func TestModifyBookNeedsAnAdmin(t *testing.T) {
+	for _, userID := range []string{"", "visitor"} {
+		bookDAO, before := newModifyTestDAO(t)
+
+		w := postMultipartForm(t, ModifyBook, bookDAO, sessionCookie(t, userID), url.Values{
+			"book_id":        {fmt.Sprint(before.ID)},
+			"title":          {"Hopscotch"},
+			"author":         {"J. Cortázar"},
+			"isbn":           {""},
+			"reading_status": {""},
+			"work_id":        {newWork},
+			"tags":           {"argentina"},
+		}, []byte("not an image"))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("user %q: ModifyBook answered %d, want %d", userID, w.Code, http.StatusForbidden)
+		}
+
+		after, err := (*bookDAO).GetBookByID(before.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(after, before) {
+			t.Errorf("user %q changed the book to %+v, want %+v", userID, after, before)
+		}
+
+		works, err := (*bookDAO).GetAllWorks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(works) != 1 {
+			t.Errorf("user %q changed the works to %+v", userID, works)
+		}
+	}
+}
+
+func TestModifyBookKeepsTheFieldsTheFormLeavesOut(t *testing.T) {
+	bookDAO, before := newModifyTestDAO(t)
+
+	w := postMultipartForm(t, ModifyBook, bookDAO, sessionCookie(t, "admin"), url.Values{
+		"book_id": {fmt.Sprint(before.ID)},
+		"title":   {"Rayuela (edición conmemorativa)"},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("ModifyBook answered %d: %s", w.Code, w.Body)
+	}
+
+	after, err := (*bookDAO).GetBookByID(before.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Title != "Rayuela (edición conmemorativa)" {
+		t.Errorf("the title is %q", after.Title)
+	}
+
+	after.Title = before.Title
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("the book is %+v, want %+v with only the title changed", after, before)
+	}
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"leonlib/internal/captcha"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateInputLayout is the format of the date inputs of the forms.
+const dateInputLayout = "2006-01-02"
+
+type PageVariablesForLoans struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	catalog.LoansReport
+	// Borrowers are the people books have been lent to, to suggest them in the forms.
+	Borrowers []string
+	Today     string
+}
+
+type PageVariablesForBookLoans struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Book         book.BookInfo
+	// Loans is the loan history of the book, the latest first.
+	Loans     []book.Loan
+	Borrowers []string
+	Today     string
+}
+
+// borrowersOf returns the different borrowers of the loans, sorted.
+func borrowersOf(loans []book.Loan) []string {
+	seen := map[string]bool{}
+	var borrowers []string
+	for _, loan := range loans {
+		if key := strings.ToLower(loan.Borrower); !seen[key] {
+			seen[key] = true
+			borrowers = append(borrowers, loan.Borrower)
+		}
+	}
+	sort.Slice(borrowers, func(i, j int) bool {
+		return strings.ToLower(borrowers[i]) < strings.ToLower(borrowers[j])
+	})
+
+	return borrowers
+}
+
+// LoansPage shows the books that are lent, the overdue ones first, and lends new ones.
+func LoansPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	loans, err := (*dao).GetAllLoans()
+	if err != nil {
+		log.Printf("error getting loans: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	pageVariables := PageVariablesForLoans{
+		Year:         now.Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		LoggedIn:     true,
+		IsAdmin:      true,
+		UseAnalytics: useAnalytics,
+		Borrowers:    borrowersOf(loans),
+		Today:        now.Format(dateInputLayout),
+	}
+
+	pageVariables.LoansReport, err = catalog.LentBooks(*dao, now)
+	if err != nil {
+		log.Printf("error getting the lent books: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	renderTemplate(w, "loans.html", pageVariables)
+}
+
+func readBookID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["book_id"])
+}
+
+// BookLoansPage shows the loan history of a book, /admin/book/{book_id}/loans, to lend it, return it
+// and correct its loans.
+func BookLoansPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	bookID, err := readBookID(r)
+	if err != nil {
+		redirectToErrorPage(w, r)
+		return
+	}
+
+	bookInfo, err := (*dao).GetBookByID(bookID)
+	if err != nil {
+		log.Printf("error getting book %d: %v", bookID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "Libro no encontrado", http.StatusNotFound)
+		return
+	}
+
+	loans, err := (*dao).GetLoansByBookID(bookID)
+	if err != nil {
+		log.Printf("error getting the loans of book %d: %v", bookID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	allLoans, err := (*dao).GetAllLoans()
+	if err != nil {
+		log.Printf("error getting loans: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	pageVariables := PageVariablesForBookLoans{
+		Year:         now.Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		LoggedIn:     true,
+		IsAdmin:      true,
+		UseAnalytics: useAnalytics,
+		Book:         bookInfo,
+		Loans:        loans,
+		Borrowers:    borrowersOf(allLoans),
+		Today:        now.Format(dateInputLayout),
+	}
+
+	renderTemplate(w, "book_loans.html", pageVariables)
+}
+
+// readDate reads a date input of a form, zero when it is empty.
+func readDate(r *http.Request, name string) (time.Time, error) {
+	return parseDateInput(r.FormValue(name))
+}
+
+// parseDateInput parses the value of a date input, zero when it is empty.
+func parseDateInput(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	date, err := time.Parse(dateInputLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q no es una fecha válida", value)
+	}
+
+	return date, nil
+}
+
+// readLoan reads the borrower, the dates and the notes of the loan forms.
+func readLoan(r *http.Request) (book.Loan, error) {
+	loan := book.Loan{
+		Borrower: strings.TrimSpace(r.FormValue("borrower")),
+		Notes:    strings.TrimSpace(r.FormValue("notes")),
+	}
+
+	var err error
+	if loan.LoanedOn, err = readDate(r, "loaned_on"); err != nil {
+		return book.Loan{}, err
+	}
+	if loan.LoanedOn.IsZero() {
+		loan.LoanedOn = book.Day(time.Now())
+	}
+
+	if loan.DueOn, err = readDate(r, "due_on"); err != nil {
+		return book.Loan{}, err
+	}
+
+	if loan.ReturnedOn, err = readDate(r, "returned_on"); err != nil {
+		return book.Loan{}, err
+	}
+
+	return loan, nil
+}
+
+// redirectAfterLoan goes back to the page the loan form was on, the loans of the book when it does
+// not say.
+func redirectAfterLoan(w http.ResponseWriter, r *http.Request, bookID int) {
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/admin/") {
+		next = fmt.Sprintf("/admin/book/%d/loans", bookID)
+	}
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// LendBook saves the lend form.
+func LendBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	loan, err := readLoan(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loan.BookID, err = strconv.Atoi(strings.TrimSpace(r.FormValue("book_id")))
+	if err != nil {
+		http.Error(w, "Falta el libro que se presta", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := (*dao).CreateLoan(loan); err != nil {
+		log.Printf("error lending book %d: %v", loan.BookID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectAfterLoan(w, r, loan.BookID)
+}
+
+func readLoanID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["loan_id"])
+}
+
+// ReturnLoan records that a lent book is back, on the returned_on of the form or today.
+func ReturnLoan(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	loanID, err := readLoanID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	returnedOn, err := readDate(r, "returned_on")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if returnedOn.IsZero() {
+		returnedOn = book.Day(time.Now())
+	}
+
+	if err := (*dao).ReturnLoan(loanID, returnedOn); err != nil {
+		log.Printf("error returning loan %d: %v", loanID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bookID, _ := strconv.Atoi(r.FormValue("book_id"))
+	redirectAfterLoan(w, r, bookID)
+}
+
+// UpdateLoan saves the edit form of a loan of the loan history.
+func UpdateLoan(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	loanID, err := readLoanID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loan, err := readLoan(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	loan.ID = loanID
+
+	if err := (*dao).UpdateLoan(loan); err != nil {
+		log.Printf("error updating loan %d: %v", loanID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bookID, _ := strconv.Atoi(r.FormValue("book_id"))
+	redirectAfterLoan(w, r, bookID)
+}
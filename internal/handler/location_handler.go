@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"leonlib/internal/captcha"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShelfRoom is a room of the shelves page with its bookcases.
+type ShelfRoom struct {
+	Name      string
+	Bookcases []ShelfBookcase
+}
+
+// ShelfBookcase is a bookcase of the shelves page with its shelves.
+type ShelfBookcase struct {
+	Name    string
+	Shelves []book.Shelf
+}
+
+type PageVariablesForShelves struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Rooms        []ShelfRoom
+}
+
+type PageVariablesForShelf struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Shelf        book.Shelf
+	// Books are the books of the shelf from left to right.
+	Books []book.BookInfo
+	// Shelves are all of them, the ones the books can be moved to.
+	Shelves []book.Shelf
+}
+
+// groupShelves puts the shelves, sorted by room and bookcase, in their rooms and bookcases.
+func groupShelves(shelves []book.Shelf) []ShelfRoom {
+	var rooms []ShelfRoom
+	for _, shelf := range shelves {
+		if len(rooms) == 0 || !strings.EqualFold(rooms[len(rooms)-1].Name, shelf.Room) {
+			rooms = append(rooms, ShelfRoom{Name: shelf.Room})
+		}
+
+		room := &rooms[len(rooms)-1]
+		if len(room.Bookcases) == 0 || !strings.EqualFold(room.Bookcases[len(room.Bookcases)-1].Name, shelf.Bookcase) {
+			room.Bookcases = append(room.Bookcases, ShelfBookcase{Name: shelf.Bookcase})
+		}
+
+		bookcase := &room.Bookcases[len(room.Bookcases)-1]
+		bookcase.Shelves = append(bookcase.Shelves, shelf)
+	}
+
+	return rooms
+}
+
+// ShelvesPage lists the shelves by room and bookcase, with how many books each of them has.
+func ShelvesPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	shelves, err := (*dao).GetAllShelves()
+	if err != nil {
+		log.Printf("error getting shelves: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	pageVariables := PageVariablesForShelves{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		UseAnalytics: useAnalytics,
+		Rooms:        groupShelves(shelves),
+	}
+
+	pageResults := PageResultsVariables{}
+	setAuthenticationForPageResults(r, &pageResults, dao)
+	pageVariables.LoggedIn, pageVariables.IsAdmin = pageResults.LoggedIn, pageResults.IsAdmin
+
+	renderTemplate(w, "shelves.html", pageVariables)
+}
+
+func readShelfID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["shelf_id"])
+}
+
+// ShelfPage shows a shelf, /shelf/{shelf_id}, with its books in the order they are on it.
+func ShelfPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	shelfID, err := readShelfID(r)
+	if err != nil {
+		redirectToErrorPage(w, r)
+		return
+	}
+
+	shelf, err := (*dao).GetShelfByID(shelfID)
+	if err != nil {
+		log.Printf("error getting shelf %d: %v", shelfID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	} else if shelf.ID == 0 {
+		redirectToErrorPageWithMessageAndStatusCode(w, "Estante no encontrado", http.StatusNotFound)
+		return
+	}
+
+	books, err := (*dao).GetBooksByShelf(shelfID)
+	if err != nil {
+		log.Printf("error getting the books of shelf %d: %v", shelfID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	pageVariables := PageVariablesForShelf{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		UseAnalytics: useAnalytics,
+		Shelf:        shelf,
+		Books:        books,
+	}
+
+	pageResults := PageResultsVariables{}
+	setAuthenticationForPageResults(r, &pageResults, dao)
+	pageVariables.LoggedIn, pageVariables.IsAdmin = pageResults.LoggedIn, pageResults.IsAdmin
+
+	if pageVariables.IsAdmin {
+		pageVariables.Shelves, err = (*dao).GetAllShelves()
+		if err != nil {
+			log.Printf("error getting shelves: %v", err)
+			redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	renderTemplate(w, "shelf.html", pageVariables)
+}
+
+// readShelf reads the room, bookcase and name of the shelf forms, the name is required.
+func readShelf(r *http.Request) (book.Shelf, error) {
+	shelf := book.Shelf{
+		Room:     strings.TrimSpace(r.FormValue("room")),
+		Bookcase: strings.TrimSpace(r.FormValue("bookcase")),
+		Name:     strings.TrimSpace(r.FormValue("name")),
+	}
+	if shelf.Name == "" {
+		return book.Shelf{}, fmt.Errorf("el estante necesita un nombre")
+	}
+
+	return shelf, nil
+}
+
+// CreateShelf saves the new shelf form of the shelves page.
+func CreateShelf(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	shelf, err := readShelf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shelfID, err := (*dao).CreateShelf(shelf)
+	if err != nil {
+		log.Printf("error creating shelf %s: %v", shelf, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/shelf/%d", shelfID), http.StatusSeeOther)
+}
+
+// UpdateShelf saves the edit form of the shelf page.
+func UpdateShelf(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	shelfID, err := readShelfID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shelf, err := readShelf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shelf.ID = shelfID
+
+	if err := (*dao).UpdateShelf(shelf); err != nil {
+		log.Printf("error updating shelf %d: %v", shelfID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/shelf/%d", shelfID), http.StatusSeeOther)
+}
+
+// DeleteShelf removes a shelf, its books are left without a location.
+func DeleteShelf(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	shelfID, err := readShelfID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := (*dao).DeleteShelf(shelfID); err != nil {
+		log.Printf("error deleting shelf %d: %v", shelfID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/shelves", http.StatusSeeOther)
+}
+
+// readBookIDs reads the book IDs of the reorganize form, separated by commas, spaces or lines.
+func readBookIDs(text string) ([]int, error) {
+	fields := strings.FieldsFunc(text, func(c rune) bool {
+		return c == ',' || c == ';' || c == ' ' || c == '\t' || c == '\r' || c == '\n'
+	})
+
+	bookIDs := make([]int, 0, len(fields))
+	for _, field := range fields {
+		bookID, err := strconv.Atoi(field)
+		if err != nil || bookID <= 0 {
+			return nil, fmt.Errorf("%q no es el ID de un libro", field)
+		}
+		bookIDs = append(bookIDs, bookID)
+	}
+
+	return bookIDs, nil
+}
+
+// RelocateShelfBooks records a reorganized shelf: the books of the form, in its order, are what the
+// shelf has now. The books that were on it and are not in the form are left without a location.
+func RelocateShelfBooks(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	shelfID, err := readShelfID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bookIDs, err := readBookIDs(r.FormValue("books"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := (*dao).RelocateBooks(shelfID, bookIDs); err != nil {
+		log.Printf("error relocating the books of shelf %d: %v", shelfID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/shelf/%d", shelfID), http.StatusSeeOther)
+}
+
+// MoveShelfBooks moves every book of a shelf to the end of another one, keeping their order.
+func MoveShelfBooks(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	shelfID, err := readShelfID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	targetID, err := strconv.Atoi(r.FormValue("target_id"))
+	if err != nil || targetID == shelfID {
+		http.Error(w, "Elige otro estante", http.StatusBadRequest)
+		return
+	}
+
+	var bookIDs []int
+	for _, id := range []int{targetID, shelfID} {
+		books, err := (*dao).GetBooksByShelf(id)
+		if err != nil {
+			log.Printf("error getting the books of shelf %d: %v", id, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, bookInfo := range books {
+			bookIDs = append(bookIDs, bookInfo.ID)
+		}
+	}
+
+	if err := (*dao).RelocateBooks(targetID, bookIDs); err != nil {
+		log.Printf("error moving the books of shelf %d to %d: %v", shelfID, targetID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/shelf/%d", targetID), http.StatusSeeOther)
+}
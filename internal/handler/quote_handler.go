@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"leonlib/internal/captcha"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PageVariablesForQuotes struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	// Search and Tag are the ?q= and ?tag= the quotes were found with.
+	Search string
+	Tag    string
+	Books  []catalog.QuotedBook
+	// Quotes is how many quotes Books have.
+	Quotes int
+}
+
+// QuoteOfTheDay is the random quote of the index page with the book it is from.
+type QuoteOfTheDay struct {
+	book.Quote
+	Book book.BookInfo
+}
+
+// findQuotes returns the quotes of a book when bookID is set, otherwise the ones that match the search
+// and have the tag; without search nor tag, all of them.
+func findQuotes(dao *dao.DAO, search, tag string, bookID int) ([]book.Quote, error) {
+	if bookID > 0 {
+		return (*dao).GetQuotesByBookID(bookID)
+	}
+
+	quotes, err := (*dao).SearchQuotes(search)
+	if err != nil || tag == "" {
+		return quotes, err
+	}
+
+	tagged := []book.Quote{}
+	for _, quote := range quotes {
+		if quote.HasTag(tag) {
+			tagged = append(tagged, quote)
+		}
+	}
+
+	return tagged, nil
+}
+
+// randomQuote picks the quote of the index page, an empty one when there are no quotes.
+func randomQuote(dao *dao.DAO) (QuoteOfTheDay, error) {
+	quote, err := (*dao).GetRandomQuote()
+	if err != nil || quote.ID == 0 {
+		return QuoteOfTheDay{}, err
+	}
+
+	bookInfo, err := (*dao).GetBookByID(quote.BookID)
+	if err != nil {
+		return QuoteOfTheDay{}, err
+	}
+
+	return QuoteOfTheDay{Quote: quote, Book: bookInfo}, nil
+}
+
+// QuotesPage searches the quotes of every book, /quotes?q=texto&tag=etiqueta.
+func QuotesPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+	tag := book.NormalizeTag(r.URL.Query().Get("tag"))
+
+	quotes, err := findQuotes(dao, search, tag, 0)
+	if err != nil {
+		log.Printf("error searching quotes: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	quotedBooks, err := catalog.QuotesByBook(*dao, quotes)
+	if err != nil {
+		log.Printf("error getting the books of the quotes: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = getCurrentUserID(r)
+	pageVariables := PageVariablesForQuotes{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		LoggedIn:     err == nil,
+		IsAdmin:      isAdminUser(r, dao),
+		UseAnalytics: useAnalytics,
+		Search:       search,
+		Tag:          tag,
+		Books:        quotedBooks,
+		Quotes:       len(quotes),
+	}
+
+	renderTemplate(w, "quotes.html", pageVariables)
+}
+
+// ExportQuotesMarkdown downloads quotes as Markdown: the ones of a book (id), the ones of a search (q
+// and tag) or, without parameters, all of them.
+func ExportQuotesMarkdown(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fileName := "leonlib-citas"
+
+	bookID := 0
+	if query.Get("id") != "" {
+		var err error
+		if bookID, err = strconv.Atoi(query.Get("id")); err != nil {
+			http.Error(w, "invalid book id", http.StatusBadRequest)
+			return
+		}
+		fileName = fmt.Sprintf("leonlib-citas-%d", bookID)
+	}
+
+	quotes, err := findQuotes(dao, query.Get("q"), book.NormalizeTag(query.Get("tag")), bookID)
+	if err != nil {
+		log.Printf("error exporting quotes: %v", err)
+		http.Error(w, "error getting info from the database", http.StatusInternalServerError)
+		return
+	}
+
+	quotedBooks, err := catalog.QuotesByBook(*dao, quotes)
+	if err != nil {
+		log.Printf("error exporting quotes: %v", err)
+		http.Error(w, "error getting info from the database", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, fileName))
+
+	if err := catalog.WriteQuotesMarkdown(w, quotedBooks); err != nil {
+		log.Printf("error exporting quotes: %v", err)
+	}
+}
+
+func readQuoteID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["quote_id"])
+}
+
+// readQuote reads the text, the page and the tags of the quote forms.
+func readQuote(r *http.Request) (book.Quote, error) {
+	quote := book.Quote{
+		Text: strings.TrimSpace(r.FormValue("quote")),
+		Tags: book.ParseTags(r.FormValue("tags")),
+	}
+
+	if page := strings.TrimSpace(r.FormValue("page")); page != "" {
+		var err error
+		if quote.Page, err = strconv.Atoi(page); err != nil || quote.Page < 0 {
+			return book.Quote{}, fmt.Errorf("%q no es una página válida", page)
+		}
+	}
+
+	if quote.Text == "" {
+		return book.Quote{}, fmt.Errorf("falta el texto de la cita")
+	}
+
+	return quote, nil
+}
+
+// redirectAfterQuote goes back to the page the quote form was on, the quotes of the book page when
+// it does not say.
+func redirectAfterQuote(w http.ResponseWriter, r *http.Request, bookID int) {
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = fmt.Sprintf("/book_info?id=%d#quotes", bookID)
+	}
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// CreateQuote saves a new quote of a book, /admin/book/{book_id}/quotes.
+func CreateQuote(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	bookID, err := readBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	quote, err := readQuote(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quote.BookID = bookID
+
+	if _, err := (*dao).CreateQuote(quote); err != nil {
+		log.Printf("error saving a quote of book %d: %v", bookID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectAfterQuote(w, r, bookID)
+}
+
+// UpdateQuote saves the edit form of a quote.
+func UpdateQuote(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	quoteID, err := readQuoteID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	quote, err := readQuote(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quote.ID = quoteID
+
+	previous, err := (*dao).GetQuoteByID(quoteID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := (*dao).UpdateQuote(quote); err != nil {
+		log.Printf("error updating quote %d: %v", quoteID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectAfterQuote(w, r, previous.BookID)
+}
+
+// DeleteQuote deletes a quote.
+func DeleteQuote(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	quoteID, err := readQuoteID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	quote, err := (*dao).GetQuoteByID(quoteID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := (*dao).DeleteQuote(quoteID); err != nil {
+		log.Printf("error deleting quote %d: %v", quoteID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	redirectAfterQuote(w, r, quote.BookID)
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"fmt"
+	"html/template"
+	"leonlib/internal/captcha"
+	"leonlib/internal/dao"
+	"leonlib/internal/markdown"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RenderedReview is a review with its Markdown as HTML, safe to put in a page.
+type RenderedReview struct {
+	book.Review
+	HTML template.HTML
+}
+
+func renderReview(review book.Review) RenderedReview {
+	return RenderedReview{Review: review, HTML: markdown.ToHTML(review.Text)}
+}
+
+// ReviewedBook is a book of the "my reviews" page with the review of the user.
+type ReviewedBook struct {
+	Book   book.BookInfo
+	Review RenderedReview
+}
+
+type PageVariablesForMyReviews struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	Books        []ReviewedBook
+}
+
+// bookReviews returns the reviews of a book for its page: the ones of everyone without their notes,
+// and the one of the user of the request with them. It is empty when nobody is logged in.
+func bookReviews(dao *dao.DAO, r *http.Request, bookID int) ([]RenderedReview, RenderedReview, error) {
+	reviews, err := (*dao).GetReviewsByBookID(bookID)
+	if err != nil {
+		return nil, RenderedReview{}, err
+	}
+
+	var rendered []RenderedReview
+	for _, review := range reviews {
+		review.Notes = ""
+		if review.Text == "" && review.Rating == 0 {
+			continue
+		}
+		rendered = append(rendered, renderReview(review))
+	}
+
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		return rendered, RenderedReview{}, nil
+	}
+
+	myReview, err := (*dao).GetReview(bookID, userID)
+	if err != nil {
+		return nil, RenderedReview{}, err
+	}
+
+	return rendered, renderReview(myReview), nil
+}
+
+// readReview reads the rating, the review and the notes of the review form.
+func readReview(r *http.Request) (book.Review, error) {
+	rating, err := book.ParseRating(r.FormValue("rating"))
+	if err != nil {
+		return book.Review{}, fmt.Errorf("%q no es una calificación válida", r.FormValue("rating"))
+	}
+
+	return book.Review{
+		Rating: rating,
+		Text:   strings.TrimSpace(r.FormValue("review")),
+		Notes:  strings.TrimSpace(r.FormValue("notes")),
+	}, nil
+}
+
+// SaveReview saves the review form of the book page, /book/{book_id}/review, for the user that is
+// logged in. A form left empty deletes the review.
+func SaveReview(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	bookID, err := readBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review, err := readReview(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review.BookID = bookID
+	review.UserID = userID
+
+	if err := (*dao).SaveReview(review); err != nil {
+		log.Printf("error saving the review of book %d: %v", bookID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectAfterReview(w, r, bookID)
+}
+
+// DeleteReview deletes the review of a book by the user that is logged in.
+func DeleteReview(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	bookID, err := readBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := (*dao).DeleteReview(bookID, userID); err != nil {
+		log.Printf("error deleting the review of book %d: %v", bookID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	redirectAfterReview(w, r, bookID)
+}
+
+// redirectAfterReview goes back to the "my reviews" page when the form was sent from it, to the
+// reviews of the book page otherwise.
+func redirectAfterReview(w http.ResponseWriter, r *http.Request, bookID int) {
+	next := fmt.Sprintf("/book_info?id=%d#reviews", bookID)
+	if r.FormValue("next") == "/my_reviews" {
+		next = "/my_reviews"
+	}
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// MyReviewsPage lists the books the user that is logged in has reviewed, with their notes.
+func MyReviewsPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	userID, err := getCurrentUserID(r)
+	if err != nil {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	reviews, err := (*dao).GetReviewsByUserID(userID)
+	if err != nil {
+		log.Printf("error getting the reviews of %s: %v", userID, err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	pageVariables := PageVariablesForMyReviews{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		LoggedIn:     true,
+		IsAdmin:      isAdminUser(r, dao),
+		UseAnalytics: useAnalytics,
+	}
+
+	for _, review := range reviews {
+		bookInfo, err := (*dao).GetBookByID(review.BookID)
+		if err != nil {
+			log.Printf("error getting book %d: %v", review.BookID, err)
+			redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+			return
+		}
+
+		pageVariables.Books = append(pageVariables.Books, ReviewedBook{Book: bookInfo, Review: renderReview(review)})
+	}
+
+	renderTemplate(w, "my_reviews.html", pageVariables)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"leonlib/internal/captcha"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"time"
+)
+
+type PageVariablesForTags struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	UseAnalytics bool
+	Tags         []book.Tag
+	Categories   []*book.CategoryNode
+}
+
+// TagsPage lists the tags and the category tree, each of them links to the search of its books.
+func TagsPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	tags, err := (*dao).GetAllTags()
+	if err != nil {
+		log.Printf("error getting tags: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	categories, err := (*dao).GetAllCategories()
+	if err != nil {
+		log.Printf("error getting categories: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	pageVariables := PageVariablesForTags{
+		Year:         time.Now().Format("2006"),
+		SiteKey:      captcha.SiteKey,
+		UseAnalytics: useAnalytics,
+		Tags:         tags,
+		Categories:   book.CategoryTree(categories),
+	}
+
+	pageResults := PageResultsVariables{}
+	setAuthenticationForPageResults(r, &pageResults, dao)
+	pageVariables.LoggedIn = pageResults.LoggedIn
+
+	renderTemplate(w, "tags.html", pageVariables)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"fmt"
+	"leonlib/internal/captcha"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	"log"
+	"net/http"
+	"time"
+)
+
+type PageVariablesForValuation struct {
+	Year         string
+	SiteKey      string
+	LoggedIn     bool
+	IsAdmin      bool
+	UseAnalytics bool
+	catalog.ValuationReport
+	// Today is the day the valuation is made, the printed summary has it.
+	Today string
+}
+
+// showValuation renders a template of the valuation of the library, which only admins see.
+func showValuation(dao *dao.DAO, w http.ResponseWriter, r *http.Request, templateName string) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	report, err := catalog.Valuation(*dao)
+	if err != nil {
+		log.Printf("error valuing the library: %v", err)
+		redirectToErrorPageWithMessageAndStatusCode(w, "error getting information from the database", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	pageVariables := PageVariablesForValuation{
+		Year:            now.Format("2006"),
+		SiteKey:         captcha.SiteKey,
+		LoggedIn:        true,
+		IsAdmin:         true,
+		UseAnalytics:    useAnalytics,
+		ValuationReport: report,
+		Today:           now.Format(dateInputLayout),
+	}
+
+	renderTemplate(w, templateName, pageVariables)
+}
+
+// ValuationPage shows what the books are worth for the insurance, by book, author and category.
+func ValuationPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	showValuation(dao, w, r, "valuation.html")
+}
+
+// PrintValuationPage is the summary of the valuation to print and hand to the insurance.
+func PrintValuationPage(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	showValuation(dao, w, r, "valuation_print.html")
+}
+
+// ExportValuationCSV downloads the valuation as CSV, a row for each book or, with ?by=author or
+// ?by=category, for each author or category.
+func ExportValuationCSV(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	by, ok := catalog.ParseValuationBy(r.URL.Query().Get("by"))
+	if !ok {
+		http.Error(w, "by must be book, author or category", http.StatusBadRequest)
+		return
+	}
+
+	report, err := catalog.Valuation(*dao)
+	if err != nil {
+		log.Printf("error valuing the library: %v", err)
+		http.Error(w, "error getting info from the database", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="leonlib-valuacion-%s.csv"`, by))
+
+	if err := catalog.WriteValuationCSV(w, report, by); err != nil {
+		log.Printf("error exporting the valuation: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"leonlib/internal/catalog"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wishListFormTemplate has the fields of the forms of the wish lists, the one of the library and the
+// ones of the users.
+const wishListFormTemplate = "wishlist_form.html"
+
+func readWishListBookID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["wish_id"])
+}
+
+// getWishListBookOf returns a book of the wish list of a user, the one of the library when ownerID
+// is empty. A book of another list is as missing as one that does not exist.
+func getWishListBookOf(dao *dao.DAO, id int, ownerID string) (book.WishListBook, error) {
+	wishListBook, err := (*dao).GetWishListBookByID(id)
+	if err != nil {
+		return book.WishListBook{}, err
+	}
+
+	if wishListBook.UserID != ownerID {
+		return book.WishListBook{}, fmt.Errorf("wish list book %d does not exist", id)
+	}
+
+	return wishListBook, nil
+}
+
+// readWishListBook reads the title, the author, the description, the links and the priority of the
+// wish list forms.
+func readWishListBook(r *http.Request) (book.WishListBook, error) {
+	priority, ok := book.ParsePriority(r.FormValue("priority"))
+	if !ok {
+		return book.WishListBook{}, fmt.Errorf("%q no es una prioridad válida", r.FormValue("priority"))
+	}
+
+	wishListBook := book.WishListBook{
+		Title:         strings.TrimSpace(r.FormValue("title")),
+		Author:        strings.TrimSpace(r.FormValue("author")),
+		Description:   strings.TrimSpace(r.FormValue("description")),
+		ImageLink:     strings.TrimSpace(r.FormValue("image_link")),
+		GoodreadsLink: strings.TrimSpace(r.FormValue("goodreads_link")),
+		Priority:      priority,
+	}
+
+	if wishListBook.Title == "" {
+		return book.WishListBook{}, fmt.Errorf("falta el título")
+	}
+
+	return wishListBook, nil
+}
+
+// CreateWishListBook adds a book to the wish list, /admin/wishlist.
+func CreateWishListBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBook, err := readWishListBook(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := (*dao).CreateWishListBook(wishListBook); err != nil {
+		log.Printf("error adding %q to the wish list: %v", wishListBook.Title, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/wishlist", http.StatusSeeOther)
+}
+
+// UpdateWishListBook saves the edit form of a book of the wish list, its priority among the rest.
+func UpdateWishListBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBookID, err := readWishListBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wishListBook, err := readWishListBook(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wishListBook.ID = wishListBookID
+
+	if _, err := getWishListBookOf(dao, wishListBookID, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := (*dao).UpdateWishListBook(wishListBook); err != nil {
+		log.Printf("error updating wish list book %d: %v", wishListBookID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/wishlist#wish-%d", wishListBookID), http.StatusSeeOther)
+}
+
+// DeleteWishListBook takes a book out of the wish list.
+func DeleteWishListBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBookID, err := readWishListBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := getWishListBookOf(dao, wishListBookID, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := (*dao).DeleteWishListBook(wishListBookID); err != nil {
+		log.Printf("error deleting wish list book %d: %v", wishListBookID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/wishlist", http.StatusSeeOther)
+}
+
+// PurchaseWishListBook is the "I bought it" of the wish list: the book goes to the catalogue with
+// its cover, bought today, and the page of the new book opens to finish cataloguing it.
+func PurchaseWishListBook(dao *dao.DAO, w http.ResponseWriter, r *http.Request) {
+	if !isAdminUser(r, dao) {
+		redirectToErrorLoginPage(w)
+		return
+	}
+
+	wishListBookID, err := readWishListBookID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := getWishListBookOf(dao, wishListBookID, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	purchased, err := catalog.PurchaseWishListBook(*dao, wishListBookID, time.Now())
+	if err != nil {
+		log.Printf("error moving wish list book %d to the catalogue: %v", wishListBookID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if purchased.CoverErr != nil {
+		log.Printf("book %d, from wish list book %d, has no cover: %v", purchased.BookID, wishListBookID, purchased.CoverErr)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/book_info?id=%d", purchased.BookID), http.StatusSeeOther)
+}
@@ -0,0 +1,261 @@
+// imagestore keeps the image bytes addressed by their SHA-256, so the same photo is stored only once
+// no matter how many times it is uploaded.
+package imagestore
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var ErrNotFound = errors.New("image not found in the store")
+
+// Store saves blobs under the hex SHA-256 of their content. Put is idempotent.
+type Store interface {
+	Put(data []byte) (string, error)
+	Get(hash string) ([]byte, error)
+	Delete(hash string) error
+	Hashes() ([]string, error)
+}
+
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func validHash(hash string) error {
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("invalid image hash %q", hash)
+	}
+
+	return nil
+}
+
+// FileStore keeps every blob in its own file, dir/sha256/ab/abcdef..., so it can live inside the
+// images/ directory next to the files referenced by the TOML library.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: filepath.Join(dir, "sha256")}
+}
+
+func (s *FileStore) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+func (s *FileStore) Put(data []byte) (string, error) {
+	hash := Hash(data)
+	path := s.path(hash)
+
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	// Renaming a complete temporary file means readers never see half written images.
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	return hash, os.Rename(tmp.Name(), path)
+}
+
+func (s *FileStore) Get(hash string) ([]byte, error) {
+	if err := validHash(hash); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+func (s *FileStore) Delete(hash string) error {
+	if err := validHash(hash); err != nil {
+		return err
+	}
+
+	err := os.Remove(s.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *FileStore) Hashes() ([]string, error) {
+	var hashes []string
+
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == s.dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && validHash(d.Name()) == nil {
+			hashes = append(hashes, d.Name())
+		}
+
+		return nil
+	})
+
+	sort.Strings(hashes)
+
+	return hashes, err
+}
+
+// DBStore keeps the blobs in the image_blobs table of the same database as the books.
+type DBStore struct {
+	db *sql.DB
+}
+
+func NewDBStore(db *sql.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Put(data []byte) (string, error) {
+	hash := Hash(data)
+	_, err := s.db.Exec(`INSERT INTO image_blobs(hash, data) VALUES($1, $2) ON CONFLICT(hash) DO NOTHING`, hash, data)
+
+	return hash, err
+}
+
+func (s *DBStore) Get(hash string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM image_blobs WHERE hash=$1`, hash).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+func (s *DBStore) Delete(hash string) error {
+	_, err := s.db.Exec(`DELETE FROM image_blobs WHERE hash=$1`, hash)
+
+	return err
+}
+
+func (s *DBStore) Hashes() ([]string, error) {
+	rows, err := s.db.Query(`SELECT hash FROM image_blobs ORDER BY hash`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// MemoryStore is the store of the memory mode, its blobs live as long as the process.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Put(data []byte) (string, error) {
+	hash := Hash(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.blobs[hash]; !ok {
+		s.blobs[hash] = data
+	}
+
+	return hash, nil
+}
+
+func (s *MemoryStore) Get(hash string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.blobs[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (s *MemoryStore) Delete(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, hash)
+
+	return nil
+}
+
+func (s *MemoryStore) Hashes() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hashes := make([]string, 0, len(s.blobs))
+	for hash := range s.blobs {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	return hashes, nil
+}
+
+// CollectGarbage deletes the blobs that are not in referenced and returns how many were removed.
+func CollectGarbage(store Store, referenced map[string]bool) (int, error) {
+	hashes, err := store.Hashes()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, hash := range hashes {
+		if referenced[hash] {
+			continue
+		}
+
+		if err := store.Delete(hash); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,150 @@
+// imaging generates the smaller variants of the book images served to listings.
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Variant is a resized copy of an image, the original is served when no variant is requested.
+type Variant string
+
+const (
+	Thumbnail Variant = "thumb"
+	Medium    Variant = "medium"
+)
+
+// Variants are generated for every stored image, smallest first.
+var Variants = []Variant{Thumbnail, Medium}
+
+// Width is the maximum width in pixels of the variant. Listings show covers 150px wide, so the
+// medium variant covers high density screens.
+func (v Variant) Width() int {
+	switch v {
+	case Thumbnail:
+		return 160
+	default:
+		return 320
+	}
+}
+
+func ParseVariant(name string) (Variant, error) {
+	for _, variant := range Variants {
+		if string(variant) == name {
+			return variant, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown image size %q, use thumb or medium", name)
+}
+
+const jpegQuality = 82
+
+// Resize returns the image scaled down to the width of the variant, as a JPEG rotated as its Exif
+// orientation says. Images that are already narrow enough keep their size, and are re-encoded as
+// well so that no metadata is left in the variant.
+func Resize(data []byte, variant Variant) ([]byte, error) {
+	rgba, err := decodeOriented(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return resize(rgba, variant)
+}
+
+// GenerateVariants resizes the image to every variant.
+func GenerateVariants(data []byte) (map[Variant][]byte, error) {
+	rgba, err := decodeOriented(data)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make(map[Variant][]byte, len(Variants))
+	for _, variant := range Variants {
+		resized, err := resize(rgba, variant)
+		if err != nil {
+			return nil, err
+		}
+		variants[variant] = resized
+	}
+
+	return variants, nil
+}
+
+// decodeOriented decodes the image upright, the way the camera was held.
+func decodeOriented(data []byte) (*image.RGBA, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return orient(toRGBA(src), exifOrientation(data)), nil
+}
+
+func resize(src *image.RGBA, variant Variant) ([]byte, error) {
+	if src.Bounds().Dx() > variant.Width() {
+		src = downscale(src, variant.Width())
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// toRGBA flattens the image on a white background, the result is always encoded as JPEG.
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Over)
+
+	return rgba
+}
+
+// downscale averages the source pixels covered by each destination pixel (a box filter), which
+// is all that shrinking photos needs.
+func downscale(src *image.RGBA, width int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	height := srcHeight * width / srcWidth
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+					n++
+				}
+			}
+
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = 0xff
+		}
+	}
+
+	return dst
+}
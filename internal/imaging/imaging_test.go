@@ -0,0 +1,105 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// jpegWithOrientation encodes a width x height JPEG with an Exif segment holding the orientation,
+// as cameras store the photos taken sideways.
+func jpegWithOrientation(t *testing.T, width, height, orientation int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := []byte("II*\x00\x08\x00\x00\x00\x01\x00")
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3)
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], uint16(orientation))
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	data := append([]byte{0xFF, 0xD8}, app1...)
+	data = append(data, segment...)
+
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+func TestVariantsFollowTheExifOrientation(t *testing.T) {
+	tests := []struct {
+		name                string
+		width, height       int
+		orientation         int
+		variant             Variant
+		wantWidth, wantHigh int
+	}{
+		{"upright", 400, 300, 1, Thumbnail, 160, 120},
+		{"rotated 90", 400, 300, 6, Thumbnail, 160, 213},
+		{"rotated 270", 400, 300, 8, Medium, 300, 400},
+		{"rotated 180", 400, 300, 3, Medium, 320, 240},
+		{"narrow and rotated", 100, 60, 6, Thumbnail, 60, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := jpegWithOrientation(t, tt.width, tt.height, tt.orientation)
+			if got := exifOrientation(data); got != tt.orientation {
+				t.Fatalf("the fixture has orientation %d, want %d", got, tt.orientation)
+			}
+
+			resized, err := Resize(data, tt.variant)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config, _, err := image.DecodeConfig(bytes.NewReader(resized))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != tt.wantWidth || config.Height != tt.wantHigh {
+				t.Errorf("variant is %dx%d, want %dx%d", config.Width, config.Height, tt.wantWidth, tt.wantHigh)
+			}
+
+			if bytes.Contains(resized, []byte("Exif")) {
+				t.Error("the variant kept the Exif segment")
+			}
+		})
+	}
+}
+
+func TestGenerateVariantsReencodesEveryVariant(t *testing.T) {
+	data := jpegWithOrientation(t, 120, 90, 6)
+
+	variants, err := GenerateVariants(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, variant := range Variants {
+		if bytes.Equal(variants[variant], data) {
+			t.Errorf("the %s variant is the original image", variant)
+		}
+		if bytes.Contains(variants[variant], []byte("Exif")) {
+			t.Errorf("the %s variant kept the Exif segment", variant)
+		}
+	}
+}
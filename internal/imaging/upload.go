@@ -0,0 +1,199 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+)
+
+const (
+	// MaxUploadSize is the largest image file accepted from the forms.
+	MaxUploadSize = 20 << 20
+	// maxUploadPixels rejects images whose decoding alone would take gigabytes of memory.
+	maxUploadPixels = 50_000_000
+	// maxUploadSide is the longest side stored, bigger photos are scaled down to it.
+	maxUploadSide = 1600
+)
+
+// UploadError is an image rejected by NormalizeUpload, its message is shown to whoever uploaded it.
+type UploadError struct {
+	Reason string
+}
+
+func (e *UploadError) Error() string {
+	return e.Reason
+}
+
+func rejectUpload(format string, args ...any) error {
+	return &UploadError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// NormalizeUpload checks that the uploaded bytes really are a JPEG, PNG or GIF image and turns them
+// into the JPEG that gets stored: rotated as the camera's Exif orientation says and scaled down to
+// maxUploadSide. Re-encoding drops every metadata segment, the GPS position of phone photos included.
+func NormalizeUpload(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, rejectUpload("la imagen está vacía")
+	}
+
+	if len(data) > MaxUploadSize {
+		return nil, rejectUpload("la imagen pesa %.1f MB, el máximo es %d MB", float64(len(data))/(1<<20), MaxUploadSize>>20)
+	}
+
+	switch contentType := http.DetectContentType(data); contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	case "image/webp", "image/bmp", "image/x-icon":
+		return nil, rejectUpload("el formato %s no está soportado, usa JPEG, PNG o GIF", contentType)
+	default:
+		return nil, rejectUpload("el archivo no es una imagen (%s)", contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, rejectUpload("la imagen está dañada o incompleta")
+	}
+
+	if config.Width*config.Height > maxUploadPixels {
+		return nil, rejectUpload("la imagen es demasiado grande (%dx%d píxeles)", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, rejectUpload("la imagen está dañada o incompleta")
+	}
+
+	rgba := orient(toRGBA(src), exifOrientation(data))
+
+	if width, height := rgba.Bounds().Dx(), rgba.Bounds().Dy(); max(width, height) > maxUploadSide {
+		rgba = downscale(rgba, width*maxUploadSide/max(width, height))
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// exifOrientation reads the orientation tag (0x0112) of the Exif segment of a JPEG, 1 (upright)
+// when there is none.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// The image data starts, the metadata segments are all before it.
+			return 1
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + size
+	}
+
+	return 1
+}
+
+// tiffOrientation looks for the orientation tag in the first IFD of the TIFF structure inside Exif.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+
+		if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+
+		return 1
+	}
+
+	return 1
+}
+
+// orient applies an Exif orientation, so the photo shows the way the camera was held: 2 to 4 flip
+// it, 5 to 8 also swap its width and height.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+
+			srcOffset := y*src.Stride + x*4
+			dstOffset := dy*dst.Stride + dx*4
+			copy(dst.Pix[dstOffset:dstOffset+4], src.Pix[srcOffset:srcOffset+4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,134 @@
+// isbn validates ISBN-10 and ISBN-13 numbers and converts them to the ISBN-13 the books are stored with.
+package isbn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalid = errors.New("invalid ISBN")
+
+// Clean removes the hyphens and spaces ISBNs are usually printed with, the typographic dashes and
+// non-breaking spaces of the ones copied from a web page among them, and an "ISBN" prefix.
+func Clean(s string) string {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	s = strings.TrimPrefix(s, "ISBN-13")
+	s = strings.TrimPrefix(s, "ISBN-10")
+	s = strings.TrimPrefix(s, "ISBN")
+	s = strings.TrimPrefix(strings.TrimSpace(s), ":")
+
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Pd, r) || unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, s)
+}
+
+// Normalize validates an ISBN-10 or ISBN-13, printed with or without hyphens, and returns it as
+// the 13 digits of its ISBN-13. An empty ISBN stays empty.
+func Normalize(s string) (string, error) {
+	cleaned := Clean(s)
+
+	switch len(cleaned) {
+	case 0:
+		return "", nil
+	case 10:
+		if !valid10(cleaned) {
+			return "", fmt.Errorf("%w %q: wrong check digit", ErrInvalid, s)
+		}
+
+		return To13(cleaned), nil
+	case 13:
+		if !valid13(cleaned) {
+			return "", fmt.Errorf("%w %q: wrong check digit", ErrInvalid, s)
+		}
+
+		return cleaned, nil
+	default:
+		return "", fmt.Errorf("%w %q: it must have 10 or 13 digits", ErrInvalid, s)
+	}
+}
+
+// Valid reports whether s is a correct ISBN-10 or ISBN-13.
+func Valid(s string) bool {
+	normalized, err := Normalize(s)
+
+	return err == nil && normalized != ""
+}
+
+func valid10(s string) bool {
+	sum := 0
+	for i, r := range s {
+		var digit int
+		switch {
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		case r == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * digit
+	}
+
+	return sum%11 == 0
+}
+
+func valid13(s string) bool {
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return checkDigit13(s[:12]) == s[12]
+}
+
+// checkDigit13 computes the last digit of an ISBN-13 from its first 12 digits.
+func checkDigit13(s string) byte {
+	sum := 0
+	for i, r := range s {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(r-'0')
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
+
+// To13 converts a valid ISBN-10, without hyphens, to its ISBN-13: the 978 prefix and a new check digit.
+func To13(isbn10 string) string {
+	prefix := "978" + isbn10[:9]
+
+	return prefix + string(checkDigit13(prefix))
+}
+
+// To10 converts an ISBN-13 starting with 978 back to its ISBN-10, the form older books and catalogues
+// print. The ISBN-13 starting with 979 have none and return false.
+func To10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+
+	sum := 0
+	for i, r := range isbn13[3:12] {
+		sum += (10 - i) * int(r-'0')
+	}
+
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return isbn13[3:12] + "X", true
+	}
+
+	return isbn13[3:12] + string(rune('0'+check)), true
+}
@@ -0,0 +1,97 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		isbn string
+		want string
+	}{
+		{"empty", "", ""},
+		{"blank", "   ", ""},
+		{"ISBN-13", "9780306406157", "9780306406157"},
+		{"ISBN-13 with hyphens", "978-0-306-40615-7", "9780306406157"},
+		{"ISBN-13 with prefix", "ISBN-13: 978-0-306-40615-7", "9780306406157"},
+		{"ISBN-13 starting with 979", "979-10-90636-07-1", "9791090636071"},
+		{"ISBN-10", "0306406152", "9780306406157"},
+		{"ISBN-10 with spaces and prefix", "isbn 0 306 40615 2", "9780306406157"},
+		{"ISBN-10 ending in X", "0-8044-2957-X", "9780804429573"},
+		{"ISBN-10 ending in lowercase x", "843760494x", "9788437604947"},
+		{"typographic hyphens", "978‐0‐306‐40615–7", "9780306406157"},
+		{"non-breaking spaces", "978 0 306 40615 7", "9780306406157"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.isbn)
+			if err != nil {
+				t.Fatalf("Normalize(%q) failed: %v", tt.isbn, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.isbn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		isbn string
+	}{
+		{"ISBN-13 with a wrong check digit", "9780306406158"},
+		{"ISBN-10 with a wrong check digit", "0306406153"},
+		{"X before the check digit", "03064061X2"},
+		{"X in an ISBN-13", "978030640615X"},
+		{"ISBN-13 without the 978 or 979 prefix", "1234567890128"},
+		{"too short", "030640615"},
+		{"too long", "97803064061570"},
+		{"twelve digits", "978030640615"},
+		{"letters", "ABCDEFGHIJ"},
+		{"full-width digits", "０３０６４０６１５２"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.isbn)
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q) = %q, %v, want ErrInvalid", tt.isbn, got, err)
+			}
+			if Valid(tt.isbn) {
+				t.Errorf("Valid(%q) = true", tt.isbn)
+			}
+		})
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		isbn10 string
+		isbn13 string
+	}{
+		{"0306406152", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"843760494X", "9788437604947"},
+	}
+
+	for _, tt := range tests {
+		if got := To13(tt.isbn10); got != tt.isbn13 {
+			t.Errorf("To13(%q) = %q, want %q", tt.isbn10, got, tt.isbn13)
+		}
+
+		got, ok := To10(tt.isbn13)
+		if !ok || got != tt.isbn10 {
+			t.Errorf("To10(%q) = %q, %t, want %q", tt.isbn13, got, ok, tt.isbn10)
+		}
+	}
+
+	for _, isbn13 := range []string{"9791090636071", "978030640615", ""} {
+		if got, ok := To10(isbn13); ok {
+			t.Errorf("To10(%q) = %q, want no ISBN-10", isbn13, got)
+		}
+	}
+}
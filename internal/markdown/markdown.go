@@ -0,0 +1,238 @@
+// markdown renders the Markdown of the reviews as HTML that is safe to put in a page: every HTML
+// the text has is escaped and only the tags of the Markdown it understands are written. It knows
+// paragraphs, headings, quotes, lists, code, emphasis and links.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// ToHTML renders text as HTML.
+func ToHTML(text string) template.HTML {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var sb strings.Builder
+	renderBlocks(&sb, strings.Split(text, "\n"))
+
+	return template.HTML(sb.String())
+}
+
+// renderBlocks writes the blocks the lines make, separated by blank lines or by the start of a
+// different kind of block.
+func renderBlocks(sb *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "```") {
+				end++
+			}
+			sb.WriteString("<pre><code>")
+			sb.WriteString(html.EscapeString(strings.Join(lines[i+1:min(end, len(lines))], "\n")))
+			sb.WriteString("</code></pre>\n")
+			i = end + 1
+
+		case headingLevel(trimmed) > 0:
+			level := headingLevel(trimmed)
+			tag := "h" + string(rune('0'+level))
+			sb.WriteString("<" + tag + ">")
+			sb.WriteString(renderInline(strings.TrimSpace(trimmed[level:])))
+			sb.WriteString("</" + tag + ">\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			sb.WriteString("<blockquote>\n")
+			renderBlocks(sb, quoted)
+			sb.WriteString("</blockquote>\n")
+
+		case listItem(trimmed, false) != "":
+			i = renderList(sb, lines, i, false)
+
+		case listItem(trimmed, true) != "":
+			i = renderList(sb, lines, i, true)
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && (len(paragraph) == 0 || continuesParagraph(lines[i])); i++ {
+				paragraph = append(paragraph, renderInline(strings.TrimSpace(lines[i])))
+			}
+			sb.WriteString("<p>")
+			sb.WriteString(strings.Join(paragraph, "<br>\n"))
+			sb.WriteString("</p>\n")
+		}
+	}
+}
+
+// continuesParagraph tells if the line goes on the paragraph: it is not blank nor the start of
+// another block.
+func continuesParagraph(line string) bool {
+	trimmed := strings.TrimSpace(line)
+
+	return trimmed != "" && !strings.HasPrefix(trimmed, "```") && headingLevel(trimmed) == 0 &&
+		!strings.HasPrefix(trimmed, ">") && listItem(trimmed, false) == "" && listItem(trimmed, true) == ""
+}
+
+// headingLevel is the level of a "## Heading" line, 0 when the line is not a heading.
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0
+	}
+
+	return level
+}
+
+// listItem is the text of a "- item" line, or of a "1. item" one when ordered; empty when the line
+// is not an item.
+func listItem(line string, ordered bool) string {
+	if !ordered {
+		for _, bullet := range []string{"- ", "* ", "+ "} {
+			if strings.HasPrefix(line, bullet) {
+				return strings.TrimSpace(line[len(bullet):])
+			}
+		}
+
+		return ""
+	}
+
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || !strings.HasPrefix(line[digits:], ". ") {
+		return ""
+	}
+
+	return strings.TrimSpace(line[digits+2:])
+}
+
+// renderList writes the list that starts at lines[i] and returns the line after it.
+func renderList(sb *strings.Builder, lines []string, i int, ordered bool) int {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	sb.WriteString("<" + tag + ">\n")
+	for ; i < len(lines); i++ {
+		item := listItem(strings.TrimSpace(lines[i]), ordered)
+		if item == "" {
+			break
+		}
+		sb.WriteString("<li>" + renderInline(item) + "</li>\n")
+	}
+	sb.WriteString("</" + tag + ">\n")
+
+	return i
+}
+
+// renderInline renders the code, the links and the emphasis of a line, escaping everything else.
+func renderInline(text string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#>-+.!", rune(rest[1])):
+			sb.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			if end := strings.Index(rest[1:], "`"); end >= 0 {
+				sb.WriteString("<code>" + html.EscapeString(rest[1:end+1]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case rest[0] == '[':
+			if label, target, length, ok := link(rest); ok {
+				sb.WriteString(`<a href="` + html.EscapeString(target) + `" rel="nofollow noopener">` + renderInline(label) + "</a>")
+				i += length
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				sb.WriteString("<strong>" + renderInline(rest[2:end+2]) + "</strong>")
+				i += end + 4
+				continue
+			}
+
+		// An underscore inside a word, as in snake_case, is not emphasis.
+		case rest[0] == '*' || (rest[0] == '_' && (i == 0 || !isWordByte(text[i-1]))):
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 && rest[1] != ' ' && rest[end] != ' ' {
+				sb.WriteString("<em>" + renderInline(rest[1:end+1]) + "</em>")
+				i += end + 2
+				continue
+			}
+		}
+
+		sb.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+
+	return sb.String()
+}
+
+// link reads a "[label](target)" at the start of text, with the length it takes. Only web, mail and
+// relative links are links, a "javascript:" one stays text.
+func link(text string) (label, target string, length int, ok bool) {
+	closing := strings.Index(text, "](")
+	if closing < 0 {
+		return "", "", 0, false
+	}
+
+	end := strings.IndexByte(text[closing+2:], ')')
+	if end < 0 {
+		return "", "", 0, false
+	}
+
+	label = text[1:closing]
+	target = strings.TrimSpace(text[closing+2 : closing+2+end])
+	if label == "" || !safeURL(target) {
+		return "", "", 0, false
+	}
+
+	return label, target, closing + 3 + end, true
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func safeURL(target string) bool {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	case "":
+		// Browsers read a backslash as a slash, so "/\host" would leave the site like "//host".
+		return parsed.Host == "" && strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") &&
+			!strings.ContainsRune(target, '\\')
+	default:
+		return false
+	}
+}
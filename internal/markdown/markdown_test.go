@@ -0,0 +1,87 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"paragraph", "Una novela\nmagnífica", "<p>Una novela<br>\nmagnífica</p>\n"},
+		{"heading", "## Resumen", "<h2>Resumen</h2>\n"},
+		{"not a heading", "#hashtag", "<p>#hashtag</p>\n"},
+		{"emphasis", "*muy* **bueno** y __mejor__", "<p><em>muy</em> <strong>bueno</strong> y <strong>mejor</strong></p>\n"},
+		{"snake case", "read_me_first", "<p>read_me_first</p>\n"},
+		{"code", "`a < b`", "<p><code>a &lt; b</code></p>\n"},
+		{"code block", "```\n<b>x</b>\n```", "<pre><code>&lt;b&gt;x&lt;/b&gt;</code></pre>\n"},
+		{"quote", "> cita", "<blockquote>\n<p>cita</p>\n</blockquote>\n"},
+		{"list", "- uno\n- dos", "<ul>\n<li>uno</li>\n<li>dos</li>\n</ul>\n"},
+		{"ordered list", "1. uno\n2. dos", "<ol>\n<li>uno</li>\n<li>dos</li>\n</ol>\n"},
+		{"link", "[Goodreads](https://www.goodreads.com/book/show/1?a=1&b=2)",
+			`<p><a href="https://www.goodreads.com/book/show/1?a=1&amp;b=2" rel="nofollow noopener">Goodreads</a></p>` + "\n"},
+		{"relative link", "[libro](/book_info?id=3)", `<p><a href="/book_info?id=3" rel="nofollow noopener">libro</a></p>` + "\n"},
+		{"escaped", `\*no\*`, "<p>*no*</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(ToHTML(tt.text)); got != tt.want {
+				t.Errorf("ToHTML(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestToHTMLEscapesWhatItDoesNotRender feeds the usual ways around a sanitizer: none of them may
+// leave a tag, an event handler or a script link in the page.
+func TestToHTMLEscapesWhatItDoesNotRender(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"script", "<script>alert(1)</script>"},
+		{"image", `<img src=x onerror="alert(1)">`},
+		{"script in a heading", "# <script>alert(1)</script>"},
+		{"script in a list", "- <svg onload=alert(1)>"},
+		{"script in a quote", "> <iframe src=javascript:alert(1)>"},
+		{"script in emphasis", "**<script>alert(1)</script>**"},
+		{"javascript link", "[x](javascript:alert(1))"},
+		{"javascript link in capitals", "[x](JaVaScRiPt:alert(1))"},
+		{"javascript link with spaces", "[x](  javascript:alert(1))"},
+		{"javascript link with a tab", "[x](java\tscript:alert(1))"},
+		{"javascript link with an entity", "[x](javascript&#58;alert(1))"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)"},
+		{"vbscript link", "[x](vbscript:msgbox(1))"},
+		{"quote in the link", `[x](https://example.com/" onmouseover="alert(1))`},
+		{"quote in a relative link", `[x](/" onmouseover="alert(1))`},
+		{"script in the label", "[<script>alert(1)</script>](https://example.com)"},
+		{"javascript link in the label", "[[x](javascript:alert(1))](https://example.com)"},
+		{"escaped bracket", `\<script>alert(1)</script>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.ToLower(string(ToHTML(tt.text)))
+			for _, unsafe := range []string{"<script", "<img", "<svg", "<iframe", `href="javascript`, `href="data`,
+				`href="vbscript`, `" onmouseover`, `"onmouseover`} {
+				if strings.Contains(got, unsafe) {
+					t.Errorf("ToHTML(%q) = %q, it has %q", tt.text, got, unsafe)
+				}
+			}
+		})
+	}
+}
+
+// TestToHTMLKeepsRelativeLinksOnTheSite checks that the links without a scheme stay in the site:
+// browsers take "//host" and "/\host" as links to another host.
+func TestToHTMLKeepsRelativeLinksOnTheSite(t *testing.T) {
+	for _, text := range []string{"[x](//evil.example)", `[x](/\evil.example)`, `[x](/\/evil.example)`, "[x](evil.example)"} {
+		if got := string(ToHTML(text)); strings.Contains(got, "<a ") {
+			t.Errorf("ToHTML(%q) = %q, want no link", text, got)
+		}
+	}
+}
@@ -0,0 +1,766 @@
+// migrate copies the whole library (authors, works, books, quotes, the wish lists, raw images, users, likes and reviews) from one DAO backend to another.
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"leonlib/internal/dao"
+	book "leonlib/internal/types"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Progress is the checkpoint of a migration. It is saved after every copied entity so an interrupted
+// run resumes where it stopped instead of starting over.
+type Progress struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	LastBookID  int    `json:"lastBookID"`
+	UsersDone   bool   `json:"usersDone"`
+	LastImageID int    `json:"lastImageID"`
+	LikesDone   bool   `json:"likesDone"`
+}
+
+func loadProgress(path, source, destination string) (Progress, error) {
+	progress := Progress{Source: source, Destination: destination}
+	if path == "" {
+		return progress, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return progress, nil
+	} else if err != nil {
+		return progress, err
+	}
+
+	var saved Progress
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return progress, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if saved.Source != source || saved.Destination != destination {
+		return progress, fmt.Errorf("%s belongs to a migration from %s to %s, remove it to start a new one", path, saved.Source, saved.Destination)
+	}
+
+	return saved, nil
+}
+
+func (p Progress) save(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Writing to a temporary file first means a crash never leaves a half written checkpoint.
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// Check compares an entity between both backends.
+type Check struct {
+	Entity              string
+	SourceCount         int
+	DestinationCount    int
+	SourceChecksum      string
+	DestinationChecksum string
+}
+
+func (c Check) OK() bool {
+	return c.SourceCount == c.DestinationCount && c.SourceChecksum == c.DestinationChecksum
+}
+
+func (c Check) String() string {
+	status := "ok"
+	if !c.OK() {
+		status = "MISMATCH"
+	}
+
+	return fmt.Sprintf("%-7s %-8s source=%d (%.12s) destination=%d (%.12s)", c.Entity, status, c.SourceCount, c.SourceChecksum, c.DestinationCount, c.DestinationChecksum)
+}
+
+// Summary tells what a migration copied and how the verification went.
+type Summary struct {
+	Authors int
+	Shelves int
+	Works   int
+	Books   int
+	Loans   int
+	Quotes  int
+	Wishes  int
+	Gifts   int
+	Users   int
+	Images  int
+	Likes   int
+	Reviews int
+	Checks  []Check
+}
+
+func (s Summary) OK() bool {
+	for _, check := range s.Checks {
+		if !check.OK() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s Summary) String() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "copied: %d authors, %d shelves, %d works, %d books, %d loans, %d quotes, %d wishes, %d gifts, %d users, %d images, %d likes, %d reviews\n",
+		s.Authors, s.Shelves, s.Works, s.Books, s.Loans, s.Quotes, s.Wishes, s.Gifts, s.Users, s.Images, s.Likes, s.Reviews)
+	for _, check := range s.Checks {
+		sb.WriteString(check.String())
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// Migrate copies every author, shelf, work, book, loan, wish, gift reservation, image, user and like from source to destination, keeping
+// their IDs, and verifies both backends match at the end. The progress is checkpointed in statePath
+// (when it is not empty); every write is idempotent as well, so running it again after an
+// interruption is safe.
+func Migrate(source, destination dao.DAO, sourceName, destinationName, statePath string, logf func(format string, args ...any)) (Summary, error) {
+	var summary Summary
+
+	progress, err := loadProgress(statePath, sourceName, destinationName)
+	if err != nil {
+		return summary, err
+	}
+
+	if progress.LastBookID > 0 || progress.LastImageID > 0 || progress.UsersDone {
+		logf("resuming: books after id %d, images after id %d, users done=%t, likes done=%t",
+			progress.LastBookID, progress.LastImageID, progress.UsersDone, progress.LikesDone)
+	}
+
+	// The authors, the shelves and the works go first, the books reference them. There are few and copying them
+	// again changes nothing, so they need no checkpoint.
+	if summary.Authors, err = copyAuthors(source, destination); err != nil {
+		return summary, fmt.Errorf("authors: %v", err)
+	}
+	logf("%d authors copied", summary.Authors)
+
+	if summary.Shelves, err = copyShelves(source, destination); err != nil {
+		return summary, fmt.Errorf("shelves: %v", err)
+	}
+	logf("%d shelves copied", summary.Shelves)
+
+	if summary.Works, err = copyWorks(source, destination); err != nil {
+		return summary, fmt.Errorf("works: %v", err)
+	}
+	logf("%d works copied", summary.Works)
+
+	if summary.Books, err = copyBooks(source, destination, &progress, statePath, logf); err != nil {
+		return summary, fmt.Errorf("books: %v", err)
+	}
+
+	// Copying the loans again changes nothing either.
+	if summary.Loans, err = copyLoans(source, destination); err != nil {
+		return summary, fmt.Errorf("loans: %v", err)
+	}
+	logf("%d loans copied", summary.Loans)
+
+	// And so does copying the quotes.
+	if summary.Quotes, err = copyQuotes(source, destination); err != nil {
+		return summary, fmt.Errorf("quotes: %v", err)
+	}
+	logf("%d quotes copied", summary.Quotes)
+
+	if !progress.UsersDone {
+		if summary.Users, err = copyUsers(source, destination); err != nil {
+			return summary, fmt.Errorf("users: %v", err)
+		}
+		progress.UsersDone = true
+		if err := progress.save(statePath); err != nil {
+			return summary, err
+		}
+		logf("%d users copied", summary.Users)
+	}
+
+	// The wish lists go after their owners, and so do the reservations of their gifts. Copying them
+	// again changes nothing either.
+	if summary.Wishes, summary.Gifts, err = copyWishLists(source, destination); err != nil {
+		return summary, fmt.Errorf("wish lists: %v", err)
+	}
+	logf("%d wishes and %d gifts copied", summary.Wishes, summary.Gifts)
+
+	if summary.Images, err = copyImages(source, destination, &progress, statePath, logf); err != nil {
+		return summary, fmt.Errorf("images: %v", err)
+	}
+
+	if !progress.LikesDone {
+		if summary.Likes, err = copyLikes(source, destination); err != nil {
+			return summary, fmt.Errorf("likes: %v", err)
+		}
+		progress.LikesDone = true
+		if err := progress.save(statePath); err != nil {
+			return summary, err
+		}
+		logf("%d likes copied", summary.Likes)
+	}
+
+	// Reviews are saved by book and user, copying them again changes nothing.
+	if summary.Reviews, err = copyReviews(source, destination); err != nil {
+		return summary, fmt.Errorf("reviews: %v", err)
+	}
+	logf("%d reviews copied", summary.Reviews)
+
+	if summary.Checks, err = Verify(source, destination); err != nil {
+		return summary, fmt.Errorf("verify: %v", err)
+	}
+
+	if summary.OK() && statePath != "" {
+		if err := os.Remove(statePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return summary, err
+		}
+	}
+
+	return summary, nil
+}
+
+func copyAuthors(source, destination dao.DAO) (int, error) {
+	authors, err := source.GetAllAuthors()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, author := range authors {
+		if _, err := destination.CreateAuthor(author); err != nil {
+			return 0, fmt.Errorf("author %d: %v", author.ID, err)
+		}
+	}
+
+	return len(authors), nil
+}
+
+func copyShelves(source, destination dao.DAO) (int, error) {
+	shelves, err := source.GetAllShelves()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, shelf := range shelves {
+		if _, err := destination.CreateShelf(shelf); err != nil {
+			return 0, fmt.Errorf("shelf %d: %v", shelf.ID, err)
+		}
+	}
+
+	return len(shelves), nil
+}
+
+func copyWorks(source, destination dao.DAO) (int, error) {
+	works, err := source.GetAllWorks()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, work := range works {
+		if _, err := destination.CreateWork(work); err != nil {
+			return 0, fmt.Errorf("work %d: %v", work.ID, err)
+		}
+	}
+
+	return len(works), nil
+}
+
+func copyLoans(source, destination dao.DAO) (int, error) {
+	loans, err := source.GetAllLoans()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, loan := range loans {
+		if _, err := destination.CreateLoan(loan); err != nil {
+			return 0, fmt.Errorf("loan %d: %v", loan.ID, err)
+		}
+	}
+
+	return len(loans), nil
+}
+
+func copyQuotes(source, destination dao.DAO) (int, error) {
+	quotes, err := source.GetAllQuotes()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, quote := range quotes {
+		if _, err := destination.CreateQuote(quote); err != nil {
+			return 0, fmt.Errorf("quote %d: %v", quote.ID, err)
+		}
+	}
+
+	return len(quotes), nil
+}
+
+// wishListOwners are the library, whose wish list has no user, and every user.
+func wishListOwners(bookDAO dao.DAO) ([]string, error) {
+	users, err := bookDAO.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	owners := []string{""}
+	for _, userInfo := range users {
+		owners = append(owners, userInfo.UserID)
+	}
+
+	return owners, nil
+}
+
+// copyWishLists copies the wish list of the library and of every user with the reservations of
+// their books.
+func copyWishLists(source, destination dao.DAO) (int, int, error) {
+	owners, err := wishListOwners(source)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	wishes, gifts := 0, 0
+	for _, owner := range owners {
+		wishListBooks, err := source.GetWishListBooks(owner)
+		if err != nil {
+			return wishes, gifts, err
+		}
+
+		for _, wishListBook := range wishListBooks {
+			if _, err := destination.CreateWishListBook(wishListBook); err != nil {
+				return wishes, gifts, fmt.Errorf("wish %d: %v", wishListBook.ID, err)
+			}
+			wishes++
+		}
+
+		reservations, err := source.GetWishListReservations(owner)
+		if err != nil {
+			return wishes, gifts, err
+		}
+
+		for _, reservation := range reservations {
+			if err := destination.ReserveWishListBook(reservation); err != nil {
+				return wishes, gifts, fmt.Errorf("gift %d: %v", reservation.WishListBookID, err)
+			}
+			gifts++
+		}
+	}
+
+	return wishes, gifts, nil
+}
+
+func copyBooks(source, destination dao.DAO, progress *Progress, statePath string, logf func(format string, args ...any)) (int, error) {
+	books, err := source.GetAllBooks()
+	if err != nil {
+		return 0, err
+	}
+
+	existingBooks, err := destination.GetAllBooks()
+	if err != nil {
+		return 0, err
+	}
+
+	existing := make(map[int]bool, len(existingBooks))
+	for _, existingBook := range existingBooks {
+		existing[existingBook.ID] = true
+	}
+
+	copied := 0
+	for _, bookInfo := range books {
+		if bookInfo.ID <= progress.LastBookID {
+			continue
+		}
+
+		// The book may have been written right before an interruption, after the last checkpoint.
+		if !existing[bookInfo.ID] {
+			bookInfo.Image = nil
+			bookInfo.Images = nil
+			if _, err := destination.CreateBook(bookInfo); err != nil {
+				return copied, fmt.Errorf("book %d: %v", bookInfo.ID, err)
+			}
+			copied++
+		}
+
+		progress.LastBookID = bookInfo.ID
+		if err := progress.save(statePath); err != nil {
+			return copied, err
+		}
+
+		if copied > 0 && copied%100 == 0 {
+			logf("%d books copied", copied)
+		}
+	}
+	logf("%d books copied", copied)
+
+	return copied, nil
+}
+
+func copyUsers(source, destination dao.DAO) (int, error) {
+	users, err := source.GetAllUsers()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userInfo := range users {
+		if err := destination.AddUser(userInfo.UserID, userInfo.Email, userInfo.Name, userInfo.OAuthIdentifier); err != nil {
+			return 0, fmt.Errorf("user %s: %v", userInfo.UserID, err)
+		}
+	}
+
+	return len(users), nil
+}
+
+func copyImages(source, destination dao.DAO, progress *Progress, statePath string, logf func(format string, args ...any)) (int, error) {
+	copied := 0
+
+	err := source.ForEachImage(func(image book.BookImage) error {
+		if image.ImageID <= progress.LastImageID {
+			return nil
+		}
+
+		if err := destination.RestoreImage(image); err != nil {
+			return fmt.Errorf("image %d of book %d: %v", image.ImageID, image.BookID, err)
+		}
+		copied++
+
+		progress.LastImageID = image.ImageID
+		if err := progress.save(statePath); err != nil {
+			return err
+		}
+
+		if copied%100 == 0 {
+			logf("%d images copied", copied)
+		}
+
+		return nil
+	})
+	logf("%d images copied", copied)
+
+	return copied, err
+}
+
+func copyLikes(source, destination dao.DAO) (int, error) {
+	likes, err := source.GetAllLikes()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, like := range likes {
+		if err := destination.AddLike(like); err != nil {
+			return 0, fmt.Errorf("like of book %d by %s: %v", like.BookID, like.UserID, err)
+		}
+	}
+
+	return len(likes), nil
+}
+
+func copyReviews(source, destination dao.DAO) (int, error) {
+	reviews, err := source.GetAllReviews()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, review := range reviews {
+		if err := destination.SaveReview(review); err != nil {
+			return 0, fmt.Errorf("review of book %d by %s: %v", review.BookID, review.UserID, err)
+		}
+	}
+
+	return len(reviews), nil
+}
+
+// snapshot counts an entity of a backend and computes a checksum over a canonical form of its rows,
+// which is independent of the order and the types each backend stores them with.
+type snapshot struct {
+	count    int
+	checksum string
+}
+
+func newSnapshot(lines []string) snapshot {
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	for _, line := range lines {
+		hash.Write([]byte(line))
+		hash.Write([]byte("\n"))
+	}
+
+	return snapshot{count: len(lines), checksum: hex.EncodeToString(hash.Sum(nil))}
+}
+
+func snapshotBooks(bookDAO dao.DAO) (snapshot, error) {
+	books, err := bookDAO.GetAllBooks()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(books))
+	for _, bookInfo := range books {
+		if addedOn, err := book.ParseAddedOn(bookInfo.AddedOn); err == nil {
+			bookInfo.AddedOn = addedOn.Format(book.AddedOnLayout)
+		}
+		contributors := make([]string, 0, len(bookInfo.Contributors))
+		for _, contributor := range bookInfo.Contributors {
+			contributors = append(contributors, fmt.Sprintf("%q:%s:%d", contributor.Name, contributor.Role, contributor.AuthorID))
+		}
+		readings := make([]string, 0, len(bookInfo.Readings))
+		for _, reading := range bookInfo.Readings {
+			readings = append(readings, fmt.Sprintf("%s:%s:%d", snapshotDate(reading.StartedOn), snapshotDate(reading.FinishedOn), reading.CurrentPage))
+		}
+		acquisition := bookInfo.Acquisition
+		lines = append(lines, fmt.Sprintf("%d\t%q\t%q\t%q\t%t\t%s\t%q\t%s\t%d\t%q\t%d\t%q\t%q\t%s\t%q\t%q\t%d:%d\t%s\t%d\t%s\t%s:%s:%s:%q:%s:%s", bookInfo.ID, bookInfo.Title, bookInfo.Author,
+			bookInfo.Description, bookInfo.HasBeenRead, bookInfo.AddedOn, bookInfo.GoodreadsLink, bookInfo.ISBN,
+			bookInfo.WorkID, bookInfo.Publisher, bookInfo.Year, bookInfo.Language, bookInfo.Format, strings.Join(contributors, ","),
+			strings.Join(bookInfo.Tags, ","), bookInfo.Category.String(), bookInfo.Location.ShelfID, bookInfo.Location.Position,
+			bookInfo.ReadingStatus, bookInfo.Pages, strings.Join(readings, ","), snapshotDate(acquisition.PurchasedOn), acquisition.Price,
+			acquisition.Currency, acquisition.Source, acquisition.Condition, acquisition.EstimatedValue))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotAuthors(bookDAO dao.DAO) (snapshot, error) {
+	authors, err := bookDAO.GetAllAuthors()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(authors))
+	for _, author := range authors {
+		aliases := append([]string(nil), author.Aliases...)
+		sort.Strings(aliases)
+		lines = append(lines, fmt.Sprintf("%d\t%q\t%q\t%q\t%q\t%q", author.ID, author.Name, author.SortName, author.Bio,
+			author.PhotoURL, strings.Join(aliases, "|")))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotShelves(bookDAO dao.DAO) (snapshot, error) {
+	shelves, err := bookDAO.GetAllShelves()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(shelves))
+	for _, shelf := range shelves {
+		lines = append(lines, fmt.Sprintf("%d\t%q\t%q\t%q", shelf.ID, shelf.Room, shelf.Bookcase, shelf.Name))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotWorks(bookDAO dao.DAO) (snapshot, error) {
+	works, err := bookDAO.GetAllWorks()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(works))
+	for _, work := range works {
+		lines = append(lines, fmt.Sprintf("%d\t%q\t%q", work.ID, work.Title, work.Author))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotLoans(bookDAO dao.DAO) (snapshot, error) {
+	loans, err := bookDAO.GetAllLoans()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(loans))
+	for _, loan := range loans {
+		lines = append(lines, fmt.Sprintf("%d\t%d\t%q\t%s\t%s\t%s\t%q", loan.ID, loan.BookID, loan.Borrower, snapshotDate(loan.LoanedOn),
+			snapshotDate(loan.DueOn), snapshotDate(loan.ReturnedOn), loan.Notes))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+// snapshotDate is a date of the snapshots, empty when it is not known.
+func snapshotDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(book.AddedOnLayout)
+}
+
+func snapshotImages(bookDAO dao.DAO) (snapshot, error) {
+	var lines []string
+	err := bookDAO.ForEachImage(func(image book.BookImage) error {
+		imageHash := sha256.Sum256(image.Data)
+		lines = append(lines, fmt.Sprintf("%d\t%d\t%d\t%t\t%x", image.ImageID, image.BookID, image.Position, image.IsCover, imageHash))
+
+		return nil
+	})
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotUsers(bookDAO dao.DAO) (snapshot, error) {
+	users, err := bookDAO.GetAllUsers()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(users))
+	for _, userInfo := range users {
+		lines = append(lines, fmt.Sprintf("%q\t%q\t%q\t%q", userInfo.UserID, userInfo.Email, userInfo.Name, userInfo.OAuthIdentifier))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotLikes(bookDAO dao.DAO) (snapshot, error) {
+	likes, err := bookDAO.GetAllLikes()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(likes))
+	for _, like := range likes {
+		// Backends keep different sub-second precision, seconds are what we can compare.
+		createdAt := like.CreatedAt.UTC().Truncate(time.Second).Format(time.RFC3339)
+		lines = append(lines, fmt.Sprintf("%d\t%q\t%s", like.BookID, like.UserID, createdAt))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotQuotes(bookDAO dao.DAO) (snapshot, error) {
+	quotes, err := bookDAO.GetAllQuotes()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(quotes))
+	for _, quote := range quotes {
+		lines = append(lines, fmt.Sprintf("%d\t%d\t%q\t%d\t%q\t%s", quote.ID, quote.BookID, quote.Text, quote.Page,
+			strings.Join(quote.Tags, ","), snapshotDate(quote.AddedOn)))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotWishLists(bookDAO dao.DAO) (snapshot, error) {
+	owners, err := wishListOwners(bookDAO)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	var lines []string
+	for _, owner := range owners {
+		wishListBooks, err := bookDAO.GetWishListBooks(owner)
+		if err != nil {
+			return snapshot{}, err
+		}
+
+		for _, wishListBook := range wishListBooks {
+			lines = append(lines, fmt.Sprintf("%d\t%q\t%q\t%q\t%q\t%q\t%s\t%q", wishListBook.ID, wishListBook.Title, wishListBook.Author,
+				wishListBook.Description, wishListBook.ImageLink, wishListBook.GoodreadsLink, wishListBook.Priority, wishListBook.UserID))
+		}
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotGifts(bookDAO dao.DAO) (snapshot, error) {
+	owners, err := wishListOwners(bookDAO)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	var lines []string
+	for _, owner := range owners {
+		reservations, err := bookDAO.GetWishListReservations(owner)
+		if err != nil {
+			return snapshot{}, err
+		}
+
+		for _, reservation := range reservations {
+			reservedOn := reservation.ReservedOn.UTC().Truncate(time.Second).Format(time.RFC3339)
+			lines = append(lines, fmt.Sprintf("%d\t%q\t%s", reservation.WishListBookID, reservation.UserID, reservedOn))
+		}
+	}
+
+	return newSnapshot(lines), nil
+}
+
+func snapshotReviews(bookDAO dao.DAO) (snapshot, error) {
+	reviews, err := bookDAO.GetAllReviews()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	lines := make([]string, 0, len(reviews))
+	for _, review := range reviews {
+		updatedOn := review.UpdatedOn.UTC().Truncate(time.Second).Format(time.RFC3339)
+		lines = append(lines, fmt.Sprintf("%d\t%q\t%d\t%q\t%q\t%s", review.BookID, review.UserID, review.Rating,
+			review.Text, review.Notes, updatedOn))
+	}
+
+	return newSnapshot(lines), nil
+}
+
+// Verify compares counts and checksums of every entity between both backends.
+func Verify(source, destination dao.DAO) ([]Check, error) {
+	entities := []struct {
+		name     string
+		snapshot func(dao.DAO) (snapshot, error)
+	}{
+		{"authors", snapshotAuthors},
+		{"shelves", snapshotShelves},
+		{"works", snapshotWorks},
+		{"books", snapshotBooks},
+		{"loans", snapshotLoans},
+		{"quotes", snapshotQuotes},
+		{"users", snapshotUsers},
+		{"wishes", snapshotWishLists},
+		{"gifts", snapshotGifts},
+		{"images", snapshotImages},
+		{"likes", snapshotLikes},
+		{"reviews", snapshotReviews},
+	}
+
+	var checks []Check
+	for _, entity := range entities {
+		sourceSnapshot, err := entity.snapshot(source)
+		if err != nil {
+			return nil, fmt.Errorf("%s in source: %v", entity.name, err)
+		}
+
+		destinationSnapshot, err := entity.snapshot(destination)
+		if err != nil {
+			return nil, fmt.Errorf("%s in destination: %v", entity.name, err)
+		}
+
+		checks = append(checks, Check{
+			Entity:              entity.name,
+			SourceCount:         sourceSnapshot.count,
+			DestinationCount:    destinationSnapshot.count,
+			SourceChecksum:      sourceSnapshot.checksum,
+			DestinationChecksum: destinationSnapshot.checksum,
+		})
+	}
+
+	return checks, nil
+}
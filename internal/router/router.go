@@ -53,6 +53,54 @@ func createRoutes(dao *dao.DAO) *Routes {
 				handler.AddBook(dao, w, r)
 			},
 		},
+		Router{
+			Name:   "Catalog Page",
+			Method: "GET",
+			Path:   "/admin/catalog",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.CatalogPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Export Catalog CSV",
+			Method: "GET",
+			Path:   "/admin/catalog/export.csv",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ExportCatalogCSV(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Import Catalog CSV",
+			Method: "POST",
+			Path:   "/admin/catalog/import",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ImportCatalogCSV(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Import Goodreads Export",
+			Method: "POST",
+			Path:   "/admin/catalog/goodreads",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ImportGoodreadsExport(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Book Image",
+			Method: "GET",
+			Path:   "/images/{image_id}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.BookImage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Export Bibliography",
+			Method: "GET",
+			Path:   "/export/{format}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ExportBibliography(dao, w, r)
+			},
+		},
 		Router{
 			Name:   "Check Like Status",
 			Method: "GET",
@@ -77,6 +125,86 @@ func createRoutes(dao *dao.DAO) *Routes {
 				handler.LikesCount(dao, w, r)
 			},
 		},
+		Router{
+			Name:   "Save Review",
+			Method: "POST",
+			Path:   "/book/{book_id}/review",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.SaveReview(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Delete Review",
+			Method: "POST",
+			Path:   "/book/{book_id}/review/delete",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.DeleteReview(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "My Reviews",
+			Method: "GET",
+			Path:   "/my_reviews",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.MyReviewsPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "My Wish List",
+			Method: "GET",
+			Path:   "/my_wishlist",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.MyWishListPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Create My Wish List Book",
+			Method: "POST",
+			Path:   "/my_wishlist",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.CreateMyWishListBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Update My Wish List Book",
+			Method: "POST",
+			Path:   "/my_wishlist/{wish_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.UpdateMyWishListBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Delete My Wish List Book",
+			Method: "POST",
+			Path:   "/my_wishlist/{wish_id:[0-9]+}/delete",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.DeleteMyWishListBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Gift List",
+			Method: "GET",
+			Path:   "/gifts/{user_id}/{token}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.GiftListPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Reserve Gift",
+			Method: "POST",
+			Path:   "/gifts/{user_id}/{token}/{wish_id:[0-9]+}/reserve",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ReserveGift(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Cancel Gift",
+			Method: "POST",
+			Path:   "/gifts/{user_id}/{token}/{wish_id:[0-9]+}/cancel",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.CancelGift(dao, w, r)
+			},
+		},
 		Router{
 			Name:   "Like Book",
 			Method: "POST",
@@ -123,6 +251,238 @@ func createRoutes(dao *dao.DAO) *Routes {
 				handler.BooksByAuthorPage(dao, w, r)
 			},
 		},
+		Router{
+			Name:   "Tags page",
+			Method: "GET",
+			Path:   "/tags",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.TagsPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Shelves page",
+			Method: "GET",
+			Path:   "/shelves",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ShelvesPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Shelf page",
+			Method: "GET",
+			Path:   "/shelf/{shelf_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ShelfPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Create Shelf",
+			Method: "POST",
+			Path:   "/admin/shelves",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.CreateShelf(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Update Shelf",
+			Method: "POST",
+			Path:   "/admin/shelf/{shelf_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.UpdateShelf(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Delete Shelf",
+			Method: "POST",
+			Path:   "/admin/shelf/{shelf_id:[0-9]+}/delete",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.DeleteShelf(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Relocate Shelf Books",
+			Method: "POST",
+			Path:   "/admin/shelf/{shelf_id:[0-9]+}/books",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.RelocateShelfBooks(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Move Shelf Books",
+			Method: "POST",
+			Path:   "/admin/shelf/{shelf_id:[0-9]+}/move",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.MoveShelfBooks(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Author page",
+			Method: "GET",
+			Path:   "/author/{author_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.AuthorPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Update Author",
+			Method: "POST",
+			Path:   "/admin/author/{author_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.UpdateAuthor(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Authors Admin Page",
+			Method: "GET",
+			Path:   "/admin/authors",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.AuthorsAdminPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Merge Authors",
+			Method: "POST",
+			Path:   "/admin/authors/merge",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.MergeAuthors(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Loans Page",
+			Method: "GET",
+			Path:   "/admin/loans",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.LoansPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Lend Book",
+			Method: "POST",
+			Path:   "/admin/loans",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.LendBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Book Loans Page",
+			Method: "GET",
+			Path:   "/admin/book/{book_id:[0-9]+}/loans",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.BookLoansPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Update Loan",
+			Method: "POST",
+			Path:   "/admin/loan/{loan_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.UpdateLoan(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Return Loan",
+			Method: "POST",
+			Path:   "/admin/loan/{loan_id:[0-9]+}/return",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ReturnLoan(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Create Wish List Book",
+			Method: "POST",
+			Path:   "/admin/wishlist",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.CreateWishListBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Update Wish List Book",
+			Method: "POST",
+			Path:   "/admin/wishlist/{wish_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.UpdateWishListBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Delete Wish List Book",
+			Method: "POST",
+			Path:   "/admin/wishlist/{wish_id:[0-9]+}/delete",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.DeleteWishListBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Purchase Wish List Book",
+			Method: "POST",
+			Path:   "/admin/wishlist/{wish_id:[0-9]+}/purchase",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.PurchaseWishListBook(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Valuation Page",
+			Method: "GET",
+			Path:   "/admin/valuation",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ValuationPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Export Valuation CSV",
+			Method: "GET",
+			Path:   "/admin/valuation/export.csv",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ExportValuationCSV(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Print Valuation Page",
+			Method: "GET",
+			Path:   "/admin/valuation/print",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.PrintValuationPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Quotes Page",
+			Method: "GET",
+			Path:   "/quotes",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.QuotesPage(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Export Quotes Markdown",
+			Method: "GET",
+			Path:   "/quotes/export.md",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ExportQuotesMarkdown(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Create Quote",
+			Method: "POST",
+			Path:   "/admin/book/{book_id:[0-9]+}/quotes",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.CreateQuote(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Update Quote",
+			Method: "POST",
+			Path:   "/admin/quote/{quote_id:[0-9]+}",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.UpdateQuote(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Delete Quote",
+			Method: "POST",
+			Path:   "/admin/quote/{quote_id:[0-9]+}/delete",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.DeleteQuote(dao, w, r)
+			},
+		},
 		Router{
 			Name:   "Contact page",
 			Method: "GET",
@@ -138,10 +498,12 @@ func createRoutes(dao *dao.DAO) *Routes {
 			HandlerFunc: handler.ErrorPage,
 		},
 		Router{
-			Name:        "IndexPage",
-			Method:      "GET",
-			Path:        "/",
-			HandlerFunc: handler.IndexPage,
+			Name:   "IndexPage",
+			Method: "GET",
+			Path:   "/",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.IndexPage(dao, w, r)
+			},
 		},
 		Router{
 			Name:   "Search for books",
@@ -205,6 +567,14 @@ func createRoutes(dao *dao.DAO) *Routes {
 				handler.BooksList(dao, w, r)
 			},
 		},
+		Router{
+			Name:   "Owned Book",
+			Method: "GET",
+			Path:   "/api/owned",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.OwnedBook(dao, w, r)
+			},
+		},
 		Router{
 			Name:   "Remove Image",
 			Method: "POST",
@@ -213,6 +583,22 @@ func createRoutes(dao *dao.DAO) *Routes {
 				handler.RemoveImage(dao, w, r)
 			},
 		},
+		Router{
+			Name:   "Reorder Images",
+			Method: "POST",
+			Path:   "/reorderimages",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.ReorderImages(dao, w, r)
+			},
+		},
+		Router{
+			Name:   "Set Cover Image",
+			Method: "POST",
+			Path:   "/setcoverimage",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				handler.SetCoverImage(dao, w, r)
+			},
+		},
 		Router{
 			Name:   "Wish List Books",
 			Method: "GET",
@@ -0,0 +1,202 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Money is an amount in cents, so totals add up exactly. It is written with two decimals, "450.00".
+type Money int64
+
+// ParseMoney reads an amount as the forms and the library files write it: "450", "450.5", "450,50" or
+// "1,450.50". An empty amount is 0.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "$"))
+	if s == "" {
+		return 0, nil
+	}
+
+	// A comma is the decimal separator when there is no point and at most two digits follow it,
+	// "450,50"; otherwise it separates thousands, "1,450.50".
+	if comma := strings.LastIndexByte(s, ','); comma >= 0 && !strings.Contains(s, ".") && len(s)-comma-1 <= 2 {
+		s = s[:comma] + "." + s[comma+1:]
+	}
+	s = strings.ReplaceAll(s, ",", "")
+
+	units, cents, _ := strings.Cut(s, ".")
+	if len(cents) > 2 {
+		return 0, fmt.Errorf("%q has more than two decimals", s)
+	}
+	cents += strings.Repeat("0", 2-len(cents))
+
+	amount, err := strconv.ParseInt(units+cents, 10, 64)
+	if err != nil || strings.HasPrefix(units, "+") || strings.HasPrefix(cents, "-") || strings.HasPrefix(cents, "+") {
+		return 0, fmt.Errorf("%q is not an amount", s)
+	}
+
+	return Money(amount), nil
+}
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText reads the amounts of the library files, which are numbers: price = 450.50. The TOML
+// decoder hands the numbers over with six decimals, 450.500000, they are rounded to cents.
+func (m *Money) UnmarshalText(text []byte) error {
+	if amount, err := strconv.ParseFloat(string(text), 64); err == nil {
+		*m = Money(math.Round(amount * 100))
+		return nil
+	}
+
+	amount, err := ParseMoney(string(text))
+	if err != nil {
+		return err
+	}
+	*m = amount
+
+	return nil
+}
+
+// Condition is the state of the copy of a book, with the grades the booksellers use.
+type Condition string
+
+const (
+	// ConditionUnknown is a book whose condition has not been written down.
+	ConditionUnknown  Condition = ""
+	ConditionNew      Condition = "new"
+	ConditionFine     Condition = "fine"
+	ConditionVeryGood Condition = "very-good"
+	ConditionGood     Condition = "good"
+	ConditionFair     Condition = "fair"
+	ConditionPoor     Condition = "poor"
+)
+
+// Conditions are the known conditions, from the best to the worst.
+var Conditions = []Condition{ConditionUnknown, ConditionNew, ConditionFine, ConditionVeryGood, ConditionGood, ConditionFair, ConditionPoor}
+
+// ParseCondition accepts the conditions of Conditions, "as-new" is fine.
+func ParseCondition(s string) (Condition, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "as-new" {
+		return ConditionFine, true
+	}
+
+	for _, condition := range Conditions {
+		if string(condition) == s {
+			return condition, true
+		}
+	}
+
+	return "", false
+}
+
+// Label is the name of the condition the pages show.
+func (c Condition) Label() string {
+	switch c {
+	case ConditionUnknown:
+		return "Sin indicar"
+	case ConditionNew:
+		return "Nuevo"
+	case ConditionFine:
+		return "Como nuevo"
+	case ConditionVeryGood:
+		return "Muy bueno"
+	case ConditionGood:
+		return "Bueno"
+	case ConditionFair:
+		return "Regular"
+	case ConditionPoor:
+		return "Malo"
+	default:
+		return string(c)
+	}
+}
+
+// Acquisition is how a book came to the library and what it is worth, what the insurance asks for.
+// Only admins see it.
+type Acquisition struct {
+	// PurchasedOn is the day the book was bought, zero when it is not known; AddedOn is when it was
+	// catalogued.
+	PurchasedOn time.Time
+	Price       Money
+	// Currency is the ISO 4217 code of Price and EstimatedValue, "MXN".
+	Currency string
+	// Source is the store, the fair or the person the book came from.
+	Source         string
+	Condition      Condition
+	EstimatedValue Money
+}
+
+// IsZero tells if nothing is known about the acquisition.
+func (a Acquisition) IsZero() bool {
+	return a == Acquisition{}
+}
+
+// Validate rejects negative amounts, an unknown condition and a currency that is not a three letter
+// code.
+func (a Acquisition) Validate() error {
+	switch {
+	case a.Price < 0:
+		return fmt.Errorf("the price %s is negative", a.Price)
+	case a.EstimatedValue < 0:
+		return fmt.Errorf("the estimated value %s is negative", a.EstimatedValue)
+	case a.Currency != "" && !isCurrencyCode(a.Currency):
+		return fmt.Errorf("%q is not a currency code like MXN or USD", a.Currency)
+	}
+
+	if _, ok := ParseCondition(string(a.Condition)); !ok {
+		return fmt.Errorf("unknown condition %q", a.Condition)
+	}
+
+	return nil
+}
+
+func isCurrencyCode(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+
+	for _, c := range strings.ToUpper(currency) {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Normalize writes the currency in uppercase, the condition as Conditions do and the purchase date as
+// a day.
+func (a *Acquisition) Normalize() {
+	a.Currency = strings.ToUpper(strings.TrimSpace(a.Currency))
+	a.Source = strings.TrimSpace(a.Source)
+	if condition, ok := ParseCondition(string(a.Condition)); ok {
+		a.Condition = condition
+	}
+	if !a.PurchasedOn.IsZero() {
+		a.PurchasedOn = Day(a.PurchasedOn)
+	}
+}
+
+// InsuredValue is what the book is worth for the insurance: its estimated value or, when it has not
+// been estimated, its price.
+func (a Acquisition) InsuredValue() Money {
+	if a.EstimatedValue > 0 {
+		return a.EstimatedValue
+	}
+
+	return a.Price
+}
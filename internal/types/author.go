@@ -0,0 +1,95 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Author is a person of the library, however their name is written on the books: the contributors
+// of "Cien años de soledad" and "Crónica de una muerte anunciada" are "Gabriel García Márquez" and
+// "G. García Márquez", both the same Author.
+type Author struct {
+	ID int
+	// Name is the canonical name, the one the lists and the author page show.
+	Name string
+	// SortName orders the lists, "Joyce, James".
+	SortName string
+	Bio      string
+	PhotoURL string
+	// Aliases are the other ways the name is written, merged authors among them.
+	Aliases []string
+	// Books is how many books they took part in, as the database counts them.
+	Books int `toml:"-"`
+}
+
+// SortName turns a name into the way it is sorted, its surname first: "James Joyce" is
+// "Joyce, James" and "Henry S. Warren, JR." is "Warren, Henry S., JR.". The particles of the surname
+// go after the given name, "Antoine de Saint-Exupéry" is "Saint-Exupéry, Antoine de", and surnames
+// joined by "y" stay together, "José Ortega y Gasset" is "Ortega y Gasset, José". A single word and
+// a name already sorted ("Joyce, James") are left as they are.
+//
+// The surname is only guessed after a given name of one word and its initials. Two surnames such as
+// "Gabriel García Márquez" cannot be told apart from a middle name such as "Edgar Allan Poe", so
+// those names get no sort name, "", and an admin sets it on the page of the author or with the
+// sortName of books_db.toml. database/sql/08_authors.sql guesses with the same sortNamePattern.
+func SortName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+
+	var suffix string
+	if i := strings.LastIndex(name, ","); i >= 0 {
+		if !nameSuffixes[strings.ToLower(strings.TrimSpace(name[i+1:]))] {
+			return name
+		}
+		name, suffix = strings.TrimSpace(name[:i]), name[i:]
+		if strings.Contains(name, ",") {
+			return name + suffix
+		}
+	}
+
+	if !strings.Contains(name, " ") {
+		return name + suffix
+	}
+
+	parts := sortNameRegexp.FindStringSubmatch(name)
+	if parts == nil {
+		return ""
+	}
+
+	// The given name with its initials, its particles (each with its space before) and the surname.
+	given, particles, surname := parts[1], parts[2], parts[3]
+
+	return surname + ", " + given + particles + suffix
+}
+
+// sortNamePattern matches the names whose surname SortName guesses, written with single spaces and
+// without their suffix: a given name of one word followed by initials ("Henry S."), the particles
+// of the surname and the surname, words joined by "y" among it. It is written for Go and for the
+// regular expressions of postgres alike.
+const sortNamePattern = `(?i)^([^ ]+(?: [^ .]\.)*)((?: (?:de|del|la|las|los|da|das|do|dos|di|du|le|van|von|der|den))*) ([^ ]+(?: y [^ ]+)*)$`
+
+var sortNameRegexp = regexp.MustCompile(sortNamePattern)
+
+// SortKey is what the lists order the author by and show: the sort name or, while it is not set,
+// the name.
+func (a Author) SortKey() string {
+	if a.SortName == "" {
+		return a.Name
+	}
+
+	return a.SortName
+}
+
+// HasName tells if the name is the one of the author or one of their aliases, regardless of case.
+func (a Author) HasName(name string) bool {
+	if strings.EqualFold(a.Name, name) {
+		return true
+	}
+
+	for _, alias := range a.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package types
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSortName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"James Joyce", "Joyce, James"},
+		{"  James   Joyce ", "Joyce, James"},
+		{"Homero", "Homero"},
+		{"Joyce, James", "Joyce, James"},
+		{"García Márquez, Gabriel", "García Márquez, Gabriel"},
+		{"Henry S. Warren, JR.", "Warren, Henry S., JR."},
+		{"Albert Harkness, Jr.", "Harkness, Albert, Jr."},
+		{"J. R. R. Tolkien", "Tolkien, J. R. R."},
+		{"Antoine de Saint-Exupéry", "Saint-Exupéry, Antoine de"},
+		{"Miguel de la Madrid", "Madrid, Miguel de la"},
+		{"Ludwig van Beethoven", "Beethoven, Ludwig van"},
+		{"José Ortega y Gasset", "Ortega y Gasset, José"},
+		{"Arturo Pérez-Reverte", "Pérez-Reverte, Arturo"},
+		// Two surnames without a "y" look like a middle name, the admin sets them by hand.
+		{"Gabriel García Márquez", ""},
+		{"Edgar Allan Poe", ""},
+		{"Juana Inés de la Cruz", ""},
+	}
+
+	for _, tt := range tests {
+		if got := SortName(tt.name); got != tt.want {
+			t.Errorf("SortName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The migration of postgres guesses the sort names of the authors it creates, it must guess as
+// SortName does.
+func TestSortNamePatternIsTheOneOfTheMigration(t *testing.T) {
+	migration, err := os.ReadFile("../../database/sql/08_authors.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(migration), "'"+sortNamePattern+"'") {
+		t.Errorf("08_authors.sql does not sort the names with %s", sortNamePattern)
+	}
+}
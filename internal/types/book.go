@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -12,20 +13,100 @@ type WishListBook struct {
 	Description   string
 	ImageLink     string
 	GoodreadsLink string
+	// Priority is how much the book is wanted, PriorityNormal when the list does not say.
+	Priority Priority
+	// UserID is the user whose wish list it is, empty for the one of the library.
+	UserID string `toml:"-"`
+}
+
+// Work is what the editions of a book have in common: "Ulises" by James Joyce, whichever
+// publisher or translation the books on the shelf are.
+type Work struct {
+	ID     int
+	Title  string
+	Author string
+}
+
+// Edition describes the book on the shelf as opposed to its work. Every field is optional, the
+// translator is one of its Contributors.
+type Edition struct {
+	Publisher string
+	Year      int
+	Language  string
+	// Format is how it is bound: hardcover, paperback, pocket...
+	Format string
+}
+
+// IsZero tells if nothing is known about the edition.
+func (e Edition) IsZero() bool {
+	return e == Edition{}
 }
 
 // BookInfo ...
 type BookInfo struct {
-	ID            int
-	Title         string
+	ID    int
+	Title string
+	// Author is how the authors are shown, the names of the authors among the Contributors.
 	Author        string
+	Contributors  []Contributor
 	Description   string
 	HasBeenRead   bool
 	ImageNames    []string
 	Image         []byte
-	Base64Images  []BookImageInfo
+	Images        []BookImageInfo
+	Cover         *BookImageInfo
 	AddedOn       string
 	GoodreadsLink string
+	// ISBN is the ISBN-13 of the edition, without hyphens. Empty when it is not known.
+	ISBN string
+	// WorkID groups the editions of the same work, 0 when the book is not grouped with any other.
+	WorkID int
+	Edition
+	// Tags are the free-form labels of the book, normalized and sorted.
+	Tags     []string
+	Category Category
+	Location Location
+	// ReadingStatus is where the book is in its reading and Readings are its read-throughs, the
+	// oldest first. HasBeenRead follows from them, see NormalizeReading.
+	ReadingStatus ReadingStatus
+	Readings      []Reading
+	// Pages is the number of pages of the edition, 0 when it is not known.
+	Pages int
+	// Acquisition is when, where and for how much the book was bought and what it is worth now.
+	Acquisition Acquisition
+	// CurrentLoan is the loan of the book while it is lent, zero otherwise.
+	CurrentLoan Loan `toml:"-"`
+}
+
+// SetImages sets the images of the book, ordered by position, and the cover listings show.
+func (b *BookInfo) SetImages(images []BookImageInfo) {
+	b.Images = images
+	b.Cover = CoverOf(images)
+}
+
+// CoverOf returns the image flagged as cover or, when none is, the first one. Nil when there are
+// no images.
+func CoverOf(images []BookImageInfo) *BookImageInfo {
+	for i := range images {
+		if images[i].IsCover {
+			return &images[i]
+		}
+	}
+
+	if len(images) == 0 {
+		return nil
+	}
+
+	return &images[0]
+}
+
+// AddedOnLayout is the format of BookInfo.AddedOn.
+const AddedOnLayout = "2006-01-02"
+
+// ParseAddedOn parses an AddedOn date. Some dates in the library files are not zero padded
+// ("2024-02-3"), they are accepted as well.
+func ParseAddedOn(addedOn string) (time.Time, error) {
+	return time.Parse("2006-1-2", strings.TrimSpace(addedOn))
 }
 
 type BookSearchType int
@@ -34,6 +115,7 @@ const (
 	Unknown BookSearchType = iota
 	ByTitle
 	ByAuthor
+	ByISBN
 )
 
 func (bt BookSearchType) String() string {
@@ -42,16 +124,44 @@ func (bt BookSearchType) String() string {
 		return "ByTitle"
 	case ByAuthor:
 		return "ByAuthor"
+	case ByISBN:
+		return "ByISBN"
 	default:
 		return "Unknown"
 	}
 }
 
-// BookImageInfo ...
+// BookImageInfo describes an image of a book, its bytes are served from URL and its resized
+// variants from ThumbnailURL and MediumURL. The images of a book are shown sorted by Position.
 type BookImageInfo struct {
-	ImageID int
-	BookID  int
-	Image   string
+	ImageID      int    `json:"id"`
+	BookID       int    `json:"bookID"`
+	Position     int    `json:"position"`
+	IsCover      bool   `json:"isCover"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailURL"`
+	MediumURL    string `json:"mediumURL"`
+}
+
+func NewBookImageInfo(imageID, bookID int) BookImageInfo {
+	url := fmt.Sprintf("/images/%d", imageID)
+
+	return BookImageInfo{
+		ImageID:      imageID,
+		BookID:       bookID,
+		URL:          url,
+		ThumbnailURL: url + "?size=thumb",
+		MediumURL:    url + "?size=medium",
+	}
+}
+
+// BookImage is a stored image with its raw bytes, as it is moved between backends.
+type BookImage struct {
+	ImageID  int
+	BookID   int
+	Position int
+	IsCover  bool
+	Data     []byte
 }
 
 /*
@@ -73,7 +183,10 @@ type BookLike struct {
 }
 
 type Library struct {
-	Book []BookInfo
+	Author []Author
+	Shelf  []Shelf
+	Work   []Work
+	Book   []BookInfo
 }
 
 type WishList struct {
@@ -0,0 +1,183 @@
+package types
+
+import (
+	"strings"
+)
+
+// ContributorRole is what a person did for a book.
+type ContributorRole string
+
+const (
+	RoleAuthor      ContributorRole = "author"
+	RoleTranslator  ContributorRole = "translator"
+	RoleEditor      ContributorRole = "editor"
+	RoleIllustrator ContributorRole = "illustrator"
+	RolePrologue    ContributorRole = "prologue"
+)
+
+// ContributorRoles are the known roles, in the order a book lists them.
+var ContributorRoles = []ContributorRole{RoleAuthor, RoleTranslator, RoleEditor, RoleIllustrator, RolePrologue}
+
+// ParseContributorRole accepts the roles of ContributorRoles, an empty role is an author.
+func ParseContributorRole(s string) (ContributorRole, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return RoleAuthor, true
+	}
+
+	for _, role := range ContributorRoles {
+		if string(role) == s {
+			return role, true
+		}
+	}
+
+	return "", false
+}
+
+// Label is the name of the role the pages show.
+func (r ContributorRole) Label() string {
+	switch r {
+	case RoleAuthor:
+		return "Autor"
+	case RoleTranslator:
+		return "Traducción"
+	case RoleEditor:
+		return "Edición"
+	case RoleIllustrator:
+		return "Ilustración"
+	case RolePrologue:
+		return "Prólogo"
+	default:
+		return string(r)
+	}
+}
+
+// Contributor is a person who took part in a book: its author, translator, editor...
+type Contributor struct {
+	// Name is written as the book does.
+	Name string
+	Role ContributorRole
+	// AuthorID is the Author the name is of, 0 until the database resolves it.
+	AuthorID int
+}
+
+// nameSuffixes go after a comma but are part of the name before them: "Albert Harkness, Jr.".
+var nameSuffixes = map[string]bool{"jr": true, "jr.": true, "sr": true, "sr.": true, "ii": true, "iii": true}
+
+// SplitAuthors splits an author string of the library into its people: "Kathy Sierra, Bert Bates"
+// and "Eric Freeman & Elisabeth Freeman" are two, "Henry S. Warren, JR." is one. " y " and " and "
+// are left alone, they are part of too many names ("José María Morelos y Pavón").
+func SplitAuthors(author string) []string {
+	parts := strings.FieldsFunc(author, func(r rune) bool {
+		return r == ',' || r == ';' || r == '&'
+	})
+
+	var names []string
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if nameSuffixes[strings.ToLower(part)] && len(names) > 0 {
+			names[len(names)-1] += ", " + part
+			continue
+		}
+
+		names = append(names, part)
+	}
+
+	return names
+}
+
+// NewAuthors returns the authors of an author string as contributors.
+func NewAuthors(author string) []Contributor {
+	var contributors []Contributor
+	for _, name := range SplitAuthors(author) {
+		contributors = append(contributors, Contributor{Name: name, Role: RoleAuthor})
+	}
+
+	return contributors
+}
+
+// ContributorsAs returns the names of the contributors with the role, in their order.
+func (bi BookInfo) ContributorsAs(role ContributorRole) []string {
+	var names []string
+	for _, contributor := range bi.Contributors {
+		if contributor.Role == role {
+			names = append(names, contributor.Name)
+		}
+	}
+
+	return names
+}
+
+// Authors are the contributors who are authors.
+func (bi BookInfo) Authors() []Contributor {
+	var contributors []Contributor
+	for _, contributor := range bi.Contributors {
+		if contributor.Role == RoleAuthor {
+			contributors = append(contributors, contributor)
+		}
+	}
+
+	return contributors
+}
+
+// OtherContributors are the contributors who are not authors.
+func (bi BookInfo) OtherContributors() []Contributor {
+	var contributors []Contributor
+	for _, contributor := range bi.Contributors {
+		if contributor.Role != RoleAuthor {
+			contributors = append(contributors, contributor)
+		}
+	}
+
+	return contributors
+}
+
+// NormalizeContributors keeps Author and Contributors in agreement. The authors of a book whose
+// contributors have none, as the library files and the older databases have, come from Author;
+// then Author becomes the names of the authors (or of everyone, for a book without authors like an
+// anthology). Names are trimmed, the empty ones dropped and a missing role is author.
+func (bi *BookInfo) NormalizeContributors() {
+	var contributors []Contributor
+	for _, contributor := range bi.Contributors {
+		contributor.Name = strings.TrimSpace(contributor.Name)
+		if contributor.Name == "" {
+			continue
+		}
+		if role, ok := ParseContributorRole(string(contributor.Role)); ok {
+			contributor.Role = role
+		}
+		contributors = append(contributors, contributor)
+	}
+
+	bi.Contributors = contributors
+	if len(bi.ContributorsAs(RoleAuthor)) == 0 {
+		bi.Contributors = append(NewAuthors(bi.Author), contributors...)
+	}
+
+	if len(bi.Contributors) == 0 {
+		return
+	}
+
+	names := bi.ContributorsAs(RoleAuthor)
+	if len(names) == 0 {
+		for _, contributor := range contributors {
+			names = append(names, contributor.Name)
+		}
+	}
+	bi.Author = strings.Join(names, ", ")
+}
+
+// SetAuthor replaces the authors of the book by the ones of an author string, the rest of the
+// contributors stay.
+func (bi *BookInfo) SetAuthor(author string) {
+	contributors := NewAuthors(author)
+	contributors = append(contributors, bi.OtherContributors()...)
+
+	bi.Author = author
+	bi.Contributors = contributors
+	bi.NormalizeContributors()
+}
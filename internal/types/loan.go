@@ -0,0 +1,78 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Loan is a book lent to someone: who has it, since when, when it should be back and when it came
+// back. Dates are days, at midnight UTC.
+type Loan struct {
+	ID       int
+	BookID   int
+	Borrower string
+	LoanedOn time.Time
+	// DueOn is when the book should be back, zero when there is no date.
+	DueOn time.Time
+	// ReturnedOn is zero while the book is still lent.
+	ReturnedOn time.Time
+	Notes      string
+}
+
+// IsZero tells if there is no loan, a book that is not lent has a zero CurrentLoan.
+func (l Loan) IsZero() bool {
+	return l.ID == 0
+}
+
+// Validate rejects a loan without borrower or date, and one that ends before it starts.
+func (l Loan) Validate() error {
+	switch {
+	case l.Borrower == "":
+		return fmt.Errorf("loan of book %d: the borrower is missing", l.BookID)
+	case l.LoanedOn.IsZero():
+		return fmt.Errorf("loan of book %d: the loan date is missing", l.BookID)
+	case !l.DueOn.IsZero() && Day(l.DueOn).Before(Day(l.LoanedOn)):
+		return fmt.Errorf("loan of book %d: it is due before it is lent", l.BookID)
+	case l.Returned() && Day(l.ReturnedOn).Before(Day(l.LoanedOn)):
+		return fmt.Errorf("loan of book %d: it is returned before it is lent", l.BookID)
+	}
+
+	return nil
+}
+
+// Returned tells if the book is back.
+func (l Loan) Returned() bool {
+	return !l.ReturnedOn.IsZero()
+}
+
+// DaysOverdue is how many days after its due date the book still has not been returned, 0 when it
+// is not overdue.
+func (l Loan) DaysOverdue(today time.Time) int {
+	if l.Returned() || l.DueOn.IsZero() {
+		return 0
+	}
+
+	days := int(Day(today).Sub(Day(l.DueOn)).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+
+	return days
+}
+
+// Overdue tells if the book should have been returned before today.
+func (l Loan) Overdue(today time.Time) bool {
+	return l.DaysOverdue(today) > 0
+}
+
+// Day is the date of t at midnight UTC, the way the dates of the loans are kept.
+func Day(t time.Time) time.Time {
+	year, month, day := t.Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// OnLoan tells if the book is lent right now.
+func (bi BookInfo) OnLoan() bool {
+	return !bi.CurrentLoan.IsZero()
+}
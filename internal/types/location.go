@@ -0,0 +1,43 @@
+package types
+
+import (
+	"strings"
+)
+
+// LocationSeparator goes between the room, the bookcase and the shelf when a shelf is written as
+// text.
+const LocationSeparator = " › "
+
+// Shelf is a shelf of a bookcase in a room, the place a book is found at: Estudio › Librero 2 ›
+// Balda 3. The rooms and the bookcases are the ones their shelves name.
+type Shelf struct {
+	ID       int
+	Room     string
+	Bookcase string
+	Name     string
+	// Books is how many books are on it, as the database counts them.
+	Books int `toml:"-"`
+}
+
+func (s Shelf) String() string {
+	var parts []string
+	for _, part := range []string{s.Room, s.Bookcase, s.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, LocationSeparator)
+}
+
+// Location is where a book is: the shelf and its place on it, counted from the left starting at 1.
+// A book with no ShelfID has not been put anywhere yet.
+type Location struct {
+	ShelfID  int
+	Position int
+}
+
+// IsZero tells if the book has no location.
+func (l Location) IsZero() bool {
+	return l.ShelfID == 0
+}
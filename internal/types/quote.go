@@ -0,0 +1,64 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Quote is a passage of a book worth keeping, with the page it is on and tags to find it again.
+type Quote struct {
+	ID     int
+	BookID int
+	Text   string
+	// Page is where the passage is, 0 when it is not known.
+	Page int
+	// Tags are free-form like the ones of the books, normalized with NormalizeTags.
+	Tags []string
+	// AddedOn is the day the quote was saved, at midnight UTC.
+	AddedOn time.Time
+}
+
+// Validate rejects a quote without book or text, and one on a negative page.
+func (q Quote) Validate() error {
+	switch {
+	case q.BookID <= 0:
+		return fmt.Errorf("quote: the book is missing")
+	case strings.TrimSpace(q.Text) == "":
+		return fmt.Errorf("quote of book %d: the text is missing", q.BookID)
+	case q.Page < 0:
+		return fmt.Errorf("quote of book %d: the page %d does not exist", q.BookID, q.Page)
+	}
+
+	return nil
+}
+
+// HasTag tells if the quote has the tag, regardless of how it is written.
+func (q Quote) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, quoteTag := range q.Tags {
+		if NormalizeTag(quoteTag) == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches tells if every word of the search is in the text or the tags of the quote, ignoring
+// case: "tiempo perdido" finds the quotes with both words anywhere in them.
+func (q Quote) Matches(search string) bool {
+	text := strings.ToLower(q.Text + " " + strings.Join(q.Tags, " "))
+	for _, word := range SearchWords(search) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SearchWords are the words of a search in lowercase, the ones quotes are searched by.
+func SearchWords(search string) []string {
+	return strings.Fields(strings.ToLower(search))
+}
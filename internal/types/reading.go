@@ -0,0 +1,202 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReadingStatus is where a book is in its reading: waiting to be read, being read, read...
+type ReadingStatus string
+
+const (
+	// StatusNone is a book nobody plans to read yet.
+	StatusNone      ReadingStatus = ""
+	StatusToRead    ReadingStatus = "to-read"
+	StatusReading   ReadingStatus = "reading"
+	StatusRead      ReadingStatus = "read"
+	StatusAbandoned ReadingStatus = "abandoned"
+	StatusRereading ReadingStatus = "re-reading"
+)
+
+// ReadingStatuses are the known statuses, in the order of the lifecycle of a book.
+var ReadingStatuses = []ReadingStatus{StatusNone, StatusToRead, StatusReading, StatusRead, StatusAbandoned, StatusRereading}
+
+// ParseReadingStatus accepts the statuses of ReadingStatuses, "reread" and "rereading" are
+// re-reading and "currently-reading" is reading, as Goodreads calls them.
+func ParseReadingStatus(s string) (ReadingStatus, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "reread", "rereading":
+		return StatusRereading, true
+	case "currently-reading":
+		return StatusReading, true
+	}
+
+	for _, status := range ReadingStatuses {
+		if string(status) == s {
+			return status, true
+		}
+	}
+
+	return "", false
+}
+
+// Label is the name of the status the pages show.
+func (s ReadingStatus) Label() string {
+	switch s {
+	case StatusNone:
+		return "Sin leer"
+	case StatusToRead:
+		return "Por leer"
+	case StatusReading:
+		return "Leyendo"
+	case StatusRead:
+		return "Leído"
+	case StatusAbandoned:
+		return "Abandonado"
+	case StatusRereading:
+		return "Releyendo"
+	default:
+		return string(s)
+	}
+}
+
+// InProgress tells if the book is being read, for the first time or again.
+func (s ReadingStatus) InProgress() bool {
+	return s == StatusReading || s == StatusRereading
+}
+
+// Reading is a read-through of a book: when it started, when it finished and the page it got to.
+// Dates are days, at midnight UTC, and zero when they are not known; the books that were only
+// marked as read have no read-throughs.
+type Reading struct {
+	StartedOn time.Time
+	// FinishedOn is zero while the read-through goes on, or when it was abandoned.
+	FinishedOn  time.Time
+	CurrentPage int
+}
+
+// IsZero tells if nothing is known about the read-through.
+func (r Reading) IsZero() bool {
+	return r == Reading{}
+}
+
+// Finished tells if the book was read to the end in this read-through.
+func (r Reading) Finished() bool {
+	return !r.FinishedOn.IsZero()
+}
+
+// Validate rejects a read-through that finishes before it starts or goes past the last page.
+func (r Reading) Validate(pages int) error {
+	switch {
+	case r.CurrentPage < 0:
+		return fmt.Errorf("the page %d does not exist", r.CurrentPage)
+	case pages > 0 && r.CurrentPage > pages:
+		return fmt.Errorf("the page %d is past the last one, %d", r.CurrentPage, pages)
+	case r.Finished() && !r.StartedOn.IsZero() && Day(r.FinishedOn).Before(Day(r.StartedOn)):
+		return fmt.Errorf("it finishes on %s, before it starts", r.FinishedOn.Format(AddedOnLayout))
+	}
+
+	return nil
+}
+
+// ValidateReading rejects an unknown status, a negative number of pages and wrong read-throughs.
+func (bi BookInfo) ValidateReading() error {
+	if _, ok := ParseReadingStatus(string(bi.ReadingStatus)); !ok {
+		return fmt.Errorf("book %d: unknown reading status %q", bi.ID, bi.ReadingStatus)
+	}
+
+	if bi.Pages < 0 {
+		return fmt.Errorf("book %d: it cannot have %d pages", bi.ID, bi.Pages)
+	}
+
+	for i, reading := range bi.Readings {
+		if err := reading.Validate(bi.Pages); err != nil {
+			return fmt.Errorf("book %d, read-through %d: %v", bi.ID, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// NormalizeReading keeps ReadingStatus, Readings and HasBeenRead in agreement. A book marked as read
+// without status nor read-throughs, as the library files and the older databases have, is read;
+// then HasBeenRead tells if the book is read (or re-read) or was finished in any read-through.
+// Empty read-throughs are dropped and their dates are kept as days.
+func (bi *BookInfo) NormalizeReading() {
+	if status, ok := ParseReadingStatus(string(bi.ReadingStatus)); ok {
+		bi.ReadingStatus = status
+	}
+
+	var readings []Reading
+	for _, reading := range bi.Readings {
+		if reading.IsZero() {
+			continue
+		}
+		if !reading.StartedOn.IsZero() {
+			reading.StartedOn = Day(reading.StartedOn)
+		}
+		if reading.Finished() {
+			reading.FinishedOn = Day(reading.FinishedOn)
+		}
+		readings = append(readings, reading)
+	}
+	bi.Readings = readings
+
+	if bi.HasBeenRead && bi.ReadingStatus == StatusNone && len(bi.Readings) == 0 {
+		bi.ReadingStatus = StatusRead
+	}
+
+	bi.HasBeenRead = bi.ReadingStatus == StatusRead || bi.ReadingStatus == StatusRereading
+	for _, reading := range bi.Readings {
+		if reading.Finished() {
+			bi.HasBeenRead = true
+		}
+	}
+}
+
+// CurrentReading is the last read-through while the book is being read, nil otherwise.
+func (bi BookInfo) CurrentReading() *Reading {
+	if !bi.ReadingStatus.InProgress() || len(bi.Readings) == 0 {
+		return nil
+	}
+
+	return &bi.Readings[len(bi.Readings)-1]
+}
+
+// Progress is the percentage of the book read in the current read-through, 0 when the book is not
+// being read or its pages are not known.
+func (bi BookInfo) Progress() int {
+	reading := bi.CurrentReading()
+	if reading == nil || bi.Pages == 0 {
+		return 0
+	}
+
+	return reading.CurrentPage * 100 / bi.Pages
+}
+
+// TimesRead is how many read-throughs got to the end.
+func (bi BookInfo) TimesRead() int {
+	times := 0
+	for _, reading := range bi.Readings {
+		if reading.Finished() {
+			times++
+		}
+	}
+
+	return times
+}
+
+// MarkRead marks the book as read or unread, as the imports that only know a yes or a no do: a read
+// book that was not read nor being re-read is read, and an unread one that was has no status.
+func (bi *BookInfo) MarkRead(read bool) {
+	done := bi.ReadingStatus == StatusRead || bi.ReadingStatus == StatusRereading
+	switch {
+	case read && !done:
+		bi.ReadingStatus = StatusRead
+	case !read && done:
+		bi.ReadingStatus = StatusNone
+	}
+	bi.HasBeenRead = read
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRating is the highest rating, five stars counted in half stars.
+const MaxRating = 10
+
+// Review is what a user thinks of a book: a rating, a review everyone can read and notes only the
+// user sees. A user has one review per book.
+type Review struct {
+	BookID int
+	UserID string
+	// UserName is the name of the user, for the pages; it is not saved with the review.
+	UserName string
+	// Rating is in half stars, from 1 (half a star) to MaxRating; 0 is not rated.
+	Rating int
+	// Text is the review, in Markdown.
+	Text string
+	// Notes are private, only their user sees them.
+	Notes     string
+	UpdatedOn time.Time
+}
+
+// IsEmpty tells if the review has nothing to keep: no rating, no text and no notes.
+func (r Review) IsEmpty() bool {
+	return r.Rating == 0 && strings.TrimSpace(r.Text) == "" && strings.TrimSpace(r.Notes) == ""
+}
+
+// Validate rejects a review without book or user, and a rating out of range.
+func (r Review) Validate() error {
+	switch {
+	case r.BookID <= 0:
+		return fmt.Errorf("review: the book is missing")
+	case r.UserID == "":
+		return fmt.Errorf("review of book %d: the user is missing", r.BookID)
+	case r.Rating < 0 || r.Rating > MaxRating:
+		return fmt.Errorf("review of book %d: the rating %d is not between 0 and %d", r.BookID, r.Rating, MaxRating)
+	}
+
+	return nil
+}
+
+// Stars draws the rating: "★★★½☆" is three stars and a half.
+func (r Review) Stars() string {
+	full, half := r.Rating/2, r.Rating%2
+
+	return strings.Repeat("★", full) + strings.Repeat("½", half) + strings.Repeat("☆", MaxRating/2-full-half)
+}
+
+// RatingText is the rating in stars as a number, "3.5"; empty when it is not rated.
+func (r Review) RatingText() string {
+	if r.Rating == 0 {
+		return ""
+	}
+
+	return FormatRating(r.Rating)
+}
+
+// FormatRating writes a rating in half stars as stars, 7 is "3.5".
+func FormatRating(rating int) string {
+	return strconv.FormatFloat(float64(rating)/2, 'f', -1, 64)
+}
+
+// ParseRating reads a rating in stars, "3.5" or "3,5", as half stars. An empty rating is 0, not rated.
+func ParseRating(s string) (int, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	if s == "" {
+		return 0, nil
+	}
+
+	stars, err := strconv.ParseFloat(s, 64)
+	if err != nil || stars*2 != float64(int(stars*2)) || stars < 0 || stars*2 > MaxRating {
+		return 0, fmt.Errorf("%q is not a rating from 0.5 to %s in half stars", s, FormatRating(MaxRating))
+	}
+
+	return int(stars * 2), nil
+}
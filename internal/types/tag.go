@@ -0,0 +1,184 @@
+package types
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Tag is a free-form label of books, "ciencia ficción" or "regalo", with how many books have it.
+type Tag struct {
+	Name  string
+	Books int
+}
+
+// NormalizeTag is how a tag is stored: lowercase and without extra spaces, so "Ciencia  Ficción"
+// and "ciencia ficción" are the same tag.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// NormalizeTags normalizes the tags, drops the empty and repeated ones and sorts them. No tags
+// at all are nil.
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	var normalized []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+
+	return normalized
+}
+
+// ParseTags reads the comma separated tags of a form: "novela, ciencia ficción".
+func ParseTags(text string) []string {
+	return NormalizeTags(strings.Split(text, ","))
+}
+
+// HasTag tells if the book has the tag, regardless of how it is written.
+func (bi BookInfo) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, bookTag := range bi.Tags {
+		if NormalizeTag(bookTag) == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CategorySeparator goes between the levels of a category when it is written as text.
+const CategorySeparator = " › "
+
+// Category is where a book is in the category tree, from the root: Literatura › Novela ›
+// Posmodernismo is {"Literatura", "Novela", "Posmodernismo"}. A book without one has an empty
+// Category.
+type Category []string
+
+var categorySeparators = regexp.MustCompile(`\s*[›>/]\s*`)
+
+// ParseCategory reads a category written as text, its levels separated by "›", ">" or "/":
+// "Literatura › Novela" and "Literatura/Novela" are the same category.
+func ParseCategory(text string) Category {
+	category := Category{}
+	for _, level := range categorySeparators.Split(text, -1) {
+		if level = strings.Join(strings.Fields(level), " "); level != "" {
+			category = append(category, level)
+		}
+	}
+
+	return category
+}
+
+func (c Category) String() string {
+	return strings.Join(c, CategorySeparator)
+}
+
+// Name is the last level of the category, the one it is known by in its parent.
+func (c Category) Name() string {
+	if len(c) == 0 {
+		return ""
+	}
+
+	return c[len(c)-1]
+}
+
+// Levels returns the category of every level down to c: Literatura, Literatura › Novela and
+// Literatura › Novela › Posmodernismo. The pages link each of them.
+func (c Category) Levels() []Category {
+	levels := make([]Category, len(c))
+	for i := range c {
+		levels[i] = append(Category{}, c[:i+1]...)
+	}
+
+	return levels
+}
+
+// MarshalText writes the category as text, which is how books_db.toml and the JSON of the API
+// have it.
+func (c Category) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Category) UnmarshalText(text []byte) error {
+	*c = ParseCategory(string(text))
+
+	return nil
+}
+
+// Within tells if the category is other or one of its subcategories, regardless of case: a book of
+// Literatura › Novela is within Literatura. Every category is within the empty one.
+func (c Category) Within(other Category) bool {
+	if len(c) < len(other) {
+		return false
+	}
+
+	for i, level := range other {
+		if !strings.EqualFold(c[i], level) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CategoryCount is a category the books are filed in and how many of them are.
+type CategoryCount struct {
+	Category Category
+	Books    int
+}
+
+// CategoryNode is a category of the tree, Books counts the books in it and in its subcategories.
+type CategoryNode struct {
+	Name     string
+	Category Category
+	Books    int
+	Children []*CategoryNode
+}
+
+// CategoryTree builds the tree of categories out of the ones the books are filed in. The parents
+// of a category are in the tree even when no book is filed right in them. The categories of every
+// level are sorted by name.
+func CategoryTree(counts []CategoryCount) []*CategoryNode {
+	var roots []*CategoryNode
+	for _, count := range counts {
+		level := &roots
+		for i, name := range count.Category {
+			var node *CategoryNode
+			for _, sibling := range *level {
+				if strings.EqualFold(sibling.Name, name) {
+					node = sibling
+					break
+				}
+			}
+
+			if node == nil {
+				node = &CategoryNode{Name: name, Category: append(Category{}, count.Category[:i+1]...)}
+				*level = append(*level, node)
+			}
+
+			node.Books += count.Books
+			level = &node.Children
+		}
+	}
+
+	sortCategoryNodes(roots)
+
+	return roots
+}
+
+func sortCategoryNodes(nodes []*CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+	})
+
+	for _, node := range nodes {
+		sortCategoryNodes(node.Children)
+	}
+}
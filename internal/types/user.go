@@ -14,3 +14,11 @@ type UserInfo struct {
 func (ui UserInfo) String() string {
 	return fmt.Sprintf("Name=(%s), email=(%s), nickname=(%s), verified=(%t), sub=(%s)", ui.Name, ui.Email, ui.Nickname, ui.Verified, ui.Sub)
 }
+
+// User is a row of the users table.
+type User struct {
+	UserID          string
+	Email           string
+	Name            string
+	OAuthIdentifier string
+}
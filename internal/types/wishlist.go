@@ -0,0 +1,132 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Priority is how much a book of the wish list is wanted, the most wanted go first.
+type Priority int
+
+const (
+	PriorityHigh   Priority = 1
+	PriorityNormal Priority = 2
+	PriorityLow    Priority = 3
+)
+
+// Priorities are the priorities from the highest to the lowest.
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// ParsePriority accepts high, normal and low, or their numbers 1, 2 and 3. Empty is normal.
+func ParsePriority(s string) (Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "normal", "2":
+		return PriorityNormal, true
+	case "high", "1":
+		return PriorityHigh, true
+	case "low", "3":
+		return PriorityLow, true
+	default:
+		return 0, false
+	}
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	default:
+		return strconv.Itoa(int(p))
+	}
+}
+
+// Label is the name of the priority the pages show.
+func (p Priority) Label() string {
+	switch p {
+	case PriorityHigh:
+		return "Alta"
+	case PriorityLow:
+		return "Baja"
+	default:
+		return "Normal"
+	}
+}
+
+// MarshalText writes the priority as wish_list.toml has it, priority = "high".
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	priority, ok := ParsePriority(string(text))
+	if !ok {
+		return fmt.Errorf("unknown priority %q", text)
+	}
+	*p = priority
+
+	return nil
+}
+
+// Validate rejects a book of the wish list without title and one with an unknown priority.
+func (w WishListBook) Validate() error {
+	switch {
+	case strings.TrimSpace(w.Title) == "":
+		return fmt.Errorf("wish list book %d: the title is missing", w.ID)
+	case w.Priority != 0 && (w.Priority < PriorityHigh || w.Priority > PriorityLow):
+		return fmt.Errorf("wish list book %d: unknown priority %d", w.ID, w.Priority)
+	}
+
+	return nil
+}
+
+// Normalize trims the fields and gives the normal priority to a book without one.
+func (w *WishListBook) Normalize() {
+	w.Title = strings.TrimSpace(w.Title)
+	w.Author = strings.TrimSpace(w.Author)
+	w.Description = strings.TrimSpace(w.Description)
+	w.ImageLink = strings.TrimSpace(w.ImageLink)
+	w.GoodreadsLink = strings.TrimSpace(w.GoodreadsLink)
+	if w.Priority == 0 {
+		w.Priority = PriorityNormal
+	}
+}
+
+// ToBook is the book of the catalogue the wish list book becomes once it is bought on the day
+// purchasedOn. Its cover, at ImageLink, is downloaded apart.
+func (w WishListBook) ToBook(purchasedOn time.Time) BookInfo {
+	return BookInfo{
+		Title:         w.Title,
+		Author:        w.Author,
+		Description:   w.Description,
+		GoodreadsLink: w.GoodreadsLink,
+		AddedOn:       purchasedOn.Format(AddedOnLayout),
+		Acquisition:   Acquisition{PurchasedOn: Day(purchasedOn)},
+	}
+}
+
+// WishListReservation is a book of the wish list of a user that a friend will give as a present.
+// The owner of the list never sees it, so the gift stays a surprise.
+type WishListReservation struct {
+	WishListBookID int
+	UserID         string
+	ReservedOn     time.Time
+}
+
+// CheckReservation tells why the user cannot reserve the book: those of the library are not
+// gifts, and nobody reserves their own.
+func (w WishListBook) CheckReservation(userID string) error {
+	switch {
+	case w.UserID == "":
+		return fmt.Errorf("wish list book %d is not in the wish list of a user", w.ID)
+	case w.UserID == userID:
+		return fmt.Errorf("wish list book %d is in the own wish list of %s", w.ID, userID)
+	}
+
+	return nil
+}